@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
@@ -8,6 +9,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/validation"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/version"
 	"github.com/spf13/pflag"
@@ -53,13 +55,25 @@ func main() {
 		logger.Fatalf("%s", err)
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background(), opts.Tracing.OTLPEndpoint, opts.Tracing.ServiceName, opts.Tracing.SampleRatio)
+	if err != nil {
+		logger.Fatalf("ERROR: Failed to initialise tracing: %v", err)
+	}
+
 	validator := NewValidator(opts.EmailDomains, opts.AuthenticatedEmailsFile)
 	oauthproxy, err := NewOAuthProxy(opts, validator)
 	if err != nil {
 		logger.Fatalf("ERROR: Failed to initialise OAuth2 Proxy: %v", err)
 	}
 
-	if err := oauthproxy.Start(); err != nil {
+	err = oauthproxy.Start()
+
+	// Export the spans of the last requests before exiting
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Errorf("ERROR: Failed to flush traces: %v", err)
+	}
+
+	if err != nil {
 		logger.Fatalf("ERROR: Failed to start OAuth2 Proxy: %v", err)
 	}
 }
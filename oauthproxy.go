@@ -7,18 +7,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"regexp"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/admin"
 	ipapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/ip"
 	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
@@ -26,8 +29,11 @@ import (
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/app/pagewriter"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/app/redirect"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/authentication/basic"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/authentication/introspection"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/authorization"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/cookies"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/encryption"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/extauthz"
 	proxyhttp "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/http"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/util"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/version"
@@ -37,6 +43,8 @@ import (
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/middleware"
 	requestutil "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests/util"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+	sessionsjwt "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/jwt"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/upstream"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/providers"
 )
@@ -52,8 +60,13 @@ const (
 	oauthStartPath    = "/start"
 	oauthCallbackPath = "/callback"
 	authOnlyPath      = "/auth"
+	forwardAuthPath   = "/forward_auth"
 	userInfoPath      = "/userinfo"
+	tenantPath        = "/tenant"
+	jwksPath          = "/jwks.json"
 	staticPathPrefix  = "/static/"
+
+	backChannelLogoutPath = "/backchannel_logout"
 )
 
 var (
@@ -92,6 +105,11 @@ type OAuthProxy struct {
 	whitelistDomains     []string
 	provider             providers.Provider
 	sessionStore         sessionsapi.SessionStore
+	revocationList       sessionsapi.RevocationList
+	maxSessionsPerUser   int
+	evictSessions        bool
+	clientBinder         *sessions.ClientBinder
+	jwtKeys              *sessionsjwt.KeySet
 	ProxyPrefix          string
 	basicAuthValidator   basic.Validator
 	basicAuthGroups      []string
@@ -103,15 +121,23 @@ type OAuthProxy struct {
 	realClientIPParser   ipapi.RealClientIPParser
 	trustedIPs           *ip.NetSet
 
-	sessionChain      alice.Chain
-	headersChain      alice.Chain
-	preAuthChain      alice.Chain
-	pageWriter        pagewriter.Writer
-	server            proxyhttp.Server
-	upstreamProxy     http.Handler
-	serveMux          *mux.Router
-	redirectValidator redirect.Validator
-	appDirector       redirect.AppDirector
+	backChannelLogoutTrustedIPs *ip.NetSet
+	// crossOriginProtection rejects the state changing requests of the
+	// browsers sent from other origins
+	crossOriginProtection http.CrossOriginProtection
+
+	sessionChain       alice.Chain
+	headersChain       alice.Chain
+	preAuthChain       alice.Chain
+	pageWriter         pagewriter.Writer
+	server             proxyhttp.Server
+	upstreamProxy      http.Handler
+	upstreamAuthorizer *authorization.UpstreamAuthorizer
+	authorizationRules *authorization.Rules
+	authorizationHook  *authorization.Webhook
+	serveMux           *mux.Router
+	redirectValidator  redirect.Validator
+	appDirector        redirect.AppDirector
 
 	encodeState bool
 }
@@ -168,6 +194,12 @@ func NewOAuthProxy(opts *options.Options, validator func(string) bool) (*OAuthPr
 		}
 
 	}
+	if opts.SkipOpaqueBearerTokens {
+		logger.Printf("Skipping opaque bearer tokens validated by the provider, caching the results for %s", opts.OpaqueBearerTokenCacheTTL)
+		if !opts.BearerTokenLoginFallback {
+			logger.Println("Denying requests with invalid opaque tokens")
+		}
+	}
 	redirectURL := opts.GetRedirectURL()
 	if redirectURL.Path == "" {
 		redirectURL.Path = fmt.Sprintf("%s/callback", opts.ProxyPrefix)
@@ -190,6 +222,15 @@ func NewOAuthProxy(opts *options.Options, validator func(string) bool) (*OAuthPr
 		}
 	}
 
+	backChannelLogoutTrustedIPs := ip.NewNetSet()
+	for _, ipStr := range opts.BackChannelLogoutTrustedIPs {
+		if ipNet := ip.ParseIPNet(ipStr); ipNet != nil {
+			backChannelLogoutTrustedIPs.AddIPNet(*ipNet)
+		} else {
+			return nil, fmt.Errorf("could not parse IP network (%s)", ipStr)
+		}
+	}
+
 	allowedRoutes, err := buildRoutesAllowlist(opts)
 	if err != nil {
 		return nil, err
@@ -204,12 +245,35 @@ func NewOAuthProxy(opts *options.Options, validator func(string) bool) (*OAuthPr
 	if err != nil {
 		return nil, fmt.Errorf("could not build pre-auth chain: %v", err)
 	}
-	sessionChain := buildSessionChain(opts, provider, sessionStore, basicAuthValidator)
-	headersChain, err := buildHeadersChain(opts)
+	revocationList := sessions.NewRevocationList(sessionStore)
+	clientBinder := sessions.NewClientBinder(&opts.Session, opts.GetRealClientIPParser())
+	authorizationRules, err := authorization.NewRules(opts.AuthorizationRules, opts.GetRealClientIPParser())
+	if err != nil {
+		return nil, fmt.Errorf("error initialising authorization rules: %v", err)
+	}
+	upstreamAuthorizer, err := authorization.NewUpstreamAuthorizer(opts.UpstreamServers, opts.GetRealClientIPParser())
+	if err != nil {
+		return nil, fmt.Errorf("error initialising upstream authorization: %v", err)
+	}
+	sessionChain, err := buildSessionChain(opts, provider, sessionStore, revocationList, clientBinder, basicAuthValidator)
+	if err != nil {
+		return nil, fmt.Errorf("could not build session chain: %v", err)
+	}
+	jwtKeys, err := buildJWTKeys(opts, sessionStore)
+	if err != nil {
+		return nil, fmt.Errorf("could not load jwt keys: %v", err)
+	}
+	headersChain, err := buildHeadersChain(opts, jwtKeys)
 	if err != nil {
 		return nil, fmt.Errorf("could not build headers chain: %v", err)
 	}
 
+	// The JWT keys are published so that upstreams can verify the session
+	// cookie and the injected JWTs themselves, unless they are shared secrets
+	if jwtKeys != nil && jwtKeys.Symmetric() {
+		jwtKeys = nil
+	}
+
 	redirectValidator := redirect.NewValidator(opts.WhitelistDomains)
 	appDirector := redirect.NewAppDirector(redirect.AppDirectorOpts{
 		ProxyPrefix: opts.ProxyPrefix,
@@ -225,6 +289,11 @@ func NewOAuthProxy(opts *options.Options, validator func(string) bool) (*OAuthPr
 		ProxyPrefix:          opts.ProxyPrefix,
 		provider:             provider,
 		sessionStore:         sessionStore,
+		revocationList:       revocationList,
+		maxSessionsPerUser:   opts.Session.MaxPerUser,
+		evictSessions:        opts.Session.LimitPolicy == options.EvictSessionLimitPolicy,
+		clientBinder:         clientBinder,
+		jwtKeys:              jwtKeys,
 		redirectURL:          redirectURL,
 		relativeRedirectURL:  opts.RelativeRedirectURL,
 		apiRoutes:            apiRoutes,
@@ -238,6 +307,8 @@ func NewOAuthProxy(opts *options.Options, validator func(string) bool) (*OAuthPr
 		allowQuerySemicolons: opts.AllowQuerySemicolons,
 		trustedIPs:           trustedIPs,
 
+		backChannelLogoutTrustedIPs: backChannelLogoutTrustedIPs,
+
 		basicAuthValidator: basicAuthValidator,
 		basicAuthGroups:    opts.HtpasswdUserGroups,
 		sessionChain:       sessionChain,
@@ -245,6 +316,9 @@ func NewOAuthProxy(opts *options.Options, validator func(string) bool) (*OAuthPr
 		preAuthChain:       preAuthChain,
 		pageWriter:         pageWriter,
 		upstreamProxy:      upstreamProxy,
+		upstreamAuthorizer: upstreamAuthorizer,
+		authorizationRules: authorizationRules,
+		authorizationHook:  authorization.NewWebhook(&opts.AuthorizationWebhook),
 		redirectValidator:  redirectValidator,
 		appDirector:        appDirector,
 		encodeState:        opts.EncodeState,
@@ -275,7 +349,15 @@ func (p *OAuthProxy) Start() error {
 		cancel() // cancel the context
 	}()
 
-	return p.server.Start(ctx)
+	err := p.server.Start(ctx)
+
+	// Release the session store once the servers stopped serving requests
+	if closer, ok := p.sessionStore.(io.Closer); ok {
+		if closeErr := closer.Close(); closeErr != nil {
+			logger.Errorf("Error closing the session store: %v", closeErr)
+		}
+	}
+	return err
 }
 
 func (p *OAuthProxy) setupServer(opts *options.Options) error {
@@ -284,6 +366,8 @@ func (p *OAuthProxy) setupServer(opts *options.Options) error {
 		BindAddress:       opts.Server.BindAddress,
 		SecureBindAddress: opts.Server.SecureBindAddress,
 		TLS:               opts.Server.TLS,
+		// The client certificates the sessions are bound to must be requested
+		RequestClientCertificate: p.clientBinder.RequiresClientCertificate(),
 	}
 
 	// Option: AllowQuerySemicolons
@@ -306,10 +390,61 @@ func (p *OAuthProxy) setupServer(opts *options.Options) error {
 		return fmt.Errorf("could not build metrics server: %v", err)
 	}
 
-	p.server = proxyhttp.NewServerGroup(appServer, metricsServer)
+	servers := []proxyhttp.Server{appServer, metricsServer}
+	if opts.EnvoyExtAuthz.BindAddress != "" {
+		extAuthzServer, err := extauthz.NewServer(extauthz.Opts{
+			// The checks are not served by the router, so that the paths of
+			// the proxy, like the health checks, are not reachable through them
+			Handler: alice.New(
+				middleware.NewScope(opts.ReverseProxy, opts.Logging.RequestIDHeader),
+				middleware.NewRequestLogger(),
+			).Extend(p.sessionChain).ThenFunc(p.ExtAuthzCheck),
+			BindAddress: opts.EnvoyExtAuthz.BindAddress,
+		})
+		if err != nil {
+			return fmt.Errorf("could not build ext_authz server: %v", err)
+		}
+		servers = append(servers, extAuthzServer)
+	}
+	if opts.AdminAPI.BindAddress != "" {
+		adminServer, err := p.buildAdminServer(opts)
+		if err != nil {
+			return fmt.Errorf("could not build admin server: %v", err)
+		}
+		servers = append(servers, adminServer)
+	}
+
+	p.server = proxyhttp.NewServerGroup(servers...)
 	return nil
 }
 
+// buildAdminServer builds the server of the session administration API. The
+// administrators are authenticated like the users of the proxy.
+func (p *OAuthProxy) buildAdminServer(opts *options.Options) (proxyhttp.Server, error) {
+	handler, err := admin.NewHandler(admin.Opts{
+		SessionChain: alice.New(
+			middleware.NewScope(opts.ReverseProxy, opts.Logging.RequestIDHeader),
+			middleware.NewRequestLogger(),
+		).Extend(p.sessionChain),
+		SessionStore:         p.sessionStore,
+		RevocationList:       p.revocationList,
+		RevocationExpiration: p.CookieOptions.Expire,
+		AuthorizeSession: func(req *http.Request, s *sessionsapi.SessionState) bool {
+			authorized, _ := p.authorizeSession(req, s)
+			return authorized
+		},
+		AllowedGroups: opts.AdminAPI.AllowedGroups,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return proxyhttp.NewServer(proxyhttp.Opts{
+		Handler:     handler,
+		BindAddress: opts.AdminAPI.BindAddress,
+	})
+}
+
 func (p *OAuthProxy) buildServeMux(proxyPrefix string) {
 	// Use the encoded path here so we can have the option to pass it on in the upstream mux.
 	// Otherwise something like /%2F/ would be redirected to / here already.
@@ -324,6 +459,13 @@ func (p *OAuthProxy) buildServeMux(proxyPrefix string) {
 	// We do this to allow users to have a short cache (via nginx) of the response to reduce the
 	// likelihood of multiple requests trying to refresh sessions simultaneously.
 	r.Path(proxyPrefix + authOnlyPath).Handler(p.sessionChain.ThenFunc(p.AuthOnly))
+	r.Path(proxyPrefix + forwardAuthPath).Handler(p.sessionChain.ThenFunc(p.ForwardAuth))
+
+	// The JWKS path is registered separately as well, the keys rarely change so
+	// clients are allowed to cache them.
+	if p.jwtKeys != nil {
+		r.Path(proxyPrefix + jwksPath).HandlerFunc(p.JWKS)
+	}
 
 	// This will register all of the paths under the proxy prefix, except the auth only path so that no cache headers
 	// are not applied.
@@ -347,14 +489,21 @@ func (p *OAuthProxy) buildProxySubrouter(s *mux.Router) {
 
 	// The userinfo and logout endpoints needs to load sessions before handling the request
 	s.Path(userInfoPath).Handler(p.sessionChain.ThenFunc(p.UserInfo))
+	s.Path(tenantPath).Handler(p.sessionChain.ThenFunc(p.SwitchTenant))
 	s.Path(signOutPath).Handler(p.sessionChain.ThenFunc(p.SignOut))
+
+	// The back-channel logout is called by the provider, without a session
+	s.Path(backChannelLogoutPath).HandlerFunc(p.BackChannelLogout)
 }
 
 // buildPreAuthChain constructs a chain that should process every request before
 // the OAuth2 Proxy authentication logic kicks in.
 // For example forcing HTTPS or health checks.
 func buildPreAuthChain(opts *options.Options, sessionStore sessionsapi.SessionStore) (alice.Chain, error) {
-	chain := alice.New(middleware.NewScope(opts.ReverseProxy, opts.Logging.RequestIDHeader))
+	chain := alice.New(
+		middleware.NewTracing(),
+		middleware.NewScope(opts.ReverseProxy, opts.Logging.RequestIDHeader),
+	)
 
 	if opts.ForceHTTPS {
 		_, httpsPort, err := net.SplitHostPort(opts.Server.SecureBindAddress)
@@ -393,9 +542,27 @@ func buildPreAuthChain(opts *options.Options, sessionStore sessionsapi.SessionSt
 	return chain, nil
 }
 
-func buildSessionChain(opts *options.Options, provider providers.Provider, sessionStore sessionsapi.SessionStore, validator basic.Validator) alice.Chain {
+func buildSessionChain(opts *options.Options, provider providers.Provider, sessionStore sessionsapi.SessionStore, revocationList sessionsapi.RevocationList, clientBinder *sessions.ClientBinder, validator basic.Validator) (alice.Chain, error) {
 	chain := alice.New()
 
+	introspectToken, err := buildTokenIntrospection(opts, provider)
+	if err != nil {
+		return alice.Chain{}, err
+	}
+
+	// Opaque tokens are loaded first as, when invalid tokens are denied, the
+	// JWT session loader rejects any token that is not a JWT
+	if opts.SkipOpaqueBearerTokens {
+		sessionLoaders := []middlewareapi.TokenToSessionFunc{
+			provider.CreateSessionFromOpaqueToken,
+		}
+		if introspectToken != nil {
+			sessionLoaders = append(sessionLoaders, introspectToken)
+		}
+
+		chain = chain.Append(middleware.NewOpaqueSessionLoader(sessionLoaders, opts.OpaqueBearerTokenCacheTTL, opts.BearerTokenLoginFallback))
+	}
+
 	if opts.SkipJwtBearerTokens {
 		sessionLoaders := []middlewareapi.TokenToSessionFunc{
 			provider.CreateSessionFromToken,
@@ -406,6 +573,10 @@ func buildSessionChain(opts *options.Options, provider providers.Provider, sessi
 				middlewareapi.CreateTokenToSessionFunc(verifier.Verify))
 		}
 
+		if introspectToken != nil {
+			sessionLoaders = append(sessionLoaders, introspectToken)
+		}
+
 		chain = chain.Append(middleware.NewJwtSessionLoader(sessionLoaders, opts.BearerTokenLoginFallback))
 	}
 
@@ -418,13 +589,70 @@ func buildSessionChain(opts *options.Options, provider providers.Provider, sessi
 		RefreshPeriod:   opts.Cookie.Refresh,
 		RefreshSession:  provider.RefreshSession,
 		ValidateSession: provider.ValidateSession,
+		RevocationList:  revocationList,
+
+		MaxAge:                 opts.Session.MaxAge,
+		IdleTimeout:            opts.Session.IdleTimeout,
+		ActivityUpdateInterval: opts.Session.ActivityUpdateInterval,
+
+		ClientBinder:          clientBinder,
+		BindingMismatchAction: opts.Session.BindMismatchAction,
 	}))
 
-	return chain
+	return chain, nil
+}
+
+// buildTokenIntrospection returns the session loader validating bearer tokens
+// with the token introspection endpoint, or nil when none is configured.
+// The client credentials of the provider are used unless others are given, and
+// the tokens must be issued for the provider client ID unless audiences are.
+func buildTokenIntrospection(opts *options.Options, provider providers.Provider) (middlewareapi.TokenToSessionFunc, error) {
+	if opts.TokenIntrospection.URL == "" {
+		return nil, nil
+	}
+
+	clientID := opts.TokenIntrospection.ClientID
+	clientSecret := opts.TokenIntrospection.ClientSecret
+	if clientID == "" {
+		clientID = provider.Data().ClientID
+		if clientSecret == "" {
+			secret, err := provider.Data().GetClientSecret()
+			if err != nil {
+				return nil, fmt.Errorf("could not get the token introspection client secret: %v", err)
+			}
+			clientSecret = secret
+		}
+	}
+
+	audiences := opts.TokenIntrospection.Audiences
+	if len(audiences) == 0 {
+		audiences = []string{provider.Data().ClientID}
+	}
+
+	return introspection.NewTokenToSessionFunc(introspection.Options{
+		URL:          opts.TokenIntrospection.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		GroupsClaim:  opts.TokenIntrospection.GroupsClaim,
+		Audiences:    audiences,
+	}), nil
 }
 
-func buildHeadersChain(opts *options.Options) (alice.Chain, error) {
-	requestInjector, err := middleware.NewRequestHeaderInjector(opts.InjectRequestHeaders)
+// buildJWTKeys returns the keys of the JWT session store, which also sign the
+// JWTs injected in the request headers. When another session store is used,
+// the keys are only loaded if they are configured.
+func buildJWTKeys(opts *options.Options, sessionStore sessionsapi.SessionStore) (*sessionsjwt.KeySet, error) {
+	if jwtStore, ok := sessionStore.(*sessionsjwt.SessionStore); ok {
+		return jwtStore.Keys, nil
+	}
+	if opts.Session.JWT.JWTKey == "" && opts.Session.JWT.JWTKeyFile == "" {
+		return nil, nil
+	}
+	return sessionsjwt.NewKeySet(opts.Session.JWT)
+}
+
+func buildHeadersChain(opts *options.Options, jwtKeys *sessionsjwt.KeySet) (alice.Chain, error) {
+	requestInjector, err := middleware.NewRequestHeaderInjector(opts.InjectRequestHeaders, jwtKeys)
 	if err != nil {
 		return alice.Chain{}, fmt.Errorf("error constructing request header injector: %v", err)
 	}
@@ -558,6 +786,23 @@ func (p *OAuthProxy) ClearExtraCookies(rw http.ResponseWriter, req *http.Request
 
 // SaveSession creates a new session cookie value and sets this on the response
 func (p *OAuthProxy) SaveSession(rw http.ResponseWriter, req *http.Request, s *sessionsapi.SessionState) error {
+	// Record when the user authenticated, for the session-max-age
+	if s.AuthenticatedAt == nil {
+		if s.CreatedAt != nil {
+			authenticatedAt := *s.CreatedAt
+			s.AuthenticatedAt = &authenticatedAt
+		} else {
+			s.AuthenticatedAtNow()
+		}
+	}
+	// Bind the new sessions to the client signing in
+	if p.clientBinder != nil && s.Binding == nil {
+		binding, err := p.clientBinder.Bind(req)
+		if err != nil {
+			return fmt.Errorf("error binding the session to the client: %v", err)
+		}
+		s.Binding = binding
+	}
 	return p.sessionStore.Save(rw, req, s)
 }
 
@@ -646,6 +891,18 @@ func (p *OAuthProxy) isTrustedIP(req *http.Request) bool {
 	return p.trustedIPs.Has(remoteAddr)
 }
 
+// isBackChannelLogoutTrustedIP checks whether a back-channel logout request
+// comes from an IP address trusted to send unsigned logout requests.
+func (p *OAuthProxy) isBackChannelLogoutTrustedIP(req *http.Request) bool {
+	remoteAddr, err := ip.GetClientIP(p.realClientIPParser, req)
+	if err != nil {
+		logger.Errorf("Error obtaining real IP for back-channel logout trusted IP list: %v", err)
+		return false
+	}
+
+	return remoteAddr != nil && p.backChannelLogoutTrustedIPs.Has(remoteAddr)
+}
+
 // SignInPage writes the sign in template to the response
 func (p *OAuthProxy) SignInPage(rw http.ResponseWriter, req *http.Request, code int) {
 	prepareNoCache(rw)
@@ -701,6 +958,9 @@ func (p *OAuthProxy) SignIn(rw http.ResponseWriter, req *http.Request) {
 	user, ok, statusCode := p.ManualSignIn(req)
 	if ok {
 		session := &sessionsapi.SessionState{User: user, Groups: p.basicAuthGroups}
+		if !p.limitUserSessions(rw, req, session) {
+			return
+		}
 		err = p.SaveSession(rw, req, session)
 		if err != nil {
 			logger.Printf("Error saving session: %v", err)
@@ -736,6 +996,11 @@ func (p *OAuthProxy) UserInfo(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	p.writeUserInfo(rw, req, session)
+}
+
+// writeUserInfo encodes the session user details as the JSON userinfo document
+func (p *OAuthProxy) writeUserInfo(rw http.ResponseWriter, req *http.Request, session *sessionsapi.SessionState) {
 	userInfo := struct {
 		User              string   `json:"user"`
 		Email             string   `json:"email"`
@@ -760,6 +1025,119 @@ func (p *OAuthProxy) UserInfo(rw http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// SwitchTenant changes the active tenant of the current session to one of the
+// tenants the user belongs to, saves the session and outputs the updated user info
+func (p *OAuthProxy) SwitchTenant(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	// The forms of other sites must not switch the tenant of the user
+	if err := p.crossOriginProtection.Check(req); err != nil {
+		logger.Errorf("Error switching tenant: %v", err)
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	session, err := p.getAuthenticatedSession(rw, req)
+	switch {
+	case errors.Is(err, ErrAccessDenied):
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	case err != nil || session == nil:
+		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	tenant := req.FormValue("tenant")
+	if tenant == "" {
+		http.Error(rw, "missing tenant", http.StatusBadRequest)
+		return
+	}
+
+	if !slices.Contains(session.Tenants, tenant) {
+		logger.PrintAuthf(session.Email, req, logger.AuthFailure, "Invalid tenant switch: user is not a member of tenant %q", tenant)
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	previousTenant := session.Tenant
+	session.Tenant = tenant
+	authorized, err := p.provider.Authorize(req.Context(), session)
+	if err != nil {
+		logger.Errorf("Error with authorization: %v", err)
+	}
+	if !authorized {
+		session.Tenant = previousTenant
+		logger.PrintAuthf(session.Email, req, logger.AuthFailure, "Invalid tenant switch: tenant %q is not allowed", tenant)
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if err := p.SaveSession(rw, req, session); err != nil {
+		logger.Errorf("Error saving session after tenant switch: %v", err)
+		p.ErrorPage(rw, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+	logger.PrintAuthf(session.Email, req, logger.AuthSuccess, "Switched tenant from %q to %q", previousTenant, tenant)
+
+	rw.Header().Set("Content-Type", applicationJSON)
+	rw.WriteHeader(http.StatusOK)
+	p.writeUserInfo(rw, req, session)
+}
+
+// JWKS outputs the public keys the session JWTs are signed with as a JSON Web Key Set
+func (p *OAuthProxy) JWKS(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", applicationJSON)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(p.jwtKeys.JWKS()); err != nil {
+		logger.Printf("Error encoding JWKS: %v", err)
+		p.ErrorPage(rw, req, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// BackChannelLogout ends the sessions of a user, or a single session, when
+// the provider notifies that they have been logged out.
+// The sessions are revoked until they expire, they are removed from the
+// session store the next time they are loaded.
+func (p *OAuthProxy) BackChannelLogout(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	logout, err := p.provider.ParseBackChannelLogout(req.Context(), req)
+	if err != nil {
+		logger.Errorf("Error parsing back-channel logout: %v", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Anyone could end the sessions of any user with unsigned logout requests,
+	// they are only accepted from the trusted IPs
+	if logout.Unsigned && !p.isBackChannelLogoutTrustedIP(req) {
+		logger.PrintAuthf(logout.User, req, logger.AuthFailure, "Unsigned back-channel logout from an untrusted IP")
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if err := p.revocationList.Revoke(req.Context(), logout.User, logout.SessionID, p.CookieOptions.Expire); err != nil {
+		logger.Errorf("Error revoking sessions: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if logout.SessionID != "" {
+		logger.PrintAuthf(logout.User, req, logger.AuthSuccess, "Back-channel logout of session %q", logout.SessionID)
+	} else {
+		logger.PrintAuthf(logout.User, req, logger.AuthSuccess, "Back-channel logout of every session")
+	}
+
+	// Back-channel logout responses must not be cached
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(http.StatusOK)
+}
+
 // SignOut sends a response to clear the authentication cookie
 func (p *OAuthProxy) SignOut(rw http.ResponseWriter, req *http.Request) {
 	redirect, err := p.appDirector.GetRedirect(req)
@@ -769,6 +1147,25 @@ func (p *OAuthProxy) SignOut(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 	redirect = p.provider.GetSignOutURL(redirect)
+	if req.URL.Query().Get("all") == "true" {
+		// Ending every session of the user must not be triggered by a link
+		// or an image of another site
+		if req.Method != http.MethodPost {
+			rw.Header().Set("Allow", http.MethodPost)
+			p.ErrorPage(rw, req, http.StatusMethodNotAllowed, "Signing out of every session requires a POST request")
+			return
+		}
+		if err := p.crossOriginProtection.Check(req); err != nil {
+			logger.Errorf("Error signing out every session: %v", err)
+			p.ErrorPage(rw, req, http.StatusForbidden, err.Error())
+			return
+		}
+		if err := p.signOutEverywhere(req); err != nil {
+			logger.Errorf("Error signing out every session: %v", err)
+			p.ErrorPage(rw, req, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 	err = p.ClearSessionCookie(rw, req)
 	if err != nil {
 		logger.Errorf("Error clearing session cookie: %v", err)
@@ -782,6 +1179,22 @@ func (p *OAuthProxy) SignOut(rw http.ResponseWriter, req *http.Request) {
 	http.Redirect(rw, req, redirect, http.StatusFound)
 }
 
+// signOutEverywhere ends every session of the user of the request, on every
+// device
+func (p *OAuthProxy) signOutEverywhere(req *http.Request) error {
+	session := middlewareapi.GetRequestScope(req).Session
+	if session == nil || session.User == "" {
+		return nil
+	}
+
+	if err := sessions.EndUserSessions(req.Context(), p.sessionStore, p.revocationList, session.User, p.CookieOptions.Expire); err != nil {
+		return err
+	}
+
+	logger.PrintAuthf(session.User, req, logger.AuthSuccess, "Signed out of every session")
+	return nil
+}
+
 func (p *OAuthProxy) backendLogout(rw http.ResponseWriter, req *http.Request) {
 	session, err := p.getAuthenticatedSession(rw, req)
 	if err != nil {
@@ -820,6 +1233,19 @@ func (p *OAuthProxy) OAuthStart(rw http.ResponseWriter, req *http.Request) {
 }
 
 func (p *OAuthProxy) doOAuthStart(rw http.ResponseWriter, req *http.Request, overrides url.Values) {
+	appRedirect, err := p.appDirector.GetRedirect(req)
+	if err != nil {
+		logger.Errorf("Error obtaining application redirect: %v", err)
+		p.ErrorPage(rw, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	p.startOAuth(rw, req, overrides, appRedirect)
+}
+
+// startOAuth redirects to the login URL of the provider, with the CSRF cookie
+// and the state to return to the appRedirect once authenticated
+func (p *OAuthProxy) startOAuth(rw http.ResponseWriter, req *http.Request, overrides url.Values, appRedirect string) {
 	extraParams := p.provider.Data().LoginURLParams(overrides)
 	prepareNoCache(rw)
 
@@ -854,13 +1280,6 @@ func (p *OAuthProxy) doOAuthStart(rw http.ResponseWriter, req *http.Request, ove
 		return
 	}
 
-	appRedirect, err := p.appDirector.GetRedirect(req)
-	if err != nil {
-		logger.Errorf("Error obtaining application redirect: %v", err)
-		p.ErrorPage(rw, req, http.StatusBadRequest, err.Error())
-		return
-	}
-
 	callbackRedirect := p.getOAuthRedirectURI(req)
 	loginURL := p.provider.GetLoginURL(
 		callbackRedirect,
@@ -957,6 +1376,9 @@ func (p *OAuthProxy) OAuthCallback(rw http.ResponseWriter, req *http.Request) {
 		logger.Errorf("Error with authorization: %v", err)
 	}
 	if p.Validator(session.Email) && authorized {
+		if !p.limitUserSessions(rw, req, session) {
+			return
+		}
 		logger.PrintAuthf(session.Email, req, logger.AuthSuccess, "Authenticated via OAuth2: %s", session)
 		err := p.SaveSession(rw, req, session)
 		if err != nil {
@@ -971,6 +1393,29 @@ func (p *OAuthProxy) OAuthCallback(rw http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// limitUserSessions makes room for the new session of the user within the
+// maximum number of concurrent sessions, by evicting their oldest sessions or
+// rejecting the login. It writes the error page and returns false when the
+// session must not be saved.
+func (p *OAuthProxy) limitUserSessions(rw http.ResponseWriter, req *http.Request, session *sessionsapi.SessionState) bool {
+	evicted, err := sessions.LimitUserSessions(req, p.sessionStore, session.User, p.maxSessionsPerUser, p.evictSessions)
+	if errors.Is(err, sessions.ErrSessionLimitReached) {
+		logger.PrintAuthf(session.Email, req, logger.AuthFailure, "Invalid authentication: maximum of %d concurrent sessions reached", p.maxSessionsPerUser)
+		p.ErrorPage(rw, req, http.StatusForbidden, err.Error(), "You have reached the maximum number of sessions. Sign out from another device and try again.")
+		return false
+	}
+	if err != nil {
+		logger.Errorf("Error limiting the sessions of %s: %v", session.User, err)
+		p.ErrorPage(rw, req, http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	for _, id := range evicted {
+		logger.PrintAuthf(session.Email, req, logger.AuthSuccess, "Evicted session %s: maximum of %d concurrent sessions reached", id, p.maxSessionsPerUser)
+	}
+	return true
+}
+
 func (p *OAuthProxy) redeemCode(req *http.Request, codeVerifier string) (*sessionsapi.SessionState, error) {
 	code := req.Form.Get("code")
 	if code == "" {
@@ -978,7 +1423,9 @@ func (p *OAuthProxy) redeemCode(req *http.Request, codeVerifier string) (*sessio
 	}
 
 	redirectURI := p.getOAuthRedirectURI(req)
-	s, err := p.provider.Redeem(req.Context(), redirectURI, code, codeVerifier)
+	ctx, span := tracing.StartSpan(req.Context(), "provider.redeem")
+	s, err := p.provider.Redeem(ctx, redirectURI, code, codeVerifier)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
@@ -994,8 +1441,10 @@ func (p *OAuthProxy) redeemCode(req *http.Request, codeVerifier string) (*sessio
 	return s, nil
 }
 
-func (p *OAuthProxy) enrichSessionState(ctx context.Context, s *sessionsapi.SessionState) error {
-	var err error
+func (p *OAuthProxy) enrichSessionState(ctx context.Context, s *sessionsapi.SessionState) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "provider.enrich_session")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if s.Email == "" {
 		// TODO(@NickMeves): Remove once all provider are updated to implement EnrichSession
 		// nolint:staticcheck
@@ -1017,14 +1466,106 @@ func (p *OAuthProxy) AuthOnly(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	p.authorizeAuthOnly(rw, req, req, session)
+}
+
+// ExtAuthzCheck checks whether the user is logged in and authorized like
+// AuthOnly, for the Envoy ext_authz checks of the requests of the clients.
+// The browsers that need to log in are redirected to sign in, and are sent
+// back to the URL of the request afterwards.
+func (p *OAuthProxy) ExtAuthzCheck(rw http.ResponseWriter, req *http.Request) {
+	p.authOnlyOrSignIn(rw, req, req, req.URL.RequestURI())
+}
+
+// ForwardAuth checks whether the user is logged in and authorized like
+// AuthOnly, for the forward auth requests of Traefik and Caddy, which describe
+// the request of the client with the X-Forwarded-(Method|Proto|Host|Uri)
+// headers. The browsers that need to log in are redirected to sign in, and are
+// sent back to the original URL afterwards.
+func (p *OAuthProxy) ForwardAuth(rw http.ResponseWriter, req *http.Request) {
+	// The forward auth requests are all GET requests to the forward auth
+	// path, the checks apply to the request of the client instead
+	forwarded, err := forwardedRequest(req)
+	if err != nil {
+		logger.Errorf("Invalid forwarded request URI %q: %v", requestutil.GetRequestURI(req), err)
+		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	p.authOnlyOrSignIn(rw, forwarded, req, p.appDirector.GetForwardedRedirect(req))
+}
+
+// forwardedRequest returns the request of the client described by the
+// X-Forwarded-Method and X-Forwarded-Uri headers of a proxied request. The
+// method and the URL are applied together so that every check sees the same
+// request, the request is returned as is when it is not proxied.
+func forwardedRequest(req *http.Request) (*http.Request, error) {
+	if !requestutil.IsProxied(req) {
+		return req, nil
+	}
+
+	uri, err := url.ParseRequestURI(requestutil.GetRequestURI(req))
+	if err != nil {
+		return nil, err
+	}
+
+	forwarded := req.Clone(req.Context())
+	forwarded.Method = requestutil.GetRequestMethod(req)
+	forwarded.URL.Path = uri.Path
+	forwarded.URL.RawPath = uri.RawPath
+	forwarded.URL.RawQuery = uri.RawQuery
+	forwarded.RequestURI = uri.RequestURI()
+	return forwarded, nil
+}
+
+// authOnlyOrSignIn checks whether the user is logged in and authorized like
+// AuthOnly, but redirects the browsers that need to log in to sign in, with
+// the redirect to return to afterwards. The OAuth flow is started right away
+// when the provider button is skipped. The allowed_* constraints are read from
+// the query of authReq, the request to the auth endpoint.
+func (p *OAuthProxy) authOnlyOrSignIn(rw http.ResponseWriter, req, authReq *http.Request, redirect string) {
+	session, err := p.getAuthenticatedSession(rw, req)
+	switch err {
+	case nil:
+		p.authorizeAuthOnly(rw, req, authReq, session)
+	case ErrNeedsLogin:
+		if p.forceJSONErrors || isAjax(req) || p.isAPIPath(req) {
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if p.SkipProviderButton {
+			// The CSRF cookie is set for the domain of the host of the
+			// request of the client, as is the redirect to the callback
+			p.startOAuth(rw, req, nil, redirect)
+			return
+		}
+		signInURL := fmt.Sprintf("%s?rd=%s", p.SignInPath, url.QueryEscape(redirect))
+		http.Redirect(rw, req, signInURL, http.StatusFound)
+	default:
+		// The session of a user that is not authorized has been cleared,
+		// signing in again would fail the same way
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	}
+}
+
+// authorizeAuthOnly checks the optional authorization of the session of an
+// auth request, and responds with the headers for the upstream. The allowed_*
+// constraints are read from the query of authReq.
+func (p *OAuthProxy) authorizeAuthOnly(rw http.ResponseWriter, req, authReq *http.Request, session *sessionsapi.SessionState) {
 	// Unauthorized cases need to return 403 to prevent infinite redirects with
 	// subrequest architectures
-	if !authOnlyAuthorize(req, session) {
+	if !authOnlyAuthorize(authReq, session) || !p.authorizeRules(req, session) {
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	webhookHeaders, ok := p.authorizeWebhook(req, session)
+	if !ok {
 		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
 	// we are authenticated
+	p.authorizationHook.StripHeaders(rw.Header())
+	copyHeaders(rw.Header(), webhookHeaders)
 	p.addHeadersForProxying(rw, session)
 	p.headersChain.Then(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
 		rw.WriteHeader(http.StatusAccepted)
@@ -1039,10 +1580,28 @@ func (p *OAuthProxy) Proxy(rw http.ResponseWriter, req *http.Request) {
 	case nil:
 		// Check against our authorization constraints and return forbidden
 		// if this request fails to satisfy them.
-		if !authOnlyAuthorize(req, session) {
+		if !authOnlyAuthorize(req, session) || !p.authorizeRules(req, session) {
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		// The upstream is known before the headers are injected so that they
+		// can be specific to it
+		if matcher, ok := p.upstreamProxy.(upstream.Matcher); ok {
+			middlewareapi.GetRequestScope(req).Upstream = matcher.MatchUpstream(req)
+		}
+		if !p.authorizeUpstream(req, session) {
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		webhookHeaders, ok := p.authorizeWebhook(req, session)
+		if !ok {
 			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 			return
 		}
+		// The webhook headers sent by the client are never trusted
+		p.authorizationHook.StripHeaders(req.Header)
+		copyHeaders(req.Header, webhookHeaders)
 
 		// we are authenticated
 		p.addHeadersForProxying(rw, session)
@@ -1146,18 +1705,7 @@ func (p *OAuthProxy) getAuthenticatedSession(rw http.ResponseWriter, req *http.R
 		return nil, ErrNeedsLogin
 	}
 
-	invalidEmail := session.Email != "" && !p.Validator(session.Email)
-	authorized, err := p.provider.Authorize(req.Context(), session)
-	if err != nil {
-		logger.Errorf("Error with authorization: %v", err)
-	}
-
-	if invalidEmail || !authorized {
-		cause := "unauthorized"
-		if invalidEmail {
-			cause = "invalid email"
-		}
-
+	if authorized, cause := p.authorizeSession(req, session); !authorized {
 		logger.PrintAuthf(session.Email, req, logger.AuthFailure, "Invalid authorization via session (%s): removing session %s", cause, session)
 		// Invalid session, clear it
 		err := p.ClearSessionCookie(rw, req)
@@ -1171,6 +1719,26 @@ func (p *OAuthProxy) getAuthenticatedSession(rw http.ResponseWriter, req *http.R
 	return session, nil
 }
 
+// authorizeSession checks the email of the session with the Validator and
+// authorizes the session with the provider, it returns the cause of the
+// rejection of the sessions that are not authorized
+func (p *OAuthProxy) authorizeSession(req *http.Request, session *sessionsapi.SessionState) (bool, string) {
+	invalidEmail := session.Email != "" && !p.Validator(session.Email)
+	authorized, err := p.provider.Authorize(req.Context(), session)
+	if err != nil {
+		logger.Errorf("Error with authorization: %v", err)
+	}
+
+	switch {
+	case invalidEmail:
+		return false, "invalid email"
+	case !authorized:
+		return false, "unauthorized"
+	default:
+		return true, ""
+	}
+}
+
 // authOnlyAuthorize handles special authorization logic that is only done
 // on the AuthOnly endpoint for use with Nginx subrequest architectures.
 func authOnlyAuthorize(req *http.Request, s *sessionsapi.SessionState) bool {
@@ -1181,6 +1749,7 @@ func authOnlyAuthorize(req *http.Request, s *sessionsapi.SessionState) bool {
 
 	constraints := []func(*http.Request, *sessionsapi.SessionState) bool{
 		checkAllowedGroups,
+		checkAllowedTenants,
 		checkAllowedEmailDomains,
 		checkAllowedEmails,
 	}
@@ -1194,6 +1763,61 @@ func authOnlyAuthorize(req *http.Request, s *sessionsapi.SessionState) bool {
 	return true
 }
 
+// authorizeRules checks the session and its request satisfy the global
+// authorization rules. Requests allowed without authentication are not
+// checked.
+func (p *OAuthProxy) authorizeRules(req *http.Request, s *sessionsapi.SessionState) bool {
+	if s == nil || p.IsAllowedRequest(req) {
+		return true
+	}
+
+	if err := p.authorizationRules.Evaluate(req, s); err != nil {
+		logger.PrintAuthf(s.Email, req, logger.AuthFailure, "Invalid authorization via session: %v", err)
+		return false
+	}
+	return true
+}
+
+// authorizeUpstream checks the session meets the requirements of the upstream
+// the request is proxied to. Requests allowed without authentication are not
+// checked.
+func (p *OAuthProxy) authorizeUpstream(req *http.Request, s *sessionsapi.SessionState) bool {
+	if s == nil || p.IsAllowedRequest(req) {
+		return true
+	}
+
+	upstreamID := middlewareapi.GetRequestScope(req).Upstream
+	if err := p.upstreamAuthorizer.Authorize(req, upstreamID, s); err != nil {
+		logger.PrintAuthf(s.Email, req, logger.AuthFailure, "Invalid authorization via session for upstream %q: %v", upstreamID, err)
+		return false
+	}
+	return true
+}
+
+// authorizeWebhook asks the authorization webhook whether the session may make
+// the request, and returns the headers the webhook wants injected. Requests
+// allowed without authentication are not checked.
+func (p *OAuthProxy) authorizeWebhook(req *http.Request, s *sessionsapi.SessionState) (http.Header, bool) {
+	if s == nil || p.IsAllowedRequest(req) {
+		return nil, true
+	}
+
+	headers, err := p.authorizationHook.Authorize(req, s)
+	if err != nil {
+		logger.PrintAuthf(s.Email, req, logger.AuthFailure, "Invalid authorization via session: %v", err)
+		return nil, false
+	}
+	return headers, true
+}
+
+// copyHeaders sets the headers on the destination, replacing their existing
+// values
+func copyHeaders(dst http.Header, src http.Header) {
+	for name, values := range src {
+		dst[name] = values
+	}
+}
+
 // extractAllowedEntities aims to extract and split allowed entities linked by a key,
 // from an HTTP request query. Output is a map[string]struct{} where keys are valuable,
 // the goal is to avoid time complexity O(N^2) while finding matches during membership checks.
@@ -1253,6 +1877,22 @@ func checkAllowedGroups(req *http.Request, s *sessionsapi.SessionState) bool {
 	return false
 }
 
+// checkAllowedTenants allow secondary tenant restrictions based on the `allowed_tenants`
+// querystring parameter
+func checkAllowedTenants(req *http.Request, s *sessionsapi.SessionState) bool {
+	allowedTenants := extractAllowedEntities(req, "allowed_tenants")
+	if len(allowedTenants) == 0 {
+		return true
+	}
+
+	if _, ok := allowedTenants[s.Tenant]; ok {
+		return true
+	}
+
+	logger.PrintAuthf(s.Email, req, logger.AuthFailure, "Invalid authorization via session: tenant %q is not allowed", s.Tenant)
+	return false
+}
+
 // checkAllowedEmails allow email restrictions based on the `allowed_emails`
 // querystring parameter
 func checkAllowedEmails(req *http.Request, s *sessionsapi.SessionState) bool {
@@ -3,21 +3,31 @@ package main
 import (
 	"context"
 	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v3"
 	"github.com/mbland/hmacauth"
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/authorization"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/cookies"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
 	internaloidc "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/providers/oidc"
@@ -60,9 +70,10 @@ func TestRobotsTxt(t *testing.T) {
 
 type TestProvider struct {
 	*providers.ProviderData
-	EmailAddress   string
-	ValidToken     bool
-	GroupValidator func(string) bool
+	EmailAddress      string
+	ValidToken        bool
+	GroupValidator    func(string) bool
+	BackChannelLogout *providers.BackChannelLogout
 }
 
 var _ providers.Provider = (*TestProvider)(nil)
@@ -103,6 +114,13 @@ func (tp *TestProvider) ValidateSession(_ context.Context, _ *sessions.SessionSt
 	return tp.ValidToken
 }
 
+func (tp *TestProvider) ParseBackChannelLogout(_ context.Context, _ *http.Request) (*providers.BackChannelLogout, error) {
+	if tp.BackChannelLogout == nil {
+		return nil, errors.New("invalid logout request")
+	}
+	return tp.BackChannelLogout, nil
+}
+
 func Test_redeemCode(t *testing.T) {
 	opts := baseTestOptions()
 	err := validation.Validate(opts)
@@ -842,6 +860,11 @@ func NewProcessCookieTest(opts ProcessCookieTestOpts, modifiers ...OptionsModifi
 	for _, group := range groups {
 		testProvider.ProviderData.AllowedGroups[group] = struct{}{}
 	}
+	tenants := pcTest.opts.Providers[0].AllowedTenants
+	testProvider.ProviderData.AllowedTenants = make(map[string]struct{}, len(tenants))
+	for _, tenant := range tenants {
+		testProvider.ProviderData.AllowedTenants[tenant] = struct{}{}
+	}
 	pcTest.proxy.provider = testProvider
 
 	// Now, zero-out proxy.CookieRefresh for the cases that don't involve
@@ -900,6 +923,48 @@ func TestLoadCookiedSession(t *testing.T) {
 	assert.Equal(t, startSession.AccessToken, session.AccessToken)
 }
 
+func TestSaveSessionRecordsAuthenticationTime(t *testing.T) {
+	pcTest, err := NewProcessCookieTestWithDefaults()
+	require.NoError(t, err)
+
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	err = pcTest.SaveSession(&sessions.SessionState{Email: "john.doe@example.com", AccessToken: "my_access_token", CreatedAt: &created})
+	require.NoError(t, err)
+
+	session, err := pcTest.LoadCookiedSession()
+	require.NoError(t, err)
+	require.NotNil(t, session.AuthenticatedAt)
+	assert.True(t, session.AuthenticatedAt.Equal(created))
+}
+
+type startedServer struct{}
+
+func (startedServer) Start(_ context.Context) error {
+	return nil
+}
+
+type closedSessionStore struct {
+	sessions.SessionStore
+	closed bool
+}
+
+func (s *closedSessionStore) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestStartClosesTheSessionStore(t *testing.T) {
+	pcTest, err := NewProcessCookieTestWithDefaults()
+	require.NoError(t, err)
+
+	store := &closedSessionStore{SessionStore: pcTest.proxy.sessionStore}
+	pcTest.proxy.sessionStore = store
+	pcTest.proxy.server = startedServer{}
+
+	require.NoError(t, pcTest.proxy.Start())
+	assert.True(t, store.closed)
+}
+
 func TestProcessCookieNoCookieError(t *testing.T) {
 	pcTest, err := NewProcessCookieTestWithDefaults()
 	if err != nil {
@@ -1060,6 +1125,897 @@ func TestUserInfoEndpointUnauthorizedOnNoCookieSetError(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, test.rw.Code)
 }
 
+func TestSwitchTenantEndpoint(t *testing.T) {
+	testCases := []struct {
+		name               string
+		method             string
+		headers            map[string]string
+		allowedGroups      []string
+		allowedTenants     []string
+		tenant             string
+		expectedStatusCode int
+		expectedTenant     string
+	}{
+		{
+			name:               "SwitchToMemberTenant",
+			method:             http.MethodPost,
+			tenant:             "b",
+			expectedStatusCode: http.StatusOK,
+			expectedTenant:     "b",
+		},
+		{
+			name:               "SwitchToNonMemberTenant",
+			method:             http.MethodPost,
+			tenant:             "c",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "SwitchToDisallowedTenant",
+			method:             http.MethodPost,
+			allowedTenants:     []string{"a"},
+			tenant:             "b",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "MissingTenant",
+			method:             http.MethodPost,
+			tenant:             "",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "MethodNotAllowed",
+			method:             http.MethodGet,
+			tenant:             "b",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:               "SwitchFromSameOrigin",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Sec-Fetch-Site": "same-origin"},
+			tenant:             "b",
+			expectedStatusCode: http.StatusOK,
+			expectedTenant:     "b",
+		},
+		{
+			name:               "SwitchFromAnotherSite",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Sec-Fetch-Site": "cross-site"},
+			tenant:             "b",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "SwitchFromAnotherOrigin",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Origin": "https://evil.example.com"},
+			tenant:             "b",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "SwitchWithUnauthorizedSession",
+			method:             http.MethodPost,
+			allowedGroups:      []string{"admins"},
+			tenant:             "b",
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+				opts.Providers[0].AllowedTenants = tc.allowedTenants
+				opts.Providers[0].AllowedGroups = tc.allowedGroups
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			form := url.Values{}
+			form.Set("tenant", tc.tenant)
+			test.req, _ = http.NewRequest(tc.method, test.opts.ProxyPrefix+"/tenant", strings.NewReader(form.Encode()))
+			test.req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for name, value := range tc.headers {
+				test.req.Header.Set(name, value)
+			}
+
+			created := time.Now()
+			err = test.SaveSession(&sessions.SessionState{
+				User:        "john.doe",
+				Email:       "john.doe@example.com",
+				Tenant:      "a",
+				Tenants:     []string{"a", "b"},
+				AccessToken: "my_access_token",
+				CreatedAt:   &created,
+			})
+			assert.NoError(t, err)
+			test.rw = httptest.NewRecorder()
+
+			test.proxy.ServeHTTP(test.rw, test.req)
+			assert.Equal(t, tc.expectedStatusCode, test.rw.Code)
+			if tc.expectedTenant == "" {
+				// The session is not saved, at most cleared
+				for _, c := range test.rw.Result().Cookies() {
+					assert.Empty(t, c.Value)
+				}
+				return
+			}
+
+			bodyBytes, _ := io.ReadAll(test.rw.Body)
+			assert.Contains(t, string(bodyBytes), fmt.Sprintf("\"tenant\":%q", tc.expectedTenant))
+
+			loadReq, _ := http.NewRequest("GET", "/", nil)
+			for _, c := range test.rw.Result().Cookies() {
+				loadReq.AddCookie(c)
+			}
+			session, err := test.proxy.LoadCookiedSession(loadReq)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedTenant, session.Tenant)
+			assert.Equal(t, []string{"a", "b"}, session.Tenants)
+		})
+	}
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	signKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	t.Run("JWT session store", func(t *testing.T) {
+		opts := baseTestOptions()
+		opts.Session.Type = options.JWTSessionStoreType
+		opts.Session.JWT.JWTKey = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(signKey),
+		}))
+		require.NoError(t, validation.Validate(opts))
+
+		proxy, err := NewOAuthProxy(opts, func(string) bool { return true })
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/oauth2/jwks.json", nil)
+		proxy.ServeHTTP(rw, req)
+
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, applicationJSON, rw.Header().Get("Content-Type"))
+
+		jwks := jose.JSONWebKeySet{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &jwks))
+		require.Len(t, jwks.Keys, 1)
+		assert.Equal(t, proxy.jwtKeys.Active().ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, &signKey.PublicKey, jwks.Keys[0].Key)
+	})
+
+	t.Run("Cookie session store", func(t *testing.T) {
+		opts := baseTestOptions()
+		require.NoError(t, validation.Validate(opts))
+
+		proxy, err := NewOAuthProxy(opts, func(string) bool { return true })
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/oauth2/jwks.json", nil)
+		proxy.ServeHTTP(rw, req)
+
+		// Without the JWT session store the path is not served and requires
+		// authentication like any other upstream path
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("Cookie session store with a JWT key", func(t *testing.T) {
+		opts := baseTestOptions()
+		opts.Session.JWT.JWTKey = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(signKey),
+		}))
+		opts.InjectRequestHeaders = append(opts.InjectRequestHeaders, options.Header{
+			Name:   "X-Auth-Request-Jwt",
+			Values: []options.HeaderValue{{JWT: &options.JWTSource{}}},
+		})
+		require.NoError(t, validation.Validate(opts))
+
+		proxy, err := NewOAuthProxy(opts, func(string) bool { return true })
+		require.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/oauth2/jwks.json", nil)
+		proxy.ServeHTTP(rw, req)
+
+		// The key signing the injected JWTs is published
+		assert.Equal(t, http.StatusOK, rw.Code)
+		jwks := jose.JSONWebKeySet{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &jwks))
+		require.Len(t, jwks.Keys, 1)
+		assert.Equal(t, &signKey.PublicKey, jwks.Keys[0].Key)
+	})
+}
+
+func TestBackChannelLogoutEndpoint(t *testing.T) {
+	testCases := []struct {
+		name               string
+		method             string
+		logout             *providers.BackChannelLogout
+		trustedIPs         []string
+		expectedStatusCode int
+		expectRevoked      bool
+	}{
+		{
+			name:               "LogoutUser",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "john.doe"},
+			expectedStatusCode: http.StatusOK,
+			expectRevoked:      true,
+		},
+		{
+			name:               "LogoutSession",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "john.doe", SessionID: "session-1"},
+			expectedStatusCode: http.StatusOK,
+			expectRevoked:      true,
+		},
+		{
+			name:               "LogoutOtherSession",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "john.doe", SessionID: "session-2"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "LogoutOtherUser",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "jane.doe"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "UnsignedLogoutFromTrustedIP",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "john.doe", Unsigned: true},
+			trustedIPs:         []string{"10.0.0.0/24"},
+			expectedStatusCode: http.StatusOK,
+			expectRevoked:      true,
+		},
+		{
+			name:               "UnsignedLogoutFromUntrustedIP",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "john.doe", Unsigned: true},
+			trustedIPs:         []string{"10.0.1.0/24"},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "UnsignedLogoutWithoutTrustedIPs",
+			method:             http.MethodPost,
+			logout:             &providers.BackChannelLogout{User: "john.doe", Unsigned: true},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "InvalidLogoutRequest",
+			method:             http.MethodPost,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "MethodNotAllowed",
+			method:             http.MethodGet,
+			logout:             &providers.BackChannelLogout{User: "john.doe"},
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+				opts.BackChannelLogoutTrustedIPs = tc.trustedIPs
+			})
+			require.NoError(t, err)
+			test.proxy.provider.(*TestProvider).BackChannelLogout = tc.logout
+
+			test.req, _ = http.NewRequest(http.MethodGet, test.opts.ProxyPrefix+authOnlyPath, nil)
+			created := time.Now().Add(-time.Minute)
+			require.NoError(t, test.SaveSession(&sessions.SessionState{
+				User:        "john.doe",
+				Email:       "john.doe@example.com",
+				SessionID:   "session-1",
+				AccessToken: "my_access_token",
+				CreatedAt:   &created,
+			}))
+
+			rw := httptest.NewRecorder()
+			logoutReq, _ := http.NewRequest(tc.method, test.opts.ProxyPrefix+backChannelLogoutPath, nil)
+			logoutReq.RemoteAddr = "10.0.0.1:43210"
+			test.proxy.ServeHTTP(rw, logoutReq)
+			assert.Equal(t, tc.expectedStatusCode, rw.Code)
+			if tc.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, "no-store", rw.Header().Get("Cache-Control"))
+			}
+
+			rw = httptest.NewRecorder()
+			test.proxy.ServeHTTP(rw, test.req)
+			if tc.expectRevoked {
+				assert.Equal(t, http.StatusUnauthorized, rw.Code)
+			} else {
+				assert.Equal(t, http.StatusAccepted, rw.Code)
+			}
+		})
+	}
+}
+
+func TestSISBackChannelLogoutRequest(t *testing.T) {
+	const logoutRequest = `<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ` +
+		`xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="LR-1" Version="2.0">` +
+		`<saml:NameID>john.doe</saml:NameID><samlp:SessionIndex>ST-1</samlp:SessionIndex></samlp:LogoutRequest>`
+
+	testCases := []struct {
+		name               string
+		remoteAddr         string
+		expectedStatusCode int
+		expectRevoked      bool
+	}{
+		{
+			name:               "TrustedIP",
+			remoteAddr:         "10.0.0.1:43210",
+			expectedStatusCode: http.StatusOK,
+			expectRevoked:      true,
+		},
+		{
+			name:               "UntrustedIP",
+			remoteAddr:         "192.0.2.1:43210",
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+				opts.BackChannelLogoutTrustedIPs = []string{"10.0.0.0/24"}
+			})
+			require.NoError(t, err)
+			test.proxy.provider = providers.NewSISProvider(&providers.ProviderData{}, options.SISOptions{})
+
+			test.req, _ = http.NewRequest(http.MethodGet, test.opts.ProxyPrefix+authOnlyPath, nil)
+			created := time.Now().Add(-time.Minute)
+			require.NoError(t, test.SaveSession(&sessions.SessionState{
+				User:        "john.doe",
+				Email:       "john.doe@example.com",
+				AccessToken: "my_access_token",
+				CreatedAt:   &created,
+			}))
+
+			form := url.Values{"logoutRequest": {logoutRequest}}
+			logoutReq, _ := http.NewRequest(http.MethodPost, test.opts.ProxyPrefix+backChannelLogoutPath, strings.NewReader(form.Encode()))
+			logoutReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			logoutReq.RemoteAddr = tc.remoteAddr
+			rw := httptest.NewRecorder()
+			test.proxy.ServeHTTP(rw, logoutReq)
+			assert.Equal(t, tc.expectedStatusCode, rw.Code)
+
+			rw = httptest.NewRecorder()
+			test.proxy.ServeHTTP(rw, test.req)
+			if tc.expectRevoked {
+				assert.Equal(t, http.StatusUnauthorized, rw.Code)
+			} else {
+				assert.Equal(t, http.StatusAccepted, rw.Code)
+			}
+		})
+	}
+}
+
+func TestSignOutEverywhere(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		query         string
+		secFetchSite  string
+		expectedCode  int
+		expectRevoked bool
+	}{
+		{
+			name:          "SignOutAll",
+			method:        http.MethodPost,
+			query:         "?all=true",
+			secFetchSite:  "same-origin",
+			expectedCode:  http.StatusFound,
+			expectRevoked: true,
+		},
+		{
+			name:         "SignOut",
+			method:       http.MethodGet,
+			query:        "",
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:         "SignOutAllWithGet",
+			method:       http.MethodGet,
+			query:        "?all=true",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "SignOutAllFromAnotherSite",
+			method:       http.MethodPost,
+			query:        "?all=true",
+			secFetchSite: "cross-site",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test, err := NewProcessCookieTestWithDefaults()
+			require.NoError(t, err)
+
+			// Two sessions of the same user, as if on two devices
+			newSessionRequest := func() *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, test.opts.ProxyPrefix+authOnlyPath, nil)
+				created := time.Now().Add(-time.Minute)
+				rw := httptest.NewRecorder()
+				require.NoError(t, test.proxy.SaveSession(rw, req, &sessions.SessionState{
+					User:        "john.doe",
+					Email:       "john.doe@example.com",
+					AccessToken: "my_access_token",
+					CreatedAt:   &created,
+				}))
+				for _, cookie := range rw.Result().Cookies() {
+					req.AddCookie(cookie)
+				}
+				return req
+			}
+			device1 := newSessionRequest()
+			device2 := newSessionRequest()
+
+			rw := httptest.NewRecorder()
+			signOutReq, _ := http.NewRequest(tc.method, test.opts.ProxyPrefix+signOutPath+tc.query, strings.NewReader(""))
+			if tc.secFetchSite != "" {
+				signOutReq.Header.Set("Sec-Fetch-Site", tc.secFetchSite)
+			}
+			for _, cookie := range device1.Cookies() {
+				signOutReq.AddCookie(cookie)
+			}
+			test.proxy.ServeHTTP(rw, signOutReq)
+			assert.Equal(t, tc.expectedCode, rw.Code)
+
+			rw = httptest.NewRecorder()
+			test.proxy.ServeHTTP(rw, device2)
+			if tc.expectRevoked {
+				assert.Equal(t, http.StatusUnauthorized, rw.Code)
+			} else {
+				assert.Equal(t, http.StatusAccepted, rw.Code)
+			}
+		})
+	}
+}
+
+func TestLimitUserSessions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		policy        string
+		expectAllowed bool
+	}{
+		{
+			name:          "Evict",
+			policy:        options.EvictSessionLimitPolicy,
+			expectAllowed: true,
+		},
+		{
+			name:   "Reject",
+			policy: options.RejectSessionLimitPolicy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+				opts.Session.Type = options.FileSessionStoreType
+				opts.Session.File.Path = filepath.Join(t.TempDir(), "sessions.db")
+				opts.Session.MaxPerUser = 1
+				opts.Session.LimitPolicy = tc.policy
+			})
+			require.NoError(t, err)
+
+			session := func() *sessions.SessionState {
+				created := time.Now().Add(-time.Minute)
+				return &sessions.SessionState{
+					User:        "john.doe",
+					Email:       "john.doe@example.com",
+					AccessToken: "my_access_token",
+					CreatedAt:   &created,
+				}
+			}
+
+			// The first device of the user is signed in
+			device1, _ := http.NewRequest(http.MethodGet, test.opts.ProxyPrefix+authOnlyPath, nil)
+			rw := httptest.NewRecorder()
+			require.NoError(t, test.proxy.SaveSession(rw, device1, session()))
+			for _, cookie := range rw.Result().Cookies() {
+				device1.AddCookie(cookie)
+			}
+
+			// The second device signs in
+			rw = httptest.NewRecorder()
+			signInReq, _ := http.NewRequest(http.MethodGet, test.opts.ProxyPrefix+oauthCallbackPath, nil)
+			signInReq = middlewareapi.AddRequestScope(signInReq, &middlewareapi.RequestScope{})
+			allowed := test.proxy.limitUserSessions(rw, signInReq, session())
+			assert.Equal(t, tc.expectAllowed, allowed)
+			if !tc.expectAllowed {
+				assert.Equal(t, http.StatusForbidden, rw.Code)
+			}
+
+			rw = httptest.NewRecorder()
+			test.proxy.ServeHTTP(rw, device1)
+			if tc.expectAllowed {
+				assert.Equal(t, http.StatusUnauthorized, rw.Code)
+			} else {
+				assert.Equal(t, http.StatusAccepted, rw.Code)
+			}
+		})
+	}
+}
+
+func TestSessionClientBinding(t *testing.T) {
+	test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+		opts.Session.Bind = []string{options.UserAgentSessionBinding}
+	})
+	require.NoError(t, err)
+
+	newRequest := func(userAgent string) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, test.opts.ProxyPrefix+authOnlyPath, nil)
+		req.Header.Set("User-Agent", userAgent)
+		return req
+	}
+
+	signInReq := newRequest("Mozilla/5.0")
+	rw := httptest.NewRecorder()
+	require.NoError(t, test.proxy.SaveSession(rw, signInReq, &sessions.SessionState{
+		User:        "john.doe",
+		Email:       "john.doe@example.com",
+		AccessToken: "my_access_token",
+	}))
+	cookies := rw.Result().Cookies()
+
+	for userAgent, expectedCode := range map[string]int{
+		"Mozilla/5.0": http.StatusAccepted,
+		"curl/8.0":    http.StatusUnauthorized,
+	} {
+		req := newRequest(userAgent)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		assert.Equal(t, expectedCode, rw.Code, userAgent)
+	}
+}
+
+func TestUpstreamAuthorization(t *testing.T) {
+	test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+		opts.UpstreamServers = options.UpstreamConfig{
+			Upstreams: []options.Upstream{
+				{
+					ID:     "admin",
+					Path:   "/admin/",
+					Static: true,
+					Authorization: &options.UpstreamAuthorization{
+						AllowedGroups: []string{"admins"},
+					},
+				},
+				{
+					ID:     "app",
+					Path:   "/",
+					Static: true,
+				},
+			},
+		}
+	})
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+		User:        "john.doe",
+		Email:       "john.doe@example.com",
+		Groups:      []string{"developers"},
+		AccessToken: "my_access_token",
+	}))
+	cookies := rw.Result().Cookies()
+
+	for path, expectedCode := range map[string]int{
+		"/app":         http.StatusOK,
+		"/admin/users": http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		assert.Equal(t, expectedCode, rw.Code, path)
+	}
+}
+
+func TestAuthorizationRules(t *testing.T) {
+	test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+		opts.AuthorizationRules = []string{"'admins' in groups || request.method == 'GET'"}
+	})
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+		User:        "john.doe",
+		Email:       "john.doe@example.com",
+		Groups:      []string{"developers"},
+		AccessToken: "my_access_token",
+	}))
+	cookies := rw.Result().Cookies()
+
+	for method, expectedCode := range map[string]int{
+		http.MethodGet:  http.StatusAccepted,
+		http.MethodPost: http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest(method, test.opts.ProxyPrefix+authOnlyPath, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		assert.Equal(t, expectedCode, rw.Code, method)
+	}
+}
+
+func TestAuthorizationWebhook(t *testing.T) {
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var webhookReq authorization.WebhookRequest
+		if err := json.NewDecoder(req.Body).Decode(&webhookReq); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(rw).Encode(authorization.WebhookResponse{
+			Allowed: webhookReq.Method == http.MethodGet,
+			Headers: map[string]string{"X-Entitlements": "read"},
+		})
+	}))
+	t.Cleanup(webhookServer.Close)
+
+	test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+		opts.AuthorizationWebhook.URL = webhookServer.URL
+		opts.AuthorizationWebhook.Headers = []string{"X-Entitlements"}
+	})
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+		User:        "john.doe",
+		Email:       "john.doe@example.com",
+		AccessToken: "my_access_token",
+	}))
+	cookies := rw.Result().Cookies()
+
+	for method, expectedCode := range map[string]int{
+		http.MethodGet:  http.StatusAccepted,
+		http.MethodPost: http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest(method, test.opts.ProxyPrefix+authOnlyPath, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		assert.Equal(t, expectedCode, rw.Code, method)
+		if expectedCode == http.StatusAccepted {
+			assert.Equal(t, "read", rw.Header().Get("X-Entitlements"))
+		}
+	}
+}
+
+func TestAuthorizationWebhookSpoofedHeaders(t *testing.T) {
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(webhookServer.Close)
+
+	upstreamServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(req.Header.Get("X-Entitlements")))
+	}))
+	t.Cleanup(upstreamServer.Close)
+
+	test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+		opts.AuthorizationWebhook.URL = webhookServer.URL
+		opts.AuthorizationWebhook.FailurePolicy = options.AllowWebhookFailurePolicy
+		opts.AuthorizationWebhook.Headers = []string{"x-entitlements"}
+		opts.UpstreamServers = options.UpstreamConfig{
+			Upstreams: []options.Upstream{
+				{
+					ID:   "app",
+					Path: "/",
+					URI:  upstreamServer.URL,
+				},
+			},
+		}
+	})
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+		User:        "john.doe",
+		Email:       "john.doe@example.com",
+		AccessToken: "my_access_token",
+	}))
+	cookies := rw.Result().Cookies()
+
+	for _, path := range []string{"/documents", test.opts.ProxyPrefix + authOnlyPath} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Entitlements", "admin")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		assert.Less(t, rw.Code, http.StatusMultipleChoices, path)
+		assert.Empty(t, rw.Header().Get("X-Entitlements"), path)
+		assert.Empty(t, rw.Body.String(), path)
+	}
+}
+
+func TestExtAuthzCheck(t *testing.T) {
+	test, err := NewProcessCookieTestWithOptionsModifiers()
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+		User:        "john.doe",
+		Email:       "john.doe@example.com",
+		AccessToken: "my_access_token",
+	}))
+	cookies := rw.Result().Cookies()
+
+	check := func(req *http.Request) *httptest.ResponseRecorder {
+		req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{})
+		rw := httptest.NewRecorder()
+		test.proxy.sessionChain.ThenFunc(test.proxy.ExtAuthzCheck).ServeHTTP(rw, req)
+		return rw
+	}
+
+	t.Run("redirects the browsers without a session to sign in", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://app.example.com/documents?page=2", nil)
+		rw := check(req)
+		assert.Equal(t, http.StatusFound, rw.Code)
+		assert.Equal(t, "/oauth2/sign_in?rd=%2Fdocuments%3Fpage%3D2", rw.Header().Get("Location"))
+	})
+
+	t.Run("denies the ajax requests without a session", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://app.example.com/documents", nil)
+		req.Header.Set("Accept", "application/json")
+		rw := check(req)
+		assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	})
+
+	t.Run("accepts the requests with a session", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://app.example.com/documents", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := check(req)
+		assert.Equal(t, http.StatusAccepted, rw.Code)
+		assert.Equal(t, "john.doe@example.com", rw.Header().Get("GAP-Auth"))
+	})
+}
+
+func TestForwardAuth(t *testing.T) {
+	newForwardAuthTest := func(skipProviderButton bool) *ProcessCookieTest {
+		test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+			opts.ReverseProxy = true
+			opts.WhitelistDomains = []string{".example.com"}
+			opts.Cookie.Domains = []string{".example.com"}
+			opts.SkipProviderButton = skipProviderButton
+			opts.AuthorizationRules = []string{`request.method != "DELETE"`}
+		})
+		require.NoError(t, err)
+		return test
+	}
+
+	forwardAuth := func(test *ProcessCookieTest, method string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://oauth2-proxy:4180"+test.opts.ProxyPrefix+forwardAuthPath, nil)
+		req.Header.Set("X-Forwarded-Method", method)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "app.example.com")
+		req.Header.Set("X-Forwarded-Uri", "/documents?page=2")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		return rw
+	}
+
+	t.Run("redirects the browsers without a session to sign in", func(t *testing.T) {
+		rw := forwardAuth(newForwardAuthTest(false), http.MethodGet, nil)
+		assert.Equal(t, http.StatusFound, rw.Code)
+		assert.Equal(t, "/oauth2/sign_in?rd=https%3A%2F%2Fapp.example.com%2Fdocuments%3Fpage%3D2", rw.Header().Get("Location"))
+	})
+
+	t.Run("starts the OAuth flow for the forwarded host when the provider button is skipped", func(t *testing.T) {
+		test := newForwardAuthTest(true)
+		test.proxy.provider.Data().LoginURL = &url.URL{Scheme: "https", Host: "idp.example.com", Path: "/authorize"}
+
+		rw := forwardAuth(test, http.MethodGet, nil)
+		assert.Equal(t, http.StatusFound, rw.Code)
+
+		loginURL, err := url.Parse(rw.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/oauth2/callback", loginURL.Query().Get("redirect_uri"))
+		assert.True(t, strings.HasSuffix(loginURL.Query().Get("state"), ":https://app.example.com/documents?page=2"))
+
+		csrfCookies := rw.Result().Cookies()
+		require.Len(t, csrfCookies, 1)
+		assert.True(t, strings.HasSuffix(csrfCookies[0].Name, "_csrf"))
+		assert.Equal(t, "example.com", csrfCookies[0].Domain)
+	})
+
+	t.Run("accepts the requests with a session", func(t *testing.T) {
+		test := newForwardAuthTest(false)
+		rw := httptest.NewRecorder()
+		require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+			User:        "john.doe",
+			Email:       "john.doe@example.com",
+			AccessToken: "my_access_token",
+		}))
+		cookies := rw.Result().Cookies()
+
+		rw = forwardAuth(test, http.MethodGet, cookies)
+		assert.Equal(t, http.StatusAccepted, rw.Code)
+		assert.Equal(t, "john.doe@example.com", rw.Header().Get("GAP-Auth"))
+
+		rw = forwardAuth(test, http.MethodDelete, cookies)
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	forwardAuthURI := func(test *ProcessCookieTest, method, uri string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://oauth2-proxy:4180"+test.opts.ProxyPrefix+forwardAuthPath+"?allowed_groups=admins", nil)
+		req.Header.Set("X-Forwarded-Method", method)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "app.example.com")
+		req.Header.Set("X-Forwarded-Uri", uri)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rw := httptest.NewRecorder()
+		test.proxy.ServeHTTP(rw, req)
+		return rw
+	}
+
+	t.Run("checks the forwarded method and URI together", func(t *testing.T) {
+		test, err := NewProcessCookieTestWithOptionsModifiers(func(opts *options.Options) {
+			opts.ReverseProxy = true
+			opts.WhitelistDomains = []string{".example.com"}
+			opts.APIRoutes = []string{"^/api/"}
+			opts.SkipAuthRoutes = []string{"GET=^/public/"}
+		})
+		require.NoError(t, err)
+
+		rw := forwardAuthURI(test, http.MethodGet, "/api/documents?page=2", nil)
+		assert.Equal(t, http.StatusUnauthorized, rw.Code)
+
+		rw = forwardAuthURI(test, http.MethodGet, "/public/logo.png", nil)
+		assert.Equal(t, http.StatusAccepted, rw.Code)
+
+		rw = forwardAuthURI(test, http.MethodPost, "/public/logo.png", nil)
+		assert.Equal(t, http.StatusFound, rw.Code)
+
+		rw = forwardAuthURI(test, http.MethodGet, "/documents", nil)
+		assert.Equal(t, http.StatusFound, rw.Code)
+	})
+
+	t.Run("reads the allowed groups from the forward auth URL only", func(t *testing.T) {
+		test := newForwardAuthTest(false)
+		rw := httptest.NewRecorder()
+		require.NoError(t, test.proxy.SaveSession(rw, test.req, &sessions.SessionState{
+			User:        "john.doe",
+			Email:       "john.doe@example.com",
+			AccessToken: "my_access_token",
+			Groups:      []string{"users"},
+		}))
+
+		rw = forwardAuthURI(test, http.MethodGet, "/documents?allowed_groups=users", rw.Result().Cookies())
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+	})
+
+	t.Run("rejects the invalid forwarded URIs", func(t *testing.T) {
+		rw := forwardAuthURI(newForwardAuthTest(false), http.MethodGet, "documents", nil)
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+}
+
 func TestEncodedUrlsStayEncoded(t *testing.T) {
 	encodeTest, err := NewSignInPageTest(false)
 	if err != nil {
@@ -3174,6 +4130,107 @@ func TestAuthOnlyAllowedGroups(t *testing.T) {
 	}
 }
 
+func TestAuthOnlyAllowedTenants(t *testing.T) {
+	testCases := []struct {
+		name               string
+		allowedTenants     []string
+		tenant             string
+		querystring        string
+		expectedStatusCode int
+	}{
+		{
+			name:               "NoAllowedTenants",
+			allowedTenants:     []string{},
+			tenant:             "",
+			querystring:        "",
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "NoAllowedTenantsUserHasTenant",
+			allowedTenants:     []string{},
+			tenant:             "a",
+			querystring:        "",
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "UserInAllowedTenant",
+			allowedTenants:     []string{"a", "b"},
+			tenant:             "a",
+			querystring:        "",
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "UserNotInAllowedTenant",
+			allowedTenants:     []string{"a"},
+			tenant:             "c",
+			querystring:        "",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "UserInQuerystringTenant",
+			allowedTenants:     []string{"a", "b"},
+			tenant:             "b",
+			querystring:        "?allowed_tenants=b",
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "UserInMultiParamQuerystringTenant",
+			allowedTenants:     []string{},
+			tenant:             "b",
+			querystring:        "?allowed_tenants=a&allowed_tenants=b,d",
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "UserNotInQuerystringTenant",
+			allowedTenants:     []string{},
+			tenant:             "c",
+			querystring:        "?allowed_tenants=a,b",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "UserInConfigTenantNotInQuerystringTenant",
+			allowedTenants:     []string{"a", "b", "c"},
+			tenant:             "c",
+			querystring:        "?allowed_tenants=a,b",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "UserWithoutTenantAndQuerystringTenant",
+			allowedTenants:     []string{},
+			tenant:             "",
+			querystring:        "?allowed_tenants=a",
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			created := time.Now()
+
+			session := &sessions.SessionState{
+				Tenant:      tc.tenant,
+				Email:       "test",
+				AccessToken: "oauth_token",
+				CreatedAt:   &created,
+			}
+
+			test, err := NewAuthOnlyEndpointTest(tc.querystring, func(opts *options.Options) {
+				opts.Providers[0].AllowedTenants = tc.allowedTenants
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = test.SaveSession(session)
+			assert.NoError(t, err)
+
+			test.proxy.ServeHTTP(test.rw, test.req)
+
+			assert.Equal(t, tc.expectedStatusCode, test.rw.Code)
+		})
+	}
+}
+
 func TestAuthOnlyAllowedGroupsWithSkipMethods(t *testing.T) {
 	testCases := []struct {
 		name               string
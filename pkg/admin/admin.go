@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+)
+
+const (
+	sessionsPath     = "/sessions"
+	sessionPath      = "/sessions/{id}"
+	userSessionsPath = "/users/{user}/sessions"
+
+	applicationJSON = "application/json"
+)
+
+// Opts contains the dependencies of the session administration API
+type Opts struct {
+	// SessionChain loads the session of the administrator, it must be
+	// preceded by a request scope
+	SessionChain alice.Chain
+
+	SessionStore         sessionsapi.SessionStore
+	RevocationList       sessionsapi.RevocationList
+	RevocationExpiration time.Duration
+
+	// AuthorizeSession checks the session of the administrator like the proxy
+	// checks the sessions of its users, before the AllowedGroups
+	AuthorizeSession func(req *http.Request, s *sessionsapi.SessionState) bool
+
+	// AllowedGroups are the groups of the users allowed to use the API
+	AllowedGroups []string
+}
+
+// api serves the session administration endpoints
+type api struct {
+	sessionAdmin         sessionsapi.SessionAdmin
+	sessionStore         sessionsapi.SessionStore
+	revocationList       sessionsapi.RevocationList
+	revocationExpiration time.Duration
+	authorizeSession     func(req *http.Request, s *sessionsapi.SessionState) bool
+	allowedGroups        map[string]struct{}
+}
+
+// NewHandler creates the handler of the session administration API, which
+// lists the stored sessions and ends them individually or per user.
+// It requires a session store that can list its sessions.
+func NewHandler(opts Opts) (http.Handler, error) {
+	sessionAdmin, ok := opts.SessionStore.(sessionsapi.SessionAdmin)
+	if !ok {
+		return nil, errors.New("the session store cannot list its sessions")
+	}
+
+	a := &api{
+		sessionAdmin:         sessionAdmin,
+		sessionStore:         opts.SessionStore,
+		revocationList:       opts.RevocationList,
+		revocationExpiration: opts.RevocationExpiration,
+		authorizeSession:     opts.AuthorizeSession,
+		allowedGroups:        make(map[string]struct{}, len(opts.AllowedGroups)),
+	}
+	for _, group := range opts.AllowedGroups {
+		a.allowedGroups[group] = struct{}{}
+	}
+
+	// The encoded path is kept so that user names can contain slashes
+	r := mux.NewRouter().UseEncodedPath()
+	r.Use(opts.SessionChain.Then, a.authorize)
+	r.Path(sessionsPath).Methods(http.MethodGet).HandlerFunc(a.listSessions)
+	r.Path(sessionPath).Methods(http.MethodDelete).HandlerFunc(a.clearSession)
+	r.Path(userSessionsPath).Methods(http.MethodDelete).HandlerFunc(a.clearUserSessions)
+	return r, nil
+}
+
+// authorize only lets the members of the allowed groups through, with a
+// session the proxy would accept
+func (a *api) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		session := middlewareapi.GetRequestScope(req).Session
+		if session == nil {
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if a.authorizeSession != nil && !a.authorizeSession(req, session) {
+			logger.PrintAuthf(session.Email, req, logger.AuthFailure, "Session administration denied to an unauthorized session")
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		for _, group := range session.Groups {
+			if _, ok := a.allowedGroups[group]; ok {
+				next.ServeHTTP(rw, req)
+				return
+			}
+		}
+
+		logger.PrintAuthf(session.Email, req, logger.AuthFailure, "Session administration denied to a user outside the admin groups")
+		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+// listSessions lists the metadata of the stored sessions, of the user given
+// in the user query parameter or of every user
+func (a *api) listSessions(rw http.ResponseWriter, req *http.Request) {
+	list, err := a.sessionAdmin.ListSessions(req.Context())
+	if err != nil {
+		logger.Errorf("Error listing sessions: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if user := req.URL.Query().Get("user"); user != "" {
+		userList := []sessionsapi.SessionMetadata{}
+		for _, metadata := range list {
+			if metadata.User == user {
+				userList = append(userList, metadata)
+			}
+		}
+		list = userList
+	}
+
+	rw.Header().Set("Content-Type", applicationJSON)
+	rw.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(rw).Encode(map[string]interface{}{"sessions": list}); err != nil {
+		logger.Errorf("Error encoding sessions: %v", err)
+	}
+}
+
+// clearSession ends the session with the ID in the path
+func (a *api) clearSession(rw http.ResponseWriter, req *http.Request) {
+	id, err := pathVar(req, "id")
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = a.sessionAdmin.ClearSession(req.Context(), id)
+	switch {
+	case errors.Is(err, sessionsapi.ErrSessionNotFound):
+		http.Error(rw, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	case err != nil:
+		logger.Errorf("Error clearing session: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	logger.PrintAuthf(adminEmail(req), req, logger.AuthSuccess, "Session administration cleared session %q", id)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// clearUserSessions ends every session of the user in the path
+func (a *api) clearUserSessions(rw http.ResponseWriter, req *http.Request) {
+	user, err := pathVar(req, "user")
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := sessions.EndUserSessions(req.Context(), a.sessionStore, a.revocationList, user, a.revocationExpiration); err != nil {
+		logger.Errorf("Error ending the sessions of the user: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	logger.PrintAuthf(adminEmail(req), req, logger.AuthSuccess, "Session administration ended every session of %q", user)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// pathVar returns the decoded variable of the encoded path
+func pathVar(req *http.Request, name string) (string, error) {
+	value, err := url.PathUnescape(mux.Vars(req)[name])
+	if err != nil || value == "" {
+		return "", fmt.Errorf("invalid %s", name)
+	}
+	return value, nil
+}
+
+func adminEmail(req *http.Request) string {
+	return middlewareapi.GetRequestScope(req).Session.Email
+}
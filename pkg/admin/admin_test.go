@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/justinas/alice"
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/cookie"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/tests"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admin API Suite", func() {
+	var (
+		store          *persistence.Manager
+		revocationList sessionsapi.RevocationList
+		handler        http.Handler
+		adminSession   *sessionsapi.SessionState
+		authorized     bool
+		cookies        map[string][]*http.Cookie
+	)
+
+	cookieOpts := &options.Cookie{
+		Name:   "_oauth2_proxy",
+		Secret: "0123456789abcdef0123456789abcdef",
+		Expire: time.Hour,
+	}
+
+	saveSession := func(name, user string) {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		Expect(store.Save(rw, req, &sessionsapi.SessionState{User: user, Email: user})).To(Succeed())
+		cookies[name] = rw.Result().Cookies()
+	}
+
+	loadSession := func(name string) (*sessionsapi.SessionState, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies[name] {
+			req.AddCookie(c)
+		}
+		return store.Load(req)
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+		return rw
+	}
+
+	listSessions := func(path string) []sessionsapi.SessionMetadata {
+		rw := serve(http.MethodGet, path)
+		Expect(rw.Code).To(Equal(http.StatusOK))
+		Expect(rw.Header().Get("Content-Type")).To(Equal(applicationJSON))
+
+		var response struct {
+			Sessions []sessionsapi.SessionMetadata `json:"sessions"`
+		}
+		Expect(json.Unmarshal(rw.Body.Bytes(), &response)).To(Succeed())
+		return response.Sessions
+	}
+
+	BeforeEach(func() {
+		store = persistence.NewManager(tests.NewMockStore(), cookieOpts)
+		revocationList = sessions.NewMemoryRevocationList()
+		adminSession = &sessionsapi.SessionState{User: "admin", Email: "admin@example.com", Groups: []string{"admins"}}
+		authorized = true
+		cookies = map[string][]*http.Cookie{}
+
+		// The session of the administrator is given by the session chain
+		sessionChain := alice.New(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{Session: adminSession})
+				next.ServeHTTP(rw, req)
+			})
+		})
+
+		var err error
+		handler, err = NewHandler(Opts{
+			SessionChain:         sessionChain,
+			SessionStore:         store,
+			RevocationList:       revocationList,
+			RevocationExpiration: time.Hour,
+			AuthorizeSession: func(_ *http.Request, s *sessionsapi.SessionState) bool {
+				return authorized
+			},
+			AllowedGroups: []string{"admins"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		saveSession("john-laptop", "john@example.com")
+		saveSession("john-phone", "john@example.com")
+		saveSession("jane", "jane@example.com")
+	})
+
+	It("requires a session store that can list its sessions", func() {
+		cookieStore, err := cookie.NewCookieSessionStore(&options.SessionOptions{}, cookieOpts)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = NewHandler(Opts{SessionStore: cookieStore})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects the requests without a session", func() {
+		adminSession = nil
+		Expect(serve(http.MethodGet, sessionsPath).Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects the users outside the admin groups", func() {
+		adminSession.Groups = []string{"users"}
+		Expect(serve(http.MethodGet, sessionsPath).Code).To(Equal(http.StatusForbidden))
+		Expect(serve(http.MethodDelete, "/users/jane@example.com/sessions").Code).To(Equal(http.StatusForbidden))
+
+		_, err := loadSession("jane")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects the sessions the proxy does not authorize", func() {
+		authorized = false
+		Expect(serve(http.MethodGet, sessionsPath).Code).To(Equal(http.StatusForbidden))
+		Expect(serve(http.MethodDelete, "/users/jane@example.com/sessions").Code).To(Equal(http.StatusForbidden))
+
+		_, err := loadSession("jane")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("lists the sessions", func() {
+		list := listSessions(sessionsPath)
+		Expect(list).To(HaveLen(3))
+		users := []string{}
+		for _, metadata := range list {
+			users = append(users, metadata.User)
+		}
+		Expect(users).To(ConsistOf("john@example.com", "john@example.com", "jane@example.com"))
+	})
+
+	It("lists the sessions of a user", func() {
+		list := listSessions(sessionsPath + "?user=jane@example.com")
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].User).To(Equal("jane@example.com"))
+	})
+
+	It("clears a session", func() {
+		list := listSessions(sessionsPath + "?user=jane@example.com")
+		Expect(list).To(HaveLen(1))
+
+		Expect(serve(http.MethodDelete, sessionsPath+"/"+list[0].ID).Code).To(Equal(http.StatusNoContent))
+		_, err := loadSession("jane")
+		Expect(err).To(HaveOccurred())
+		Expect(listSessions(sessionsPath)).To(HaveLen(2))
+	})
+
+	It("returns not found for unknown sessions", func() {
+		Expect(serve(http.MethodDelete, sessionsPath+"/unknown").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("ends every session of a user", func() {
+		Expect(serve(http.MethodDelete, "/users/john%40example.com/sessions").Code).To(Equal(http.StatusNoContent))
+
+		for _, name := range []string{"john-laptop", "john-phone"} {
+			_, err := loadSession(name)
+			Expect(err).To(HaveOccurred())
+		}
+		_, err := loadSession("jane")
+		Expect(err).ToNot(HaveOccurred())
+
+		created := time.Now().Add(-time.Minute)
+		revoked, err := revocationList.IsRevoked(context.Background(), &sessionsapi.SessionState{User: "john@example.com", CreatedAt: &created})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(revoked).To(BeTrue())
+	})
+})
@@ -0,0 +1,29 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// AdminAPI contains the options of the session administration API
+type AdminAPI struct {
+	// BindAddress is the address the API is served on, it is disabled when
+	// it is empty
+	BindAddress string `flag:"admin-address" cfg:"admin_address"`
+	// AllowedGroups are the groups of the users allowed to use the API
+	AllowedGroups []string `flag:"admin-allowed-group" cfg:"admin_allowed_groups"`
+}
+
+func adminAPIFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("admin", pflag.ExitOnError)
+
+	flagSet.String("admin-address", "", "the address the session administration API will be served on (e.g. \":4181\"); disabled when empty")
+	flagSet.StringSlice("admin-allowed-group", []string{}, "restrict the session administration API to members of this group (may be given multiple times)")
+
+	return flagSet
+}
+
+// adminAPIDefaults creates an AdminAPI structure, populating each field with its default value
+func adminAPIDefaults() AdminAPI {
+	return AdminAPI{
+		BindAddress:   "",
+		AllowedGroups: nil,
+	}
+}
@@ -0,0 +1,58 @@
+package options
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// DenyWebhookFailurePolicy denies the requests when the authorization
+	// webhook cannot be reached or answers with an error
+	DenyWebhookFailurePolicy = "deny"
+
+	// AllowWebhookFailurePolicy allows the requests when the authorization
+	// webhook cannot be reached or answers with an error
+	AllowWebhookFailurePolicy = "allow"
+)
+
+// AuthorizationWebhook contains the options of the external service the
+// authorization of the requests is delegated to
+type AuthorizationWebhook struct {
+	// URL is the endpoint the requests are described to, the webhook is
+	// disabled when it is empty
+	URL string `flag:"authorization-webhook-url" cfg:"authorization_webhook_url"`
+	// Timeout is how long to wait for the decision of the webhook
+	Timeout time.Duration `flag:"authorization-webhook-timeout" cfg:"authorization_webhook_timeout"`
+	// CacheTTL is how long the decisions are reused for the same user,
+	// method and path, they are not cached when it is zero
+	CacheTTL time.Duration `flag:"authorization-webhook-cache-ttl" cfg:"authorization_webhook_cache_ttl"`
+	// FailurePolicy is whether the requests are allowed or denied when the
+	// webhook fails
+	FailurePolicy string `flag:"authorization-webhook-failure-policy" cfg:"authorization_webhook_failure_policy"`
+	// Headers are the names of the headers the webhook may set, they are
+	// removed from the requests of the clients
+	Headers []string `flag:"authorization-webhook-header" cfg:"authorization_webhook_headers"`
+}
+
+func authorizationWebhookFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("authorizationwebhook", pflag.ExitOnError)
+
+	flagSet.String("authorization-webhook-url", "", "endpoint of an external service that authorizes the requests of the sessions; disabled when empty")
+	flagSet.Duration("authorization-webhook-timeout", 5*time.Second, "maximum time to wait for the decision of the authorization webhook")
+	flagSet.Duration("authorization-webhook-cache-ttl", time.Minute, "how long the decisions of the authorization webhook are cached for the same user, method and path (0 disables the cache)")
+	flagSet.String("authorization-webhook-failure-policy", DenyWebhookFailurePolicy, "whether the requests are denied (\"deny\") or allowed (\"allow\") when the authorization webhook fails")
+	flagSet.StringSlice("authorization-webhook-header", []string{}, "name of a header the authorization webhook may set, removed from the requests of the clients (may be given multiple times)")
+
+	return flagSet
+}
+
+// authorizationWebhookDefaults creates an AuthorizationWebhook structure, populating each field with its default value
+func authorizationWebhookDefaults() AuthorizationWebhook {
+	return AuthorizationWebhook{
+		URL:           "",
+		Timeout:       5 * time.Second,
+		CacheTTL:      time.Minute,
+		FailurePolicy: DenyWebhookFailurePolicy,
+	}
+}
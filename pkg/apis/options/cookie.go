@@ -14,6 +14,7 @@ type Cookie struct {
 	Name                string        `flag:"cookie-name" cfg:"cookie_name"`
 	Secret              string        `flag:"cookie-secret" cfg:"cookie_secret"`
 	SecretFile          string        `flag:"cookie-secret-file" cfg:"cookie_secret_file"`
+	PreviousSecrets     []string      `flag:"cookie-previous-secret" cfg:"cookie_previous_secrets"`
 	Domains             []string      `flag:"cookie-domain" cfg:"cookie_domains"`
 	Path                string        `flag:"cookie-path" cfg:"cookie_path"`
 	Expire              time.Duration `flag:"cookie-expire" cfg:"cookie_expire"`
@@ -32,6 +33,7 @@ func cookieFlagSet() *pflag.FlagSet {
 	flagSet.String("cookie-name", "_oauth2_proxy", "the name of the cookie that the oauth_proxy creates")
 	flagSet.String("cookie-secret", "", "the seed string for secure cookies (optionally base64 encoded)")
 	flagSet.String("cookie-secret-file", "", "For defining a separate cookie secret file to read the encryption key from")
+	flagSet.StringSlice("cookie-previous-secret", []string{}, "previous cookie secrets, still accepted to validate and decrypt the cookies while rotating the cookie secret (may be given multiple times)")
 	flagSet.StringSlice("cookie-domain", []string{}, "Optional cookie domains to force cookies to (ie: `.yourcompany.com`). The longest domain matching the request's host will be used (or the shortest cookie domain if there is no match).")
 	flagSet.String("cookie-path", "/", "an optional cookie path to force cookies to (ie: /poc/)*")
 	flagSet.Duration("cookie-expire", time.Duration(168)*time.Hour, "expire timeframe for cookie")
@@ -51,6 +53,7 @@ func cookieDefaults() Cookie {
 		Name:                "_oauth2_proxy",
 		Secret:              "",
 		SecretFile:          "",
+		PreviousSecrets:     []string{},
 		Domains:             nil,
 		Path:                "/",
 		Expire:              time.Duration(168) * time.Hour,
@@ -78,3 +81,14 @@ func (c *Cookie) GetSecret() (secret string, err error) {
 
 	return string(fileSecret), nil
 }
+
+// GetSecrets returns the cookie secrets in order: the cookie secret signing
+// and encrypting the cookies, followed by the previous secrets only used to
+// validate and decrypt them
+func (c *Cookie) GetSecrets() ([]string, error) {
+	secret, err := c.GetSecret()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{secret}, c.PreviousSecrets...), nil
+}
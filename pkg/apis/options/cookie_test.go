@@ -68,3 +68,25 @@ func TestCookieGetSecret(t *testing.T) {
 		assert.Equal(t, "", secret)
 	})
 }
+
+func TestCookieGetSecrets(t *testing.T) {
+	t.Run("returns the secret first, followed by the previous secrets", func(t *testing.T) {
+		c := &Cookie{
+			Secret:          "my-secret",
+			PreviousSecrets: []string{"previous-secret", "older-secret"},
+		}
+		secrets, err := c.GetSecrets()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"my-secret", "previous-secret", "older-secret"}, secrets)
+	})
+
+	t.Run("returns error when the secret file does not exist", func(t *testing.T) {
+		c := &Cookie{
+			SecretFile:      "/nonexistent/file",
+			PreviousSecrets: []string{"previous-secret"},
+		}
+		secrets, err := c.GetSecrets()
+		assert.Error(t, err)
+		assert.Nil(t, secrets)
+	})
+}
@@ -0,0 +1,26 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// EnvoyExtAuthz contains the options of the Envoy ext_authz gRPC
+// authorization server
+type EnvoyExtAuthz struct {
+	// BindAddress is the address the gRPC server is served on, it is
+	// disabled when it is empty
+	BindAddress string `flag:"envoy-ext-authz-address" cfg:"envoy_ext_authz_address"`
+}
+
+func envoyExtAuthzFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("envoyextauthz", pflag.ExitOnError)
+
+	flagSet.String("envoy-ext-authz-address", "", "the address the Envoy ext_authz gRPC authorization server will be served on (e.g. \":9191\"); disabled when empty")
+
+	return flagSet
+}
+
+// envoyExtAuthzDefaults creates an EnvoyExtAuthz structure, populating each field with its default value
+func envoyExtAuthzDefaults() EnvoyExtAuthz {
+	return EnvoyExtAuthz{
+		BindAddress: "",
+	}
+}
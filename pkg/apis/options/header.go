@@ -1,5 +1,7 @@
 package options
 
+import "time"
+
 // Header represents an individual header that will be added to a request or
 // response header.
 type Header struct {
@@ -35,6 +37,9 @@ type HeaderValue struct {
 
 	// Allow users to load the value from a session claim
 	*ClaimSource `json:",omitempty"`
+
+	// Allow users to inject a signed JWT describing the session
+	JWT *JWTSource `json:"jwt,omitempty"`
 }
 
 // ClaimSource allows loading a header value from a claim within the session
@@ -53,3 +58,27 @@ type ClaimSource struct {
 	// basicAuthPassword will be used as the password value.
 	BasicAuthPassword *SecretSource `json:"basicAuthPassword,omitempty"`
 }
+
+// JWTSource allows injecting a short-lived JWT, signed with the JWT session
+// store key, that contains the claims of the session
+type JWTSource struct {
+	// Issuer is the optional `iss` claim of the JWT.
+	Issuer string `json:"issuer,omitempty"`
+
+	// Audience is the `aud` claim of the JWT.
+	// Defaults to the ID of the upstream the request is proxied to, so that
+	// each upstream receives a JWT for itself only.
+	Audience string `json:"audience,omitempty"`
+
+	// Lifetime is the duration the JWT is valid for.
+	// Defaults to 5m.
+	Lifetime *Duration `json:"lifetime,omitempty"`
+
+	// Prefix is an optional prefix that will be prepended to the JWT,
+	// e.g. `Bearer `.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// DefaultJWTSourceLifetime is the default lifetime of the JWTs injected
+// through a JWTSource
+const DefaultJWTSourceLifetime = 5 * time.Minute
@@ -47,17 +47,23 @@ var _ = Describe("Load", func() {
 		},
 
 		Options: Options{
-			BearerTokenLoginFallback: true,
-			ProxyPrefix:              "/oauth2",
-			PingPath:                 "/ping",
-			ReadyPath:                "/ready",
-			RealClientIPHeader:       "X-Real-IP",
-			ForceHTTPS:               false,
-			Cookie:                   cookieDefaults(),
-			Session:                  sessionOptionsDefaults(),
-			Templates:                templatesDefaults(),
-			SkipAuthPreflight:        false,
-			Logging:                  loggingDefaults(),
+			BearerTokenLoginFallback:  true,
+			OpaqueBearerTokenCacheTTL: time.Minute,
+			ProxyPrefix:               "/oauth2",
+			PingPath:                  "/ping",
+			ReadyPath:                 "/ready",
+			RealClientIPHeader:        "X-Real-IP",
+			ForceHTTPS:                false,
+			Cookie:                    cookieDefaults(),
+			Session:                   sessionOptionsDefaults(),
+			Templates:                 templatesDefaults(),
+			SkipAuthPreflight:         false,
+			Logging:                   loggingDefaults(),
+			Tracing:                   tracingDefaults(),
+			TokenIntrospection:        tokenIntrospectionDefaults(),
+			AdminAPI:                  adminAPIDefaults(),
+			AuthorizationWebhook:      authorizationWebhookDefaults(),
+			EnvoyExtAuthz:             envoyExtAuthzDefaults(),
 		},
 	}
 
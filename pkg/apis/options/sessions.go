@@ -1,11 +1,35 @@
 package options
 
+import "time"
+
 // SessionOptions contains configuration options for the SessionStore providers.
 type SessionOptions struct {
-	Type   string             `flag:"session-store-type" cfg:"session_store_type"`
+	Type string `flag:"session-store-type" cfg:"session_store_type"`
+	// MaxAge is the time after the authentication of the user the sessions
+	// end, however often they are refreshed
+	MaxAge time.Duration `flag:"session-max-age" cfg:"session_max_age"`
+	// IdleTimeout ends the sessions unused for longer
+	IdleTimeout time.Duration `flag:"session-idle-timeout" cfg:"session_idle_timeout"`
+	// ActivityUpdateInterval is how often the last activity of the sessions
+	// is saved, as it is not saved on every request
+	ActivityUpdateInterval time.Duration `flag:"session-activity-update-interval" cfg:"session_activity_update_interval"`
+	// MaxPerUser limits the concurrent sessions of each user, LimitPolicy
+	// chooses what happens to the new sessions above the limit
+	MaxPerUser  int    `flag:"session-max-per-user" cfg:"session_max_per_user"`
+	LimitPolicy string `flag:"session-limit-policy" cfg:"session_limit_policy"`
+	// Bind lists the attributes of the client captured at login the sessions
+	// are bound to, BindMismatchAction chooses what happens to the requests
+	// of other clients
+	Bind               []string `flag:"session-bind" cfg:"session_bind"`
+	BindIPv4Prefix     int      `flag:"session-bind-ipv4-prefix" cfg:"session_bind_ipv4_prefix"`
+	BindIPv6Prefix     int      `flag:"session-bind-ipv6-prefix" cfg:"session_bind_ipv6_prefix"`
+	BindMismatchAction string   `flag:"session-bind-mismatch-action" cfg:"session_bind_mismatch_action"`
+
 	Cookie CookieStoreOptions `cfg:",squash"`
 	Redis  RedisStoreOptions  `cfg:",squash"`
 	JWT    JWTStoreOptions    `cfg:",squash"`
+	File   FileStoreOptions   `cfg:",squash"`
+	SQL    SQLStoreOptions    `cfg:",squash"`
 }
 
 // CookieSessionStoreType is used to indicate the CookieSessionStore should be
@@ -20,6 +44,48 @@ var RedisSessionStoreType = "redis"
 // used for storing sessions.
 var JWTSessionStoreType = "jwt"
 
+// FileSessionStoreType is used to indicate the FileSessionStore should be
+// used for storing sessions.
+var FileSessionStoreType = "file"
+
+// SQLSessionStoreType is used to indicate the SQLSessionStore should be
+// used for storing sessions.
+var SQLSessionStoreType = "sql"
+
+// PostgresSQLDialect and SQLiteSQLDialect are the databases supported by the
+// SQLSessionStore.
+const (
+	PostgresSQLDialect = "postgres"
+	SQLiteSQLDialect   = "sqlite"
+)
+
+// EvictSessionLimitPolicy and RejectSessionLimitPolicy are the policies
+// applied to the new sessions of users that reached their limit of concurrent
+// sessions: evicting their oldest session, or rejecting the new one.
+const (
+	EvictSessionLimitPolicy  = "evict"
+	RejectSessionLimitPolicy = "reject"
+)
+
+// IPSessionBinding, UserAgentSessionBinding and
+// ClientCertificateSessionBinding are the attributes of the client the
+// sessions can be bound to.
+const (
+	IPSessionBinding                = "ip"
+	UserAgentSessionBinding         = "user-agent"
+	ClientCertificateSessionBinding = "client-certificate"
+)
+
+// DenySessionBindingAction, ReauthenticateSessionBindingAction and
+// LogSessionBindingAction are the actions on the requests of clients that
+// differ from the client their session is bound to: ignoring the session for
+// the request, ending the session, or only logging the mismatch.
+const (
+	DenySessionBindingAction           = "deny"
+	ReauthenticateSessionBindingAction = "reauthenticate"
+	LogSessionBindingAction            = "log"
+)
+
 // CookieStoreOptions contains configuration options for the CookieSessionStore.
 type CookieStoreOptions struct {
 	Minimal bool `flag:"session-cookie-minimal" cfg:"session_cookie_minimal"`
@@ -43,15 +109,45 @@ type RedisStoreOptions struct {
 
 // JWTStoreOptions contains configuration options for the JWTSessionStore.
 type JWTStoreOptions struct {
-	JWTKey     string `flag:"jwt-session-key" cfg:"jwt_session_key"`
-	JWTKeyFile string `flag:"jwt-session-key-file" cfg:"jwt_session_key_file"`
+	JWTKey            string   `flag:"jwt-session-key" cfg:"jwt_session_key"`
+	JWTKeyFile        string   `flag:"jwt-session-key-file" cfg:"jwt_session_key_file"`
+	JWTVerifyKeyFiles []string `flag:"jwt-session-verify-key-file" cfg:"jwt_session_verify_key_files"`
+	JWTAlgorithm      string   `flag:"jwt-session-algorithm" cfg:"jwt_session_algorithm"`
+	JWTIssuer         string   `flag:"jwt-session-issuer" cfg:"jwt_session_issuer"`
+	JWTAudience       string   `flag:"jwt-session-audience" cfg:"jwt_session_audience"`
+	JWTTokens         bool     `flag:"jwt-session-tokens" cfg:"jwt_session_tokens"`
+}
+
+// FileStoreOptions contains configuration options for the FileSessionStore.
+type FileStoreOptions struct {
+	Path               string        `flag:"file-session-path" cfg:"file_session_path"`
+	CompactionInterval time.Duration `flag:"file-session-compaction-interval" cfg:"file_session_compaction_interval"`
+}
+
+// SQLStoreOptions contains configuration options for the SQLSessionStore.
+type SQLStoreOptions struct {
+	Dialect         string        `flag:"sql-session-dialect" cfg:"sql_session_dialect"`
+	DSN             string        `flag:"sql-session-dsn" cfg:"sql_session_dsn"`
+	CleanupInterval time.Duration `flag:"sql-session-cleanup-interval" cfg:"sql_session_cleanup_interval"`
 }
 
 func sessionOptionsDefaults() SessionOptions {
 	return SessionOptions{
-		Type: CookieSessionStoreType,
+		Type:                   CookieSessionStoreType,
+		ActivityUpdateInterval: time.Minute,
+		LimitPolicy:            EvictSessionLimitPolicy,
+		BindIPv4Prefix:         32,
+		BindIPv6Prefix:         128,
+		BindMismatchAction:     DenySessionBindingAction,
 		Cookie: CookieStoreOptions{
 			Minimal: false,
 		},
+		File: FileStoreOptions{
+			CompactionInterval: 10 * time.Minute,
+		},
+		SQL: SQLStoreOptions{
+			Dialect:         PostgresSQLDialect,
+			CleanupInterval: 10 * time.Minute,
+		},
 	}
 }
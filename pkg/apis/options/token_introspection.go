@@ -0,0 +1,41 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// TokenIntrospection contains the options required to validate bearer tokens
+// with an OAuth 2.0 Token Introspection (RFC 7662) endpoint
+type TokenIntrospection struct {
+	// URL is the introspection endpoint, bearer tokens are not introspected
+	// when it is empty
+	URL string `flag:"token-introspection-url" cfg:"token_introspection_url"`
+	// ClientID and ClientSecret authenticate the proxy to the introspection
+	// endpoint, the provider client credentials are used when they are empty
+	ClientID     string `flag:"token-introspection-client-id" cfg:"token_introspection_client_id"`
+	ClientSecret string `flag:"token-introspection-client-secret" cfg:"token_introspection_client_secret"`
+	GroupsClaim  string `flag:"token-introspection-groups-claim" cfg:"token_introspection_groups_claim"`
+	// Audiences are the audiences or client IDs the tokens must be issued
+	// for, the provider client ID when it is empty
+	Audiences []string `flag:"token-introspection-audience" cfg:"token_introspection_audiences"`
+}
+
+func tokenIntrospectionFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("tokenintrospection", pflag.ExitOnError)
+
+	flagSet.String("token-introspection-url", "", "OAuth 2.0 token introspection (RFC 7662) endpoint validating the bearer tokens accepted with skip-jwt-bearer-tokens or skip-opaque-bearer-tokens")
+	flagSet.String("token-introspection-client-id", "", "client ID used to authenticate to the token introspection endpoint, defaults to the provider client ID")
+	flagSet.String("token-introspection-client-secret", "", "client secret used to authenticate to the token introspection endpoint, defaults to the provider client secret when no client ID is given")
+	flagSet.String("token-introspection-groups-claim", "groups", "claim of the token introspection response holding the groups of the user")
+	flagSet.StringSlice("token-introspection-audience", []string{}, "audience or client ID the introspected tokens must be issued for, defaults to the provider client ID (may be given multiple times)")
+
+	return flagSet
+}
+
+// tokenIntrospectionDefaults creates a TokenIntrospection structure, populating each field with its default value
+func tokenIntrospectionDefaults() TokenIntrospection {
+	return TokenIntrospection{
+		URL:          "",
+		ClientID:     "",
+		ClientSecret: "",
+		GroupsClaim:  "groups",
+	}
+}
@@ -0,0 +1,31 @@
+package options
+
+import "github.com/spf13/pflag"
+
+// Tracing contains the options required for exporting OpenTelemetry traces
+type Tracing struct {
+	// OTLPEndpoint is the URL of the OTLP/HTTP collector traces are exported to.
+	// Tracing is disabled when it is empty.
+	OTLPEndpoint string  `flag:"tracing-otlp-endpoint" cfg:"tracing_otlp_endpoint"`
+	ServiceName  string  `flag:"tracing-service-name" cfg:"tracing_service_name"`
+	SampleRatio  float64 `flag:"tracing-sample-ratio" cfg:"tracing_sample_ratio"`
+}
+
+func tracingFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("tracing", pflag.ExitOnError)
+
+	flagSet.String("tracing-otlp-endpoint", "", "URL of the OTLP/HTTP collector traces are exported to (eg. http://localhost:4318); tracing is disabled when empty")
+	flagSet.String("tracing-service-name", "oauth2-proxy", "Service name reported in the exported traces")
+	flagSet.Float64("tracing-sample-ratio", 1.0, "Ratio of new traces that are sampled, between 0 and 1; traces started upstream follow the caller's decision")
+
+	return flagSet
+}
+
+// tracingDefaults creates a Tracing structure, populating each field with its default value
+func tracingDefaults() Tracing {
+	return Tracing{
+		OTLPEndpoint: "",
+		ServiceName:  "oauth2-proxy",
+		SampleRatio:  1.0,
+	}
+}
@@ -97,4 +97,52 @@ type Upstream struct {
 	// DisableKeepAlives disables HTTP keep-alive connections to the upstream server.
 	// Defaults to false.
 	DisableKeepAlives bool `json:"disableKeepAlives,omitempty"`
+
+	// Authorization is the requirements the sessions must meet for their
+	// requests to be proxied to this upstream, in addition to the global
+	// authorization. Requests that do not meet them are denied with a 403.
+	Authorization *UpstreamAuthorization `json:"authorization,omitempty"`
+}
+
+// UpstreamAuthorization represents the requirements the sessions must meet to
+// access an upstream. A session must meet every requirement that is set.
+type UpstreamAuthorization struct {
+	// AllowedGroups restricts the upstream to the sessions in at least one of
+	// the groups.
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+
+	// AllowedTenants restricts the upstream to the sessions of one of the
+	// tenants.
+	AllowedTenants []string `json:"allowedTenants,omitempty"`
+
+	// AllowedEmails restricts the upstream to the sessions with one of the
+	// emails.
+	AllowedEmails []string `json:"allowedEmails,omitempty"`
+
+	// AllowedEmailDomains restricts the upstream to the sessions with an email
+	// in one of the domains.
+	// Domains prefixed with a `.` or a `*.` also allow their subdomains.
+	AllowedEmailDomains []string `json:"allowedEmailDomains,omitempty"`
+
+	// RequiredClaims restricts the upstream to the sessions which have all of
+	// the claims.
+	RequiredClaims []ClaimRequirement `json:"requiredClaims,omitempty"`
+
+	// Rules are CEL expressions over the claims of the session and the
+	// request, which must all evaluate to true.
+	// Eg: `'admin' in groups || (tenant == 'acme' && email.endsWith('@acme.com'))`
+	Rules []string `json:"rules,omitempty"`
+}
+
+// ClaimRequirement represents a claim a session must have to access an
+// upstream.
+type ClaimRequirement struct {
+	// Claim is the name of the claim of the session, as used by the claim
+	// sources of the headers, e.g. `email`, `groups` or `preferred_username`.
+	Claim string `json:"claim,omitempty"`
+
+	// Values are the values the claim may have. The session must have at
+	// least one of them.
+	// When no values are given, the claim must only have a non-empty value.
+	Values []string `json:"values,omitempty"`
 }
@@ -15,6 +15,56 @@ type SessionStore interface {
 	VerifyConnection(ctx context.Context) error
 }
 
+// UserSessionStore is implemented by the session stores that can find every
+// session of a user, to end them all at once or limit how many they have
+type UserSessionStore interface {
+	// ClearUserSessions clears every stored session of the user
+	ClearUserSessions(ctx context.Context, user string) error
+	// UserSessionIDs returns the IDs of the stored sessions of the user, the
+	// least recently saved first
+	UserSessionIDs(ctx context.Context, user string) ([]string, error)
+	// ClearUserSession clears the stored session of the user with the ID
+	ClearUserSession(ctx context.Context, user string, id string) error
+	// SessionID returns the ID of the stored session of the request, or an
+	// empty string when it has none
+	SessionID(req *http.Request) string
+}
+
+// SessionAdmin is implemented by the session stores that can list the
+// sessions they hold, for their administration
+type SessionAdmin interface {
+	UserSessionStore
+	// ListSessions returns the metadata of every stored session
+	ListSessions(ctx context.Context) ([]SessionMetadata, error)
+	// ClearSession clears the session with the given ID, or returns
+	// ErrSessionNotFound when there is none
+	ClearSession(ctx context.Context, id string) error
+}
+
+// SessionMetadata describes a stored session, without its tokens
+type SessionMetadata struct {
+	ID        string     `json:"id"`
+	User      string     `json:"user"`
+	Email     string     `json:"email,omitempty"`
+	Tenant    string     `json:"tenant,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	ClientIP  string     `json:"clientIP,omitempty"`
+	UserAgent string     `json:"userAgent,omitempty"`
+}
+
+// RevocationList keeps track of the sessions ended by a back-channel logout
+// of the identity provider, so that they are rejected until they expire.
+type RevocationList interface {
+	// Revoke revokes the session with the given session ID when it is set,
+	// otherwise it revokes every session of the user created until now.
+	// The revocation is kept for the expiration.
+	Revoke(ctx context.Context, user string, sessionID string, expiration time.Duration) error
+	// IsRevoked returns true if the session has been revoked
+	IsRevoked(ctx context.Context, s *SessionState) (bool, error)
+}
+
+var ErrSessionNotFound = errors.New("session not found")
 var ErrLockNotObtained = errors.New("lock: not obtained")
 var ErrNotLocked = errors.New("tried to release not existing lock")
 
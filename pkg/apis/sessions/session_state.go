@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/encryption"
@@ -16,6 +17,12 @@ import (
 type SessionState struct {
 	CreatedAt *time.Time `msgpack:"ca,omitempty"`
 	ExpiresOn *time.Time `msgpack:"eo,omitempty"`
+	// AuthenticatedAt is when the user authenticated, unlike CreatedAt it is
+	// not reset when the session is refreshed
+	AuthenticatedAt *time.Time `msgpack:"aa,omitempty"`
+	// LastActivity is when the session was last used, it is only saved
+	// periodically
+	LastActivity *time.Time `msgpack:"la,omitempty"`
 
 	AccessToken  string `msgpack:"at,omitempty"`
 	IDToken      string `msgpack:"it,omitempty"`
@@ -30,12 +37,31 @@ type SessionState struct {
 	Tenant            string   `msgpack:"t,omitempty"`
 	Username          string   `msgpack:"un,omitempty"`
 	Tenants           []string `msgpack:"tt,omitempty"`
+	// SessionID is the session of the identity provider this session belongs
+	// to, e.g. the `sid` claim of OIDC ID tokens
+	SessionID string `msgpack:"sid,omitempty"`
+	// Scopes are the scopes granted to the access token, when the session was
+	// created from a bearer token validated by token introspection
+	Scopes []string `msgpack:"sc,omitempty"`
+	// Binding is the client the session is bound to, captured at login
+	Binding *ClientBinding `msgpack:"cb,omitempty"`
 	// Internal helpers, not serialized
 	Clock     func() time.Time `msgpack:"-"` // override for time.Now, for testing
 	Lock      Lock             `msgpack:"-"`
 	Refreshed bool             `msgpack:"-"` // indicates whether the session was refreshed
 }
 
+// ClientBinding holds the attributes of the client a session is bound to.
+// The attributes the session is not bound to are empty.
+type ClientBinding struct {
+	// IP is the subnet of the client IP, in CIDR notation
+	IP string `json:"ip,omitempty" msgpack:"ip,omitempty"`
+	// UserAgent is the SHA-256 hash of the User-Agent header
+	UserAgent string `json:"ua,omitempty" msgpack:"ua,omitempty"`
+	// Certificate is the SHA-256 thumbprint of the TLS client certificate
+	Certificate string `json:"crt,omitempty" msgpack:"crt,omitempty"`
+}
+
 func (s *SessionState) now() time.Time {
 	if s.Clock != nil {
 		return s.Clock()
@@ -71,6 +97,36 @@ func (s *SessionState) PeekLock(ctx context.Context) (bool, error) {
 	return s.Lock.Peek(ctx)
 }
 
+// Clone returns a deep copy of the SessionState, sharing neither its slices nor
+// its times with the original. The Lock is shared.
+func (s *SessionState) Clone() *SessionState {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.CreatedAt = cloneTime(s.CreatedAt)
+	c.ExpiresOn = cloneTime(s.ExpiresOn)
+	c.AuthenticatedAt = cloneTime(s.AuthenticatedAt)
+	c.LastActivity = cloneTime(s.LastActivity)
+	c.Nonce = slices.Clone(s.Nonce)
+	c.Groups = slices.Clone(s.Groups)
+	c.Tenants = slices.Clone(s.Tenants)
+	c.Scopes = slices.Clone(s.Scopes)
+	if s.Binding != nil {
+		binding := *s.Binding
+		c.Binding = &binding
+	}
+	return &c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
 // CreatedAtNow sets a SessionState's CreatedAt to now
 func (s *SessionState) CreatedAtNow() {
 	now := s.now()
@@ -100,6 +156,36 @@ func (s *SessionState) IsExpired() bool {
 	return false
 }
 
+// AuthenticatedAtNow sets a SessionState's AuthenticatedAt to now
+func (s *SessionState) AuthenticatedAtNow() {
+	now := s.now()
+	s.AuthenticatedAt = &now
+}
+
+// AuthenticationAge returns the time since the user authenticated.
+// Sessions saved before AuthenticatedAt was recorded fall back to CreatedAt.
+func (s *SessionState) AuthenticationAge() time.Duration {
+	if s.AuthenticatedAt != nil && !s.AuthenticatedAt.IsZero() {
+		return s.now().Sub(*s.AuthenticatedAt)
+	}
+	return s.Age()
+}
+
+// ActivityNow sets a SessionState's LastActivity to now
+func (s *SessionState) ActivityNow() {
+	now := s.now()
+	s.LastActivity = &now
+}
+
+// IdleTime returns the time since the last activity of the session, or since
+// it was created when no activity was recorded yet
+func (s *SessionState) IdleTime() time.Duration {
+	if s.LastActivity != nil && !s.LastActivity.IsZero() {
+		return s.now().Sub(*s.LastActivity)
+	}
+	return s.Age()
+}
+
 // Age returns the age of a session
 func (s *SessionState) Age() time.Duration {
 	if s.CreatedAt != nil && !s.CreatedAt.IsZero() {
@@ -135,6 +221,9 @@ func (s *SessionState) String() string {
 	if len(s.Tenants) > 0 {
 		o += fmt.Sprintf(" tenants:%v", s.Tenants)
 	}
+	if len(s.Scopes) > 0 {
+		o += fmt.Sprintf(" scopes:%v", s.Scopes)
+	}
 	return o + "}"
 }
 
@@ -165,10 +254,16 @@ func (s *SessionState) GetClaim(claim string) []string {
 		return []string{s.PreferredUsername}
 	case "username":
 		return []string{s.Username}
+	case "tenant":
+		return []string{s.Tenant}
 	case "tenants":
 		tenants := make([]string, len(s.Tenants))
 		copy(tenants, s.Tenants)
 		return tenants
+	case "scope":
+		scopes := make([]string, len(s.Scopes))
+		copy(scopes, s.Scopes)
+		return scopes
 	default:
 		return []string{}
 	}
@@ -165,11 +165,64 @@ func TestAge(t *testing.T) {
 	assert.Equal(t, time.Hour, ss.Age().Round(time.Minute))
 }
 
+func TestAuthenticationAge(t *testing.T) {
+	ss := &SessionState{}
+
+	// Falls back to the age of the session
+	ss.CreatedAt = timePtr(time.Now().Add(-1 * time.Hour))
+	assert.Equal(t, time.Hour, ss.AuthenticationAge().Round(time.Minute))
+
+	// Not reset by a refresh
+	ss.AuthenticatedAt = ss.CreatedAt
+	ss.CreatedAtNow()
+	assert.Equal(t, time.Hour, ss.AuthenticationAge().Round(time.Minute))
+}
+
+func TestIdleTime(t *testing.T) {
+	ss := &SessionState{}
+
+	// Falls back to the age of the session
+	ss.CreatedAt = timePtr(time.Now().Add(-1 * time.Hour))
+	assert.Equal(t, time.Hour, ss.IdleTime().Round(time.Minute))
+
+	ss.LastActivity = timePtr(time.Now().Add(-5 * time.Minute))
+	assert.Equal(t, 5*time.Minute, ss.IdleTime().Round(time.Minute))
+
+	ss.ActivityNow()
+	assert.Equal(t, time.Duration(0), ss.IdleTime().Round(time.Minute))
+}
+
+func TestClone(t *testing.T) {
+	g := NewWithT(t)
+	ss := &SessionState{
+		CreatedAt: timePtr(time.Unix(1234567890, 0)),
+		User:      "john.doe",
+		Groups:    []string{"admins"},
+		Tenants:   []string{"acme"},
+		Binding:   &ClientBinding{IP: "10.0.0.0/24"},
+	}
+
+	c := ss.Clone()
+	g.Expect(c).To(Equal(ss))
+
+	c.Groups[0] = "users"
+	c.Tenants[0] = "umbrella"
+	*c.CreatedAt = time.Unix(0, 0)
+	c.Binding.IP = "10.0.1.0/24"
+	g.Expect(ss.Groups).To(Equal([]string{"admins"}))
+	g.Expect(ss.Tenants).To(Equal([]string{"acme"}))
+	g.Expect(*ss.CreatedAt).To(Equal(time.Unix(1234567890, 0)))
+	g.Expect(ss.Binding.IP).To(Equal("10.0.0.0/24"))
+
+	g.Expect((*SessionState)(nil).Clone()).To(BeNil())
+}
+
 // TestEncodeAndDecodeSessionState encodes & decodes various session states
 // and confirms the operation is 1:1
 func TestEncodeAndDecodeSessionState(t *testing.T) {
 	created := time.Now()
 	expires := time.Now().Add(time.Duration(1) * time.Hour)
+	authenticated := time.Now().Add(time.Duration(-1) * time.Hour)
 
 	// Tokens in the test table are purposefully redundant
 	// Otherwise compressing small payloads could result in a compressed value
@@ -184,6 +237,8 @@ func TestEncodeAndDecodeSessionState(t *testing.T) {
 			IDToken:           "IDToken.12349871293847fdsaihf9238h4f91h8fr.1349f831y98fd7",
 			CreatedAt:         &created,
 			ExpiresOn:         &expires,
+			AuthenticatedAt:   &authenticated,
+			LastActivity:      &created,
 			RefreshToken:      "RefreshToken.12349871293847fdsaihf9238h4f91h8fr.1349f831y98fd7",
 			Nonce:             []byte("abcdef1234567890abcdef1234567890"),
 		},
@@ -232,6 +287,18 @@ func TestEncodeAndDecodeSessionState(t *testing.T) {
 			Nonce:             []byte("abcdef1234567890abcdef1234567890"),
 			Groups:            []string{"group-a", "group-b"},
 		},
+		"With client binding": {
+			Email:        "username@example.com",
+			User:         "username",
+			AccessToken:  "AccessToken.12349871293847fdsaihf9238h4f91h8fr.1349f831y98fd7",
+			IDToken:      "IDToken.12349871293847fdsaihf9238h4f91h8fr.1349f831y98fd7",
+			CreatedAt:    &created,
+			RefreshToken: "RefreshToken.12349871293847fdsaihf9238h4f91h8fr.1349f831y98fd7",
+			Binding: &ClientBinding{
+				IP:        "10.0.0.0/24",
+				UserAgent: "6MBmq4Xpyh5Shgd0M5AVT1N1tR6DnsH3xPnm42Tbd2E",
+			},
+		},
 	}
 
 	for _, secretSize := range []int{16, 24, 32} {
@@ -277,25 +344,30 @@ func TestEncodeAndDecodeSessionState(t *testing.T) {
 }
 
 func compareSessionStates(t *testing.T, expected *SessionState, actual *SessionState) {
-	if expected.CreatedAt != nil {
-		assert.NotNil(t, actual.CreatedAt)
-		assert.Equal(t, true, expected.CreatedAt.Equal(*actual.CreatedAt))
-	} else {
-		assert.Nil(t, actual.CreatedAt)
-	}
-	if expected.ExpiresOn != nil {
-		assert.NotNil(t, actual.ExpiresOn)
-		assert.Equal(t, true, expected.ExpiresOn.Equal(*actual.ExpiresOn))
-	} else {
-		assert.Nil(t, actual.ExpiresOn)
-	}
+	compareTimes(t, expected.CreatedAt, actual.CreatedAt)
+	compareTimes(t, expected.ExpiresOn, actual.ExpiresOn)
+	compareTimes(t, expected.AuthenticatedAt, actual.AuthenticatedAt)
+	compareTimes(t, expected.LastActivity, actual.LastActivity)
 
 	// Compare sessions without *time.Time fields
 	exp := *expected
 	exp.CreatedAt = nil
 	exp.ExpiresOn = nil
+	exp.AuthenticatedAt = nil
+	exp.LastActivity = nil
 	act := *actual
 	act.CreatedAt = nil
 	act.ExpiresOn = nil
+	act.AuthenticatedAt = nil
+	act.LastActivity = nil
 	assert.Equal(t, exp, act)
 }
+
+func compareTimes(t *testing.T, expected *time.Time, actual *time.Time) {
+	if expected != nil {
+		assert.NotNil(t, actual)
+		assert.Equal(t, true, expected.Equal(*actual))
+	} else {
+		assert.Nil(t, actual)
+	}
+}
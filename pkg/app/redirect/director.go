@@ -12,6 +12,7 @@ import (
 // a users request to after the user has authenticated with the identity provider.
 type AppDirector interface {
 	GetRedirect(req *http.Request) (string, error)
+	GetForwardedRedirect(req *http.Request) string
 }
 
 // AppDirectorOpts are the requirements for constructing a new AppDirector.
@@ -74,6 +75,20 @@ func (a *appDirector) GetRedirect(req *http.Request) (string, error) {
 	return "/", nil
 }
 
+// GetForwardedRedirect determines the URL of the original request of the client
+// for the forward auth requests made on its behalf by a reverse proxy.
+// Strategy priority (first legal result is used):
+// - `X-Forwarded-(Proto|Host|Uri)` headers (when ReverseProxy mode is enabled)
+// - `X-Forwarded-Uri` direct URI path (when ReverseProxy mode is enabled)
+// - `req.URL.RequestURI` if not under the ProxyPath (i.e. /oauth2/*)
+// - `/`
+func (a *appDirector) GetForwardedRedirect(req *http.Request) string {
+	if redirect := a.getXForwardedHeadersRedirect(req); redirect != "" {
+		return redirect
+	}
+	return a.getURIRedirect(req)
+}
+
 // validateRedirect checks that the redirect is valid.
 // When an invalid, non-empty redirect is found, an error will be logged using
 // the provided format.
@@ -174,4 +174,78 @@ var _ = Describe("Director Suite", func() {
 			expectedRedirect: "https://a-service.example.com/foo/bar",
 		}),
 	)
+
+	DescribeTable("GetForwardedRedirect",
+		func(in getRedirectTableInput) {
+			appDirector := NewAppDirector(AppDirectorOpts{
+				ProxyPrefix: testProxyPrefix,
+				Validator:   in.validator,
+			})
+
+			req, _ := http.NewRequest("GET", in.requestURL, nil)
+			for header, value := range in.headers {
+				req.Header.Add(header, value)
+			}
+			req = middleware.AddRequestScope(req, &middleware.RequestScope{
+				ReverseProxy: in.reverseProxy,
+			})
+
+			Expect(appDirector.GetForwardedRedirect(req)).To(Equal(in.expectedRedirect))
+		},
+		Entry("Forwarded request with headers, redirects to the forwarded URL", getRedirectTableInput{
+			requestURL: "https://oauth.example.com" + testProxyPrefix + "/forward_auth",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "a-service.example.com",
+				"X-Forwarded-Uri":   "/foo?bar",
+			},
+			reverseProxy:     true,
+			validator:        testValidator(true),
+			expectedRedirect: "https://a-service.example.com/foo?bar",
+		}),
+		Entry("Forwarded request with RD parameter, ignores the RD parameter", getRedirectTableInput{
+			requestURL: "https://oauth.example.com" + testProxyPrefix + "/forward_auth?rd=https%3A%2F%2Fevil.example.com%2F",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "a-service.example.com",
+				"X-Forwarded-Uri":   fooBar,
+			},
+			reverseProxy:     true,
+			validator:        testValidator(true),
+			expectedRedirect: "https://a-service.example.com/foo/bar",
+		}),
+		Entry("Forwarded request for the same host, redirects to the forwarded URI", getRedirectTableInput{
+			requestURL: "https://a-service.example.com" + testProxyPrefix + "/forward_auth",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "a-service.example.com",
+				"X-Forwarded-Uri":   fooBar,
+			},
+			reverseProxy:     true,
+			validator:        testValidator(true),
+			expectedRedirect: fooBar,
+		}),
+		Entry("Forwarded request with invalid headers, redirects to root", getRedirectTableInput{
+			requestURL: "https://oauth.example.com" + testProxyPrefix + "/forward_auth",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "evil.example.com",
+				"X-Forwarded-Uri":   fooBar,
+			},
+			reverseProxy:     true,
+			validator:        testValidator(false),
+			expectedRedirect: "/",
+		}),
+		Entry("Non-proxied request with spoofed headers, redirects to root", getRedirectTableInput{
+			requestURL: "https://oauth.example.com" + testProxyPrefix + "/forward_auth",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "a-service.example.com",
+				"X-Forwarded-Uri":   fooBar,
+			},
+			reverseProxy:     false,
+			validator:        testValidator(true),
+			expectedRedirect: "/",
+		}),
+	)
 })
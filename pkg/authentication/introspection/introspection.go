@@ -0,0 +1,212 @@
+package introspection
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/providers"
+)
+
+// pruneInterval is how often the expired tokens are removed from the cache
+const pruneInterval = time.Minute
+
+// Options configures the introspection of bearer tokens
+type Options struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	GroupsClaim  string
+	// Audiences are the audiences or client IDs the tokens must be issued
+	// for, one of them must be in the aud or the client_id of the response
+	Audiences []string
+}
+
+// introspector validates tokens with an OAuth 2.0 Token Introspection
+// (RFC 7662) endpoint
+type introspector struct {
+	opts Options
+
+	cacheLock sync.Mutex
+	// cache holds the sessions of the active tokens by the hash of the token,
+	// until the token expires
+	cache     map[[sha256.Size]byte]*sessionsapi.SessionState
+	lastPrune time.Time
+}
+
+// NewTokenToSessionFunc creates a TokenToSessionFunc loading sessions from
+// the bearer tokens the introspection endpoint reports as active.
+// The sessions of the tokens with an expiry are cached until the token
+// expires, so that the endpoint is called once per token.
+func NewTokenToSessionFunc(opts Options) middlewareapi.TokenToSessionFunc {
+	i := &introspector{
+		opts:  opts,
+		cache: make(map[[sha256.Size]byte]*sessionsapi.SessionState),
+	}
+	return i.tokenToSession
+}
+
+func (i *introspector) tokenToSession(ctx context.Context, token string) (*sessionsapi.SessionState, error) {
+	key := sha256.Sum256([]byte(token))
+	if session := i.cached(key); session != nil {
+		return session, nil
+	}
+
+	session, err := i.introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	i.store(key, session)
+	return session.Clone(), nil
+}
+
+// introspect posts the token to the introspection endpoint, authenticated
+// with the client credentials as described in RFC 6749 section 2.3.1
+func (i *introspector) introspect(ctx context.Context, token string) (*sessionsapi.SessionState, error) {
+	params := url.Values{}
+	params.Add("token", token)
+	params.Add("token_type_hint", "access_token")
+
+	var response map[string]interface{}
+	err := requests.New(i.opts.URL).
+		WithContext(ctx).
+		WithMethod("POST").
+		WithBody(bytes.NewBufferString(params.Encode())).
+		SetHeader("Content-Type", "application/x-www-form-urlencoded").
+		SetHeader("Accept", "application/json").
+		SetHeader("Authorization", basicAuth(i.opts.ClientID, i.opts.ClientSecret)).
+		Do().
+		UnmarshalInto(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error introspecting token: %v", err)
+	}
+
+	if active, _ := response["active"].(bool); !active {
+		return nil, fmt.Errorf("token is not active: %w", providers.ErrSessionRejected)
+	}
+	if !i.issuedForAudience(response) {
+		return nil, fmt.Errorf("token was not issued for the audiences %v: %w", i.opts.Audiences, providers.ErrSessionRejected)
+	}
+
+	session := &sessionsapi.SessionState{
+		AccessToken:       token,
+		User:              stringClaim(response, "sub"),
+		PreferredUsername: stringClaim(response, "username"),
+		Email:             stringClaim(response, "email"),
+		Scopes:            strings.Fields(stringClaim(response, "scope")),
+		Groups:            stringSliceClaim(response, i.opts.GroupsClaim),
+	}
+	if session.User == "" {
+		session.User = session.PreferredUsername
+	}
+	if session.User == "" {
+		return nil, fmt.Errorf("no sub or username in the introspection response: %w", providers.ErrSessionRejected)
+	}
+	if session.Email == "" {
+		session.Email = session.User
+	}
+
+	if exp, ok := response["exp"].(float64); ok {
+		expiresOn := time.Unix(int64(exp), 0)
+		session.ExpiresOn = &expiresOn
+		if session.IsExpired() {
+			return nil, fmt.Errorf("token expired at %s: %w", expiresOn, providers.ErrSessionRejected)
+		}
+	}
+	session.CreatedAtNow()
+
+	return session, nil
+}
+
+func (i *introspector) cached(key [sha256.Size]byte) *sessionsapi.SessionState {
+	i.cacheLock.Lock()
+	defer i.cacheLock.Unlock()
+
+	session, ok := i.cache[key]
+	if !ok || session.IsExpired() {
+		return nil
+	}
+	return session.Clone()
+}
+
+func (i *introspector) store(key [sha256.Size]byte, session *sessionsapi.SessionState) {
+	// Tokens without an expiry are introspected on every request, as there
+	// is no telling when they are revoked
+	if session.ExpiresOn == nil {
+		return
+	}
+
+	i.cacheLock.Lock()
+	defer i.cacheLock.Unlock()
+
+	now := time.Now()
+	if now.Sub(i.lastPrune) > pruneInterval {
+		for k, s := range i.cache {
+			if s.IsExpired() {
+				delete(i.cache, k)
+			}
+		}
+		i.lastPrune = now
+	}
+
+	i.cache[key] = session
+}
+
+// issuedForAudience checks the aud or the client_id of the introspection
+// response includes one of the allowed audiences, so that the tokens issued
+// to other clients of the authorization server are not accepted
+func (i *introspector) issuedForAudience(response map[string]interface{}) bool {
+	audiences := stringSliceClaim(response, "aud")
+	if clientID := stringClaim(response, "client_id"); clientID != "" {
+		audiences = append(audiences, clientID)
+	}
+
+	for _, audience := range audiences {
+		if slices.Contains(i.opts.Audiences, audience) {
+			return true
+		}
+	}
+	return false
+}
+
+// basicAuth builds the Authorization header of the client credentials, they
+// are form encoded first as required by RFC 6749
+func basicAuth(clientID, clientSecret string) string {
+	credentials := url.QueryEscape(clientID) + ":" + url.QueryEscape(clientSecret)
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+}
+
+func stringClaim(response map[string]interface{}, claim string) string {
+	value, _ := response[claim].(string)
+	return value
+}
+
+// stringSliceClaim accepts both a list of strings and a space separated
+// string, as used by the scope claim
+func stringSliceClaim(response map[string]interface{}, claim string) []string {
+	switch value := response[claim].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
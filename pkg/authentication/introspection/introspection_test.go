@@ -0,0 +1,173 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/providers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Introspection Suite", func() {
+	const (
+		clientID     = "client id"
+		clientSecret = "client/secret"
+	)
+
+	var (
+		server         *httptest.Server
+		calls          int
+		response       map[string]interface{}
+		status         int
+		tokenToSession middlewareapi.TokenToSessionFunc
+		receivedForm   map[string]string
+	)
+
+	BeforeEach(func() {
+		calls = 0
+		status = http.StatusOK
+		receivedForm = map[string]string{}
+		response = map[string]interface{}{
+			"active":   true,
+			"sub":      "1234",
+			"aud":      "api",
+			"username": "jdoe",
+			"email":    "jdoe@example.com",
+			"scope":    "openid profile",
+			"groups":   []string{"admins", "devs"},
+			"exp":      time.Now().Add(time.Hour).Unix(),
+		}
+
+		server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			calls++
+			user, password, _ := req.BasicAuth()
+			if user != "client+id" || password != "client%2Fsecret" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			Expect(req.ParseForm()).To(Succeed())
+			for k := range req.PostForm {
+				receivedForm[k] = req.PostForm.Get(k)
+			}
+			rw.WriteHeader(status)
+			Expect(json.NewEncoder(rw).Encode(response)).To(Succeed())
+		}))
+
+		tokenToSession = NewTokenToSessionFunc(Options{
+			URL:          server.URL,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			GroupsClaim:  "groups",
+			Audiences:    []string{"api", "app"},
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("maps the claims of an active token into the session", func() {
+		session, err := tokenToSession(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(receivedForm).To(Equal(map[string]string{"token": "token", "token_type_hint": "access_token"}))
+
+		Expect(session.AccessToken).To(Equal("token"))
+		Expect(session.User).To(Equal("1234"))
+		Expect(session.PreferredUsername).To(Equal("jdoe"))
+		Expect(session.Email).To(Equal("jdoe@example.com"))
+		Expect(session.Scopes).To(Equal([]string{"openid", "profile"}))
+		Expect(session.Groups).To(Equal([]string{"admins", "devs"}))
+		Expect(session.ExpiresOn).ToNot(BeNil())
+		Expect(session.CreatedAt).ToNot(BeNil())
+	})
+
+	It("accepts the groups as a space separated string", func() {
+		response["groups"] = "admins devs"
+		session, err := tokenToSession(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(session.Groups).To(Equal([]string{"admins", "devs"}))
+	})
+
+	It("falls back to the username without a sub", func() {
+		delete(response, "sub")
+		delete(response, "email")
+		session, err := tokenToSession(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(session.User).To(Equal("jdoe"))
+		Expect(session.Email).To(Equal("jdoe"))
+	})
+
+	It("rejects inactive tokens", func() {
+		response = map[string]interface{}{"active": false}
+		_, err := tokenToSession(context.Background(), "token")
+		Expect(err).To(MatchError(providers.ErrSessionRejected))
+	})
+
+	It("rejects expired tokens", func() {
+		response["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, err := tokenToSession(context.Background(), "token")
+		Expect(err).To(MatchError(providers.ErrSessionRejected))
+	})
+
+	It("accepts the tokens issued for one of the audiences", func() {
+		response["aud"] = []string{"other api", "api"}
+		_, err := tokenToSession(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+
+		delete(response, "aud")
+		response["client_id"] = "app"
+		_, err = tokenToSession(context.Background(), "other token")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects the tokens issued for other audiences", func() {
+		response["aud"] = []string{"other api"}
+		response["client_id"] = "other app"
+		_, err := tokenToSession(context.Background(), "token")
+		Expect(err).To(MatchError(providers.ErrSessionRejected))
+	})
+
+	It("rejects the tokens without an audience", func() {
+		delete(response, "aud")
+		_, err := tokenToSession(context.Background(), "token")
+		Expect(err).To(MatchError(providers.ErrSessionRejected))
+	})
+
+	It("does not reject the token when the endpoint fails", func() {
+		status = http.StatusInternalServerError
+		_, err := tokenToSession(context.Background(), "token")
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(MatchError(providers.ErrSessionRejected))
+	})
+
+	It("caches the active tokens until they expire", func() {
+		for i := 0; i < 3; i++ {
+			session, err := tokenToSession(context.Background(), "token")
+			Expect(err).ToNot(HaveOccurred())
+			session.User = "changed"
+		}
+		Expect(calls).To(Equal(1))
+
+		session, err := tokenToSession(context.Background(), "token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(session.User).To(Equal("1234"))
+
+		_, err = tokenToSession(context.Background(), "other token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("introspects the tokens without an expiry on every request", func() {
+		delete(response, "exp")
+		for i := 0; i < 3; i++ {
+			_, err := tokenToSession(context.Background(), "token")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(calls).To(Equal(3))
+	})
+})
@@ -0,0 +1,136 @@
+package authorization
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/cel-go/cel"
+	ipapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/ip"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/ip"
+	requestutil "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests/util"
+)
+
+// ruleClaims are the claims of the session available to the rules in the
+// `claims` map. The tokens of the session are left out.
+var ruleClaims = []string{"email", "user", "groups", "preferred_username", "username", "tenant", "tenants", "scope"}
+
+// Rules are CEL expressions that a session and its request must all satisfy
+type Rules struct {
+	rules    []rule
+	ipParser ipapi.RealClientIPParser
+}
+
+type rule struct {
+	expression string
+	program    cel.Program
+}
+
+// NewRules compiles the rule expressions. It returns nil when there are no
+// rules.
+func NewRules(expressions []string, ipParser ipapi.RealClientIPParser) (*Rules, error) {
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+
+	env, err := newRuleEnv()
+	if err != nil {
+		return nil, err
+	}
+	r := &Rules{ipParser: ipParser}
+	for _, expression := range expressions {
+		program, err := compileRule(env, expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %v", expression, err)
+		}
+		r.rules = append(r.rules, rule{expression: expression, program: program})
+	}
+	return r, nil
+}
+
+// ValidateRule checks the rule expression compiles and evaluates to a bool
+func ValidateRule(expression string) error {
+	env, err := newRuleEnv()
+	if err != nil {
+		return err
+	}
+	_, err = compileRule(env, expression)
+	return err
+}
+
+// Evaluate returns an error describing the first rule the session and its
+// request do not satisfy. Rules that fail to evaluate deny the request.
+func (r *Rules) Evaluate(req *http.Request, s *sessions.SessionState) error {
+	if r == nil {
+		return nil
+	}
+
+	vars := r.variables(req, s)
+	for _, rule := range r.rules {
+		out, _, err := rule.program.Eval(vars)
+		if err != nil {
+			return fmt.Errorf("error evaluating rule %q: %v", rule.expression, err)
+		}
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			return fmt.Errorf("rule %q denied the request", rule.expression)
+		}
+	}
+	return nil
+}
+
+// variables returns the values of the variables of the rules for the session
+// and its request
+func (r *Rules) variables(req *http.Request, s *sessions.SessionState) map[string]any {
+	claims := make(map[string][]string, len(ruleClaims))
+	for _, claim := range ruleClaims {
+		claims[claim] = s.GetClaim(claim)
+	}
+
+	clientIP := ""
+	if realIP, err := ip.GetClientIP(r.ipParser, req); err == nil && realIP != nil {
+		clientIP = realIP.String()
+	}
+
+	return map[string]any{
+		"email":  s.Email,
+		"user":   s.User,
+		"tenant": s.Tenant,
+		"groups": claims["groups"],
+		"claims": claims,
+		"request": map[string]string{
+			"method": req.Method,
+			"path":   requestutil.GetRequestPath(req),
+			"host":   requestutil.GetRequestHost(req),
+			"ip":     clientIP,
+		},
+	}
+}
+
+// newRuleEnv declares the variables available to the rules
+func newRuleEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("email", cel.StringType),
+		cel.Variable("user", cel.StringType),
+		cel.Variable("tenant", cel.StringType),
+		cel.Variable("groups", cel.ListType(cel.StringType)),
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.ListType(cel.StringType))),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the rule environment: %v", err)
+	}
+	return env, nil
+}
+
+// compileRule parses and type-checks the rule expression, which must
+// evaluate to a bool
+func compileRule(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("rule must evaluate to a bool, not %s", ast.OutputType())
+	}
+	return env.Program(ast)
+}
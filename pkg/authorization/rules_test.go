@@ -0,0 +1,87 @@
+package authorization
+
+import (
+	"net/http/httptest"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rules", func() {
+	type evaluateTableInput struct {
+		rules       []string
+		method      string
+		expectedErr string
+	}
+
+	session := &sessions.SessionState{
+		Email:             "jane@acme.com",
+		User:              "jane",
+		Groups:            []string{"developers"},
+		Tenant:            "acme",
+		PreferredUsername: "jane.doe",
+	}
+
+	DescribeTable("Evaluate",
+		func(in evaluateTableInput) {
+			rules, err := NewRules(in.rules, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req := httptest.NewRequest(in.method, "http://app.example.com/admin/users", nil)
+			req.RemoteAddr = "10.0.0.1:4180"
+
+			err = rules.Evaluate(req, session)
+			if in.expectedErr == "" {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(in.expectedErr))
+			}
+		},
+		Entry("without rules", evaluateTableInput{
+			method: "GET",
+		}),
+		Entry("with a rule over the claims", evaluateTableInput{
+			rules:  []string{"'admin' in groups || (tenant == 'acme' && email.endsWith('@acme.com'))"},
+			method: "GET",
+		}),
+		Entry("with a rule over the claims map", evaluateTableInput{
+			rules:  []string{"claims.preferred_username == ['jane.doe']"},
+			method: "GET",
+		}),
+		Entry("with a rule over the request", evaluateTableInput{
+			rules: []string{
+				"request.method == 'GET'",
+				"request.path.startsWith('/admin/')",
+				"request.host == 'app.example.com'",
+				"request.ip == '10.0.0.1'",
+			},
+			method: "GET",
+		}),
+		Entry("with a rule that is not satisfied", evaluateTableInput{
+			rules:       []string{"request.method == 'GET'", "'admin' in groups || request.method == 'GET'"},
+			method:      "POST",
+			expectedErr: "rule \"request.method == 'GET'\" denied the request",
+		}),
+		Entry("with a rule that fails to evaluate", evaluateTableInput{
+			rules:       []string{"claims.unknown.size() == 0"},
+			method:      "GET",
+			expectedErr: "error evaluating rule \"claims.unknown.size() == 0\": no such key: unknown",
+		}),
+	)
+
+	DescribeTable("ValidateRule",
+		func(rule string, expectedErr string) {
+			err := ValidateRule(rule)
+			if expectedErr == "" {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("with a valid rule", "'admin' in groups", ""),
+		Entry("with a syntax error", "'admin' in", "Syntax error"),
+		Entry("with an undeclared variable", "role == 'admin'", "undeclared reference to 'role'"),
+		Entry("with a rule that is not a bool", "email", "rule must evaluate to a bool, not string"),
+	)
+})
@@ -0,0 +1,138 @@
+package authorization
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	ipapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/ip"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/util"
+)
+
+// UpstreamAuthorizer authorizes the sessions against the requirements of the
+// upstreams their requests are proxied to
+type UpstreamAuthorizer struct {
+	policies map[string]*policy
+}
+
+// NewUpstreamAuthorizer creates an UpstreamAuthorizer for the requirements of
+// the upstreams of the configuration
+func NewUpstreamAuthorizer(upstreams options.UpstreamConfig, ipParser ipapi.RealClientIPParser) (*UpstreamAuthorizer, error) {
+	a := &UpstreamAuthorizer{
+		policies: make(map[string]*policy),
+	}
+	for _, upstream := range upstreams.Upstreams {
+		if upstream.Authorization == nil {
+			continue
+		}
+		p, err := newPolicy(upstream.Authorization, ipParser)
+		if err != nil {
+			return nil, fmt.Errorf("error creating the authorization of upstream %q: %v", upstream.ID, err)
+		}
+		a.policies[upstream.ID] = p
+	}
+	return a, nil
+}
+
+// Authorize returns an error describing why the session may not access the
+// upstream. Upstreams without requirements allow every session.
+func (a *UpstreamAuthorizer) Authorize(req *http.Request, upstream string, s *sessions.SessionState) error {
+	p, ok := a.policies[upstream]
+	if !ok {
+		return nil
+	}
+	return p.authorize(req, s)
+}
+
+// policy holds the requirements of an upstream as sets for the membership
+// checks
+type policy struct {
+	groups         map[string]struct{}
+	tenants        map[string]struct{}
+	emails         map[string]struct{}
+	emailDomains   []string
+	requiredClaims []options.ClaimRequirement
+	rules          *Rules
+}
+
+func newPolicy(opts *options.UpstreamAuthorization, ipParser ipapi.RealClientIPParser) (*policy, error) {
+	rules, err := NewRules(opts.Rules, ipParser)
+	if err != nil {
+		return nil, err
+	}
+	return &policy{
+		groups:         toSet(opts.AllowedGroups),
+		tenants:        toSet(opts.AllowedTenants),
+		emails:         toSet(opts.AllowedEmails),
+		emailDomains:   opts.AllowedEmailDomains,
+		requiredClaims: opts.RequiredClaims,
+		rules:          rules,
+	}, nil
+}
+
+// authorize checks the session against each requirement that is set
+func (p *policy) authorize(req *http.Request, s *sessions.SessionState) error {
+	if len(p.groups) > 0 && !containsAny(p.groups, s.Groups) {
+		return fmt.Errorf("groups %v are not allowed", s.Groups)
+	}
+	if len(p.tenants) > 0 && !containsAny(p.tenants, []string{s.Tenant}) {
+		return fmt.Errorf("tenant %q is not allowed", s.Tenant)
+	}
+	if len(p.emails) > 0 && !containsAny(p.emails, []string{s.Email}) {
+		return fmt.Errorf("email %q is not allowed", s.Email)
+	}
+	if len(p.emailDomains) > 0 && !isEmailDomainAllowed(s.Email, p.emailDomains) {
+		return fmt.Errorf("email domain of %q is not allowed", s.Email)
+	}
+	for _, requirement := range p.requiredClaims {
+		if !hasClaim(s, requirement) {
+			return fmt.Errorf("claim %q does not have a required value", requirement.Claim)
+		}
+	}
+	return p.rules.Evaluate(req, s)
+}
+
+// isEmailDomainAllowed matches the domain of the email the same way as the
+// `allowed_email_domains` of the auth endpoint
+func isEmailDomainAllowed(email string, allowedDomains []string) bool {
+	splitEmail := strings.Split(email, "@")
+	if len(splitEmail) != 2 {
+		return false
+	}
+	return util.IsEndpointAllowed(&url.URL{Host: splitEmail[1]}, allowedDomains)
+}
+
+// hasClaim checks the session has one of the values of the claim, or any
+// non-empty value when the requirement has no values
+func hasClaim(s *sessions.SessionState, requirement options.ClaimRequirement) bool {
+	values := toSet(requirement.Values)
+	for _, value := range s.GetClaim(requirement.Claim) {
+		if value == "" {
+			continue
+		}
+		if _, ok := values[value]; ok || len(values) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(set map[string]struct{}, values []string) bool {
+	for _, value := range values {
+		if _, ok := set[value]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
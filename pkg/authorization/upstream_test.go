@@ -0,0 +1,143 @@
+package authorization
+
+import (
+	"net/http/httptest"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpstreamAuthorizer", func() {
+	type authorizeTableInput struct {
+		authorization *options.UpstreamAuthorization
+		session       *sessions.SessionState
+		expectedErr   string
+	}
+
+	session := &sessions.SessionState{
+		Email:             "jane@example.com",
+		Groups:            []string{"developers", "operators"},
+		Tenant:            "acme",
+		PreferredUsername: "jane",
+	}
+
+	DescribeTable("Authorize",
+		func(in authorizeTableInput) {
+			authorizer, err := NewUpstreamAuthorizer(options.UpstreamConfig{
+				Upstreams: []options.Upstream{
+					{ID: "app", Authorization: in.authorization},
+				},
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = authorizer.Authorize(httptest.NewRequest("GET", "http://example.com/", nil), "app", in.session)
+			if in.expectedErr == "" {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(in.expectedErr))
+			}
+		},
+		Entry("without requirements", authorizeTableInput{
+			authorization: nil,
+			session:       session,
+		}),
+		Entry("with empty requirements", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{},
+			session:       session,
+		}),
+		Entry("with one of the allowed groups", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedGroups: []string{"admins", "operators"}},
+			session:       session,
+		}),
+		Entry("without any of the allowed groups", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedGroups: []string{"admins"}},
+			session:       session,
+			expectedErr:   "groups [developers operators] are not allowed",
+		}),
+		Entry("with an allowed tenant", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedTenants: []string{"acme"}},
+			session:       session,
+		}),
+		Entry("with a tenant that is not allowed", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedTenants: []string{"globex"}},
+			session:       session,
+			expectedErr:   "tenant \"acme\" is not allowed",
+		}),
+		Entry("with an allowed email", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedEmails: []string{"jane@example.com"}},
+			session:       session,
+		}),
+		Entry("with an email that is not allowed", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedEmails: []string{"john@example.com"}},
+			session:       session,
+			expectedErr:   "email \"jane@example.com\" is not allowed",
+		}),
+		Entry("with an email in a subdomain of an allowed domain", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedEmailDomains: []string{".example.com"}},
+			session:       &sessions.SessionState{Email: "jane@eu.example.com"},
+		}),
+		Entry("with an email in a domain that is not allowed", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{AllowedEmailDomains: []string{"example.org"}},
+			session:       session,
+			expectedErr:   "email domain of \"jane@example.com\" is not allowed",
+		}),
+		Entry("with the required claims", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{
+				RequiredClaims: []options.ClaimRequirement{
+					{Claim: "preferred_username"},
+					{Claim: "groups", Values: []string{"operators"}},
+				},
+			},
+			session: session,
+		}),
+		Entry("without a required claim", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{
+				RequiredClaims: []options.ClaimRequirement{{Claim: "username"}},
+			},
+			session:     session,
+			expectedErr: "claim \"username\" does not have a required value",
+		}),
+		Entry("without a required value of a claim", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{
+				RequiredClaims: []options.ClaimRequirement{{Claim: "groups", Values: []string{"admins"}}},
+			},
+			session:     session,
+			expectedErr: "claim \"groups\" does not have a required value",
+		}),
+		Entry("with rules that are satisfied", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{Rules: []string{"'operators' in groups"}},
+			session:       session,
+		}),
+		Entry("with a rule that is not satisfied", authorizeTableInput{
+			authorization: &options.UpstreamAuthorization{Rules: []string{"'admins' in groups"}},
+			session:       session,
+			expectedErr:   "rule \"'admins' in groups\" denied the request",
+		}),
+	)
+
+	It("allows the sessions for the upstreams without requirements", func() {
+		authorizer, err := NewUpstreamAuthorizer(options.UpstreamConfig{
+			Upstreams: []options.Upstream{
+				{ID: "admin", Authorization: &options.UpstreamAuthorization{AllowedGroups: []string{"admins"}}},
+				{ID: "app"},
+			},
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		Expect(authorizer.Authorize(req, "admin", session)).ToNot(Succeed())
+		Expect(authorizer.Authorize(req, "app", session)).To(Succeed())
+		Expect(authorizer.Authorize(req, "", session)).To(Succeed())
+	})
+
+	It("fails to create an authorizer with an invalid rule", func() {
+		_, err := NewUpstreamAuthorizer(options.UpstreamConfig{
+			Upstreams: []options.Upstream{
+				{ID: "app", Authorization: &options.UpstreamAuthorization{Rules: []string{"email"}}},
+			},
+		}, nil)
+		Expect(err).To(MatchError("error creating the authorization of upstream \"app\": invalid rule \"email\": rule must evaluate to a bool, not string"))
+	})
+})
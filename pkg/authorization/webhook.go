@@ -0,0 +1,242 @@
+package authorization
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests"
+	requestutil "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests/util"
+)
+
+const (
+	// webhookPruneInterval is how often the expired decisions are removed
+	// from the cache
+	webhookPruneInterval = time.Minute
+	// webhookCacheSize is the maximum number of decisions in the cache, the
+	// decisions expiring first are evicted to make room for new ones
+	webhookCacheSize = 10000
+)
+
+// WebhookRequest describes the request of a session to the authorization
+// webhook
+type WebhookRequest struct {
+	User     string   `json:"user"`
+	Email    string   `json:"email"`
+	Groups   []string `json:"groups"`
+	Tenant   string   `json:"tenant"`
+	Method   string   `json:"method"`
+	Host     string   `json:"host"`
+	Path     string   `json:"path"`
+	Upstream string   `json:"upstream,omitempty"`
+}
+
+// WebhookResponse is the decision of the authorization webhook. The headers
+// are injected into the request of an allowed session.
+type WebhookResponse struct {
+	Allowed bool              `json:"allowed"`
+	Reason  string            `json:"reason,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Webhook delegates the authorization of the requests to an external service
+type Webhook struct {
+	url      string
+	timeout  time.Duration
+	cacheTTL time.Duration
+	failOpen bool
+	// headers are the canonical names of the headers the webhook may set
+	headers []string
+
+	cacheLock sync.Mutex
+	// cache holds the decisions by the hash of the request sent to the
+	// webhook, until they expire
+	cache     map[webhookCacheKey]*webhookDecision
+	lastPrune time.Time
+}
+
+// webhookCacheKey is the hash of the request sent to the webhook, so that a
+// decision is only reused for the same user, groups, tenant, host, path,
+// method and upstream
+type webhookCacheKey [sha256.Size]byte
+
+type webhookDecision struct {
+	response  WebhookResponse
+	expiresAt time.Time
+}
+
+// NewWebhook creates a Webhook from the options. It returns nil when the
+// webhook is disabled.
+func NewWebhook(opts *options.AuthorizationWebhook) *Webhook {
+	if opts.URL == "" {
+		return nil
+	}
+	headers := make([]string, 0, len(opts.Headers))
+	for _, name := range opts.Headers {
+		headers = append(headers, http.CanonicalHeaderKey(name))
+	}
+	return &Webhook{
+		url:      opts.URL,
+		timeout:  opts.Timeout,
+		cacheTTL: opts.CacheTTL,
+		failOpen: opts.FailurePolicy == options.AllowWebhookFailurePolicy,
+		headers:  headers,
+		cache:    make(map[webhookCacheKey]*webhookDecision),
+	}
+}
+
+// StripHeaders removes the headers the webhook may set, so that the values
+// sent by the clients never reach the upstreams, whether the webhook sets
+// them or not
+func (w *Webhook) StripHeaders(headers http.Header) {
+	if w == nil {
+		return
+	}
+	for _, name := range w.headers {
+		headers.Del(name)
+	}
+}
+
+// Authorize asks the webhook whether the session may make the request. It
+// returns the headers to inject into the request when it is allowed, and an
+// error describing why it is not otherwise. When the webhook fails, the
+// request is allowed without headers or denied according to the failure
+// policy.
+func (w *Webhook) Authorize(req *http.Request, s *sessions.SessionState) (http.Header, error) {
+	if w == nil {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(newWebhookRequest(req, s))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the authorization webhook request: %v", err)
+	}
+
+	key := webhookCacheKey(sha256.Sum256(body))
+	response, ok := w.cached(key)
+	if !ok {
+		response, err = w.call(req.Context(), body)
+		if err != nil {
+			if w.failOpen {
+				logger.Errorf("Error calling the authorization webhook, allowing the request: %v", err)
+				return nil, nil
+			}
+			return nil, err
+		}
+		w.store(key, response)
+	}
+
+	if !response.Allowed {
+		if response.Reason != "" {
+			return nil, fmt.Errorf("denied by the authorization webhook: %s", response.Reason)
+		}
+		return nil, errors.New("denied by the authorization webhook")
+	}
+
+	headers := make(http.Header, len(response.Headers))
+	for name, value := range response.Headers {
+		if !slices.Contains(w.headers, http.CanonicalHeaderKey(name)) {
+			logger.Errorf("Ignoring the header %q of the authorization webhook, it is not an authorization webhook header", name)
+			continue
+		}
+		headers.Set(name, value)
+	}
+	return headers, nil
+}
+
+func newWebhookRequest(req *http.Request, s *sessions.SessionState) *WebhookRequest {
+	webhookReq := &WebhookRequest{
+		User:   s.User,
+		Email:  s.Email,
+		Groups: s.Groups,
+		Tenant: s.Tenant,
+		Method: req.Method,
+		Host:   requestutil.GetRequestHost(req),
+		Path:   requestutil.GetRequestPath(req),
+	}
+	if scope := middlewareapi.GetRequestScope(req); scope != nil {
+		webhookReq.Upstream = scope.Upstream
+	}
+	return webhookReq
+}
+
+// call posts the description of the request to the webhook, the webhook
+// fails unless it answers with a 200 and a decision
+func (w *Webhook) call(ctx context.Context, body []byte) (WebhookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	var response WebhookResponse
+	err := requests.New(w.url).
+		WithContext(ctx).
+		WithMethod("POST").
+		WithBody(bytes.NewReader(body)).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Accept", "application/json").
+		Do().
+		UnmarshalInto(&response)
+	if err != nil {
+		return WebhookResponse{}, fmt.Errorf("error calling the authorization webhook: %v", err)
+	}
+	return response, nil
+}
+
+func (w *Webhook) cached(key webhookCacheKey) (WebhookResponse, bool) {
+	w.cacheLock.Lock()
+	defer w.cacheLock.Unlock()
+
+	decision, ok := w.cache[key]
+	if !ok || time.Now().After(decision.expiresAt) {
+		return WebhookResponse{}, false
+	}
+	return decision.response, true
+}
+
+func (w *Webhook) store(key webhookCacheKey, response WebhookResponse) {
+	if w.cacheTTL <= 0 {
+		return
+	}
+
+	w.cacheLock.Lock()
+	defer w.cacheLock.Unlock()
+
+	now := time.Now()
+	if now.Sub(w.lastPrune) > webhookPruneInterval || len(w.cache) >= webhookCacheSize {
+		for k, decision := range w.cache {
+			if now.After(decision.expiresAt) {
+				delete(w.cache, k)
+			}
+		}
+		w.lastPrune = now
+	}
+	if _, ok := w.cache[key]; !ok && len(w.cache) >= webhookCacheSize {
+		w.evictFirstExpiring()
+	}
+
+	w.cache[key] = &webhookDecision{response: response, expiresAt: now.Add(w.cacheTTL)}
+}
+
+// evictFirstExpiring removes the decision expiring first from the full cache
+func (w *Webhook) evictFirstExpiring() {
+	var (
+		firstKey       webhookCacheKey
+		firstExpiresAt time.Time
+	)
+	for k, decision := range w.cache {
+		if firstExpiresAt.IsZero() || decision.expiresAt.Before(firstExpiresAt) {
+			firstKey, firstExpiresAt = k, decision.expiresAt
+		}
+	}
+	delete(w.cache, firstKey)
+}
@@ -0,0 +1,223 @@
+package authorization
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhook", func() {
+	var (
+		server   *httptest.Server
+		calls    atomic.Int32
+		requests chan WebhookRequest
+		response WebhookResponse
+		status   int
+	)
+
+	session := &sessions.SessionState{
+		User:   "jane",
+		Email:  "jane@example.com",
+		Groups: []string{"developers"},
+		Tenant: "acme",
+	}
+
+	BeforeEach(func() {
+		calls.Store(0)
+		requests = make(chan WebhookRequest, 10)
+		response = WebhookResponse{Allowed: true}
+		status = http.StatusOK
+
+		server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			calls.Add(1)
+			var webhookReq WebhookRequest
+			Expect(json.NewDecoder(req.Body).Decode(&webhookReq)).To(Succeed())
+			requests <- webhookReq
+
+			rw.WriteHeader(status)
+			Expect(json.NewEncoder(rw).Encode(response)).To(Succeed())
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newWebhook := func(cacheTTL time.Duration, failurePolicy string) *Webhook {
+		return NewWebhook(&options.AuthorizationWebhook{
+			URL:           server.URL,
+			Timeout:       time.Second,
+			CacheTTL:      cacheTTL,
+			FailurePolicy: failurePolicy,
+			Headers:       []string{"x-entitlements"},
+		})
+	}
+
+	newRequest := func(method string, path string) *http.Request {
+		return httptest.NewRequest(method, "http://app.example.com"+path, nil)
+	}
+
+	It("is disabled without a URL", func() {
+		webhook := NewWebhook(&options.AuthorizationWebhook{})
+		Expect(webhook).To(BeNil())
+
+		headers, err := webhook.Authorize(newRequest("GET", "/"), session)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headers).To(BeNil())
+	})
+
+	It("describes the request and returns the headers of an allowed session", func() {
+		response.Headers = map[string]string{"x-entitlements": "read,write"}
+		webhook := newWebhook(0, options.DenyWebhookFailurePolicy)
+
+		headers, err := webhook.Authorize(newRequest("POST", "/documents"), session)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headers).To(Equal(http.Header{"X-Entitlements": []string{"read,write"}}))
+		Expect(<-requests).To(Equal(WebhookRequest{
+			User:   "jane",
+			Email:  "jane@example.com",
+			Groups: []string{"developers"},
+			Tenant: "acme",
+			Method: "POST",
+			Host:   "app.example.com",
+			Path:   "/documents",
+		}))
+	})
+
+	It("ignores the headers that are not authorization webhook headers", func() {
+		response.Headers = map[string]string{"X-Entitlements": "read", "X-Forwarded-User": "admin"}
+		webhook := newWebhook(0, options.DenyWebhookFailurePolicy)
+
+		headers, err := webhook.Authorize(newRequest("GET", "/"), session)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(headers).To(Equal(http.Header{"X-Entitlements": []string{"read"}}))
+	})
+
+	It("strips the authorization webhook headers", func() {
+		webhook := newWebhook(0, options.DenyWebhookFailurePolicy)
+		headers := http.Header{"X-Entitlements": []string{"admin"}, "Accept": []string{"*/*"}}
+		webhook.StripHeaders(headers)
+		Expect(headers).To(Equal(http.Header{"Accept": []string{"*/*"}}))
+
+		var disabled *Webhook
+		disabled.StripHeaders(headers)
+		Expect(headers).To(Equal(http.Header{"Accept": []string{"*/*"}}))
+	})
+
+	It("denies the sessions the webhook does not allow", func() {
+		response = WebhookResponse{Allowed: false, Reason: "no entitlement"}
+		webhook := newWebhook(0, options.DenyWebhookFailurePolicy)
+
+		_, err := webhook.Authorize(newRequest("GET", "/"), session)
+		Expect(err).To(MatchError("denied by the authorization webhook: no entitlement"))
+	})
+
+	It("caches the decisions by the request sent to the webhook", func() {
+		webhook := newWebhook(time.Minute, options.DenyWebhookFailurePolicy)
+
+		for i := 0; i < 3; i++ {
+			_, err := webhook.Authorize(newRequest("GET", "/documents"), session)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+
+		otherHost := httptest.NewRequest("GET", "http://other.example.com/documents", nil)
+		otherUpstream := middlewareapi.AddRequestScope(newRequest("GET", "/documents"), &middlewareapi.RequestScope{Upstream: "documents"})
+		otherTenant := *session
+		otherTenant.Tenant = "umbrella"
+
+		for _, authorize := range []func() (http.Header, error){
+			func() (http.Header, error) { return webhook.Authorize(newRequest("DELETE", "/documents"), session) },
+			func() (http.Header, error) {
+				return webhook.Authorize(newRequest("GET", "/documents"), &sessions.SessionState{User: "john"})
+			},
+			func() (http.Header, error) { return webhook.Authorize(otherHost, session) },
+			func() (http.Header, error) { return webhook.Authorize(otherUpstream, session) },
+			func() (http.Header, error) { return webhook.Authorize(newRequest("GET", "/documents"), &otherTenant) },
+		} {
+			_, err := authorize()
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(calls.Load()).To(BeEquivalentTo(6))
+	})
+
+	It("evicts the decisions expiring first when the cache is full", func() {
+		webhook := newWebhook(time.Minute, options.DenyWebhookFailurePolicy)
+
+		key := func(i int) webhookCacheKey {
+			return webhookCacheKey(sha256.Sum256([]byte(strconv.Itoa(i))))
+		}
+		for i := 0; i < webhookCacheSize; i++ {
+			webhook.store(key(i), WebhookResponse{Allowed: true})
+		}
+		webhook.cache[key(42)].expiresAt = time.Now().Add(time.Second)
+
+		webhook.store(key(webhookCacheSize), WebhookResponse{Allowed: true})
+		Expect(webhook.cache).To(HaveLen(webhookCacheSize))
+		_, ok := webhook.cached(key(42))
+		Expect(ok).To(BeFalse())
+		_, ok = webhook.cached(key(webhookCacheSize))
+		Expect(ok).To(BeTrue())
+	})
+
+	It("does not cache the decisions without a cache TTL", func() {
+		webhook := newWebhook(0, options.DenyWebhookFailurePolicy)
+
+		for i := 0; i < 3; i++ {
+			_, err := webhook.Authorize(newRequest("GET", "/documents"), session)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(calls.Load()).To(BeEquivalentTo(3))
+	})
+
+	It("gives up on the webhook after the timeout", func() {
+		slowServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			select {
+			case <-req.Context().Done():
+			case <-time.After(300 * time.Millisecond):
+			}
+		}))
+		DeferCleanup(slowServer.Close)
+
+		webhook := NewWebhook(&options.AuthorizationWebhook{
+			URL:           slowServer.URL,
+			Timeout:       50 * time.Millisecond,
+			FailurePolicy: options.DenyWebhookFailurePolicy,
+		})
+		_, err := webhook.Authorize(newRequest("GET", "/"), session)
+		Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+	})
+
+	Context("when the webhook fails", func() {
+		BeforeEach(func() {
+			status = http.StatusInternalServerError
+		})
+
+		It("denies the requests with the deny failure policy", func() {
+			webhook := newWebhook(time.Minute, options.DenyWebhookFailurePolicy)
+
+			_, err := webhook.Authorize(newRequest("GET", "/"), session)
+			Expect(err).To(MatchError(ContainSubstring("error calling the authorization webhook")))
+		})
+
+		It("allows the requests with the allow failure policy", func() {
+			webhook := newWebhook(time.Minute, options.AllowWebhookFailurePolicy)
+
+			headers, err := webhook.Authorize(newRequest("GET", "/"), session)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(headers).To(BeNil())
+
+			// The failures are not cached
+			_, err = webhook.Authorize(newRequest("GET", "/"), session)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls.Load()).To(BeEquivalentTo(2))
+		})
+	})
+})
@@ -214,32 +214,33 @@ func (c *csrf) encodeCookie() (string, error) {
 		return "", fmt.Errorf("error marshalling CSRF to msgpack: %v", err)
 	}
 
-	encrypted, err := encrypt(packed, c.cookieOpts)
+	secret, err := c.cookieOpts.GetSecret()
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("error getting cookie secret: %v", err)
 	}
 
-	secret, err := c.cookieOpts.GetSecret()
+	encrypted, err := encrypt(packed, secret)
 	if err != nil {
-		return "", fmt.Errorf("error getting cookie secret: %v", err)
+		return "", err
 	}
 	return encryption.SignedValue(secret, c.cookieName(), encrypted, c.clock())
 }
 
 // decodeCSRFCookie validates the signature then decrypts and decodes a CSRF
-// cookie into a CSRF struct
+// cookie into a CSRF struct. The cookies of the previous cookie secrets are
+// accepted, so that rotating the secret does not fail the logins in flight.
 func decodeCSRFCookie(cookie *http.Cookie, opts *options.Cookie) (*csrf, error) {
-	secret, err := opts.GetSecret()
+	secrets, err := opts.GetSecrets()
 	if err != nil {
 		return nil, fmt.Errorf("error getting cookie secret: %v", err)
 	}
 
-	val, t, ok := encryption.Validate(cookie, secret, opts.Expire)
+	val, t, secret, ok := encryption.ValidateAny(cookie, secrets, opts.Expire)
 	if !ok {
 		return nil, errors.New("CSRF cookie failed validation")
 	}
 
-	decrypted, err := decrypt(val, opts)
+	decrypted, err := decrypt(val, secrets[secret])
 	if err != nil {
 		return nil, err
 	}
@@ -285,26 +286,18 @@ func ExtractStateSubstring(state string) string {
 	return stateSubstring
 }
 
-func encrypt(data []byte, opts *options.Cookie) ([]byte, error) {
-	cipher, err := makeCipher(opts)
+func encrypt(data []byte, secret string) ([]byte, error) {
+	cipher, err := encryption.NewCFBCipher(encryption.SecretBytes(secret))
 	if err != nil {
 		return nil, err
 	}
 	return cipher.Encrypt(data)
 }
 
-func decrypt(data []byte, opts *options.Cookie) ([]byte, error) {
-	cipher, err := makeCipher(opts)
+func decrypt(data []byte, secret string) ([]byte, error) {
+	cipher, err := encryption.NewCFBCipher(encryption.SecretBytes(secret))
 	if err != nil {
 		return nil, err
 	}
 	return cipher.Decrypt(data)
 }
-
-func makeCipher(opts *options.Cookie) (encryption.Cipher, error) {
-	secret, err := opts.GetSecret()
-	if err != nil {
-		return nil, fmt.Errorf("error getting cookie secret: %v", err)
-	}
-	return encryption.NewCFBCipher(encryption.SecretBytes(secret))
-}
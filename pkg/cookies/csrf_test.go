@@ -122,6 +122,27 @@ var _ = Describe("CSRF Cookie Tests", func() {
 			_, _, valid := encryption.Validate(cookie, cookieOpts.Secret, cookieOpts.Expire)
 			Expect(valid).To(BeTrue())
 		})
+
+		It("decodes the cookies encoded with a previous cookie secret", func() {
+			privateCSRF.OAuthState = []byte(csrfState)
+
+			encoded, err := privateCSRF.encodeCookie()
+			Expect(err).ToNot(HaveOccurred())
+			cookie := &http.Cookie{
+				Name:  privateCSRF.cookieName(),
+				Value: encoded,
+			}
+
+			rotatedOpts := *cookieOpts
+			rotatedOpts.Secret = "3jk3lcVCJFU9320FJfH2JF03HFmh84q3"
+			_, err = decodeCSRFCookie(cookie, &rotatedOpts)
+			Expect(err).To(MatchError("CSRF cookie failed validation"))
+
+			rotatedOpts.PreviousSecrets = []string{cookieOpts.Secret}
+			decoded, err := decodeCSRFCookie(cookie, &rotatedOpts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.OAuthState).To(Equal([]byte(csrfState)))
+		})
 	})
 
 	Context("Cookie Management", func() {
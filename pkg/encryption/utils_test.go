@@ -130,6 +130,26 @@ func TestValidate(t *testing.T) {
 	assert.Equal(t, validValue, expectedValue)
 }
 
+func TestValidateAny(t *testing.T) {
+	seed := "0123456789abcdef"
+	key := "cookie-name"
+	now := time.Now()
+
+	signed, err := SignedValue(seed, key, []byte("I am soooo encoded"), now)
+	assert.NoError(t, err)
+	cookie := &http.Cookie{Name: key, Value: signed}
+
+	value, timestamp, index, ok := ValidateAny(cookie, []string{"fedcba9876543210", seed}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, now.Unix(), timestamp.Unix())
+	assert.Equal(t, []byte("I am soooo encoded"), value)
+
+	_, _, index, ok = ValidateAny(cookie, []string{"fedcba9876543210"}, 0)
+	assert.False(t, ok)
+	assert.Equal(t, -1, index)
+}
+
 func TestGenerateCodeVerifierString(t *testing.T) {
 	randomString, err := GenerateCodeVerifierString(96)
 	assert.NoError(t, err)
@@ -0,0 +1,212 @@
+package extauthz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	proxyhttp "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/http"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+)
+
+// Opts contains the information required to set up the ext_authz server
+type Opts struct {
+	// Handler authorizes the requests like the auth endpoint. The requests
+	// it answers with a 2xx are allowed, with the headers of the response
+	// injected into the request sent upstream. The other responses are
+	// returned to the client.
+	Handler http.Handler
+
+	// BindAddress is the address the gRPC server listens on
+	BindAddress string
+}
+
+// server is an Envoy ext_authz authorization server, serving the checks of
+// Envoy with an http.Handler
+type server struct {
+	authv3.UnimplementedAuthorizationServer
+
+	handler    http.Handler
+	listener   net.Listener
+	grpcServer *grpc.Server
+}
+
+// NewServer creates a Server serving the envoy.service.auth.v3.Authorization
+// gRPC service on the bind address
+func NewServer(opts Opts) (proxyhttp.Server, error) {
+	listener, err := net.Listen("tcp", opts.BindAddress)
+	if err != nil {
+		return nil, fmt.Errorf("listen (%s) failed: %v", opts.BindAddress, err)
+	}
+	logger.Printf("Envoy ext_authz server listening on %s", listener.Addr())
+
+	s := &server{
+		handler:    opts.Handler,
+		listener:   listener,
+		grpcServer: grpc.NewServer(),
+	}
+	authv3.RegisterAuthorizationServer(s.grpcServer, s)
+	return s, nil
+}
+
+// Start serves the checks until the context is cancelled
+func (s *server) Start(ctx context.Context) error {
+	g, groupCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		<-groupCtx.Done()
+		s.grpcServer.GracefulStop()
+		return nil
+	})
+
+	g.Go(func() error {
+		if err := s.grpcServer.Serve(s.listener); err != nil {
+			return fmt.Errorf("could not start ext_authz server: %v", err)
+		}
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// Check authorizes the request described by Envoy with the handler
+func (s *server) Check(ctx context.Context, check *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	req, err := newHTTPRequest(ctx, check)
+	if err != nil {
+		return nil, err
+	}
+
+	rw := newResponseRecorder()
+	s.handler.ServeHTTP(rw, req)
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	if rw.status >= 200 && rw.status < 300 {
+		return okResponse(rw), nil
+	}
+	return deniedResponse(rw), nil
+}
+
+// newHTTPRequest rebuilds the HTTP request described by Envoy
+func newHTTPRequest(ctx context.Context, check *authv3.CheckRequest) (*http.Request, error) {
+	attributes := check.GetAttributes().GetRequest().GetHttp()
+	if attributes == nil {
+		return nil, fmt.Errorf("the check request does not describe an HTTP request")
+	}
+
+	scheme := attributes.GetScheme()
+	if scheme == "" {
+		scheme = "http"
+	}
+	u, err := url.Parse(scheme + "://" + attributes.GetHost() + attributes.GetPath())
+	if err != nil {
+		return nil, fmt.Errorf("invalid request url: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, attributes.GetMethod(), u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+	for name, value := range attributes.GetHeaders() {
+		// Envoy also passes the HTTP/2 pseudo-headers
+		if !strings.HasPrefix(name, ":") {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Host = attributes.GetHost()
+
+	if address := check.GetAttributes().GetSource().GetAddress().GetSocketAddress(); address != nil {
+		req.RemoteAddr = net.JoinHostPort(address.GetAddress(), strconv.FormatUint(uint64(address.GetPortValue()), 10))
+	}
+	return req, nil
+}
+
+// okResponse allows the request, injecting the headers of the response into
+// the request sent upstream. The cookies are returned to the client.
+func okResponse(rw *responseRecorder) *authv3.CheckResponse {
+	ok := &authv3.OkHttpResponse{}
+	for name, values := range rw.header {
+		for _, value := range values {
+			if name == "Set-Cookie" {
+				ok.ResponseHeadersToAdd = append(ok.ResponseHeadersToAdd, headerValue(name, value, corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD))
+				continue
+			}
+			ok.Headers = append(ok.Headers, headerValue(name, value, corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD))
+		}
+	}
+
+	return &authv3.CheckResponse{
+		Status:       &status.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok},
+	}
+}
+
+// deniedResponse returns the response to the client instead of proxying the
+// request upstream
+func deniedResponse(rw *responseRecorder) *authv3.CheckResponse {
+	denied := &authv3.DeniedHttpResponse{
+		Status: &typev3.HttpStatus{Code: typev3.StatusCode(rw.status)},
+		Body:   rw.body.String(),
+	}
+	for name, values := range rw.header {
+		for _, value := range values {
+			denied.Headers = append(denied.Headers, headerValue(name, value, corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD))
+		}
+	}
+
+	code := codes.PermissionDenied
+	if rw.status == http.StatusUnauthorized || (rw.status >= 300 && rw.status < 400) {
+		code = codes.Unauthenticated
+	}
+	return &authv3.CheckResponse{
+		Status:       &status.Status{Code: int32(code)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: denied},
+	}
+}
+
+func headerValue(name, value string, action corev3.HeaderValueOption_HeaderAppendAction) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: name, Value: value},
+		AppendAction: action,
+	}
+}
+
+// responseRecorder records the response of the handler
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
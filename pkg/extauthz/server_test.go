@@ -0,0 +1,138 @@
+package extauthz
+
+import (
+	"context"
+	"net/http"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var _ = Describe("Server", func() {
+	newCheckRequest := func(path string, headers map[string]string) *authv3.CheckRequest {
+		return &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Source: &authv3.AttributeContext_Peer{
+					Address: &corev3.Address{
+						Address: &corev3.Address_SocketAddress{
+							SocketAddress: &corev3.SocketAddress{
+								Address:       "10.0.0.1",
+								PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 51234},
+							},
+						},
+					},
+				},
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method:  "GET",
+						Scheme:  "https",
+						Host:    "app.example.com",
+						Path:    path,
+						Headers: headers,
+					},
+				},
+			},
+		}
+	}
+
+	headerValues := func(options []*corev3.HeaderValueOption) map[string]string {
+		values := map[string]string{}
+		for _, option := range options {
+			values[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+		}
+		return values
+	}
+
+	It("rebuilds the request of the client", func() {
+		var req *http.Request
+		s := &server{handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			req = r
+		})}
+
+		_, err := s.Check(context.Background(), newCheckRequest("/documents?page=2", map[string]string{
+			":authority": "app.example.com",
+			"cookie":     "_oauth2_proxy=session",
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.Method).To(Equal("GET"))
+		Expect(req.URL.String()).To(Equal("https://app.example.com/documents?page=2"))
+		Expect(req.Host).To(Equal("app.example.com"))
+		Expect(req.RemoteAddr).To(Equal("10.0.0.1:51234"))
+		Expect(req.Header).To(Equal(http.Header{"Cookie": []string{"_oauth2_proxy=session"}}))
+	})
+
+	It("allows the requests with the headers for the upstream", func() {
+		s := &server{handler: http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			rw.Header().Set("X-Auth-Request-User", "jane")
+			rw.Header().Add("Set-Cookie", "_oauth2_proxy=refreshed")
+			rw.WriteHeader(http.StatusAccepted)
+		})}
+
+		response, err := s.Check(context.Background(), newCheckRequest("/", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.GetStatus().GetCode()).To(BeEquivalentTo(codes.OK))
+		Expect(headerValues(response.GetOkResponse().GetHeaders())).To(Equal(map[string]string{
+			"X-Auth-Request-User": "jane",
+		}))
+		Expect(headerValues(response.GetOkResponse().GetResponseHeadersToAdd())).To(Equal(map[string]string{
+			"Set-Cookie": "_oauth2_proxy=refreshed",
+		}))
+	})
+
+	It("returns the redirects to the client", func() {
+		s := &server{handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.Redirect(rw, req, "/oauth2/sign_in?rd=%2F", http.StatusFound)
+		})}
+
+		response, err := s.Check(context.Background(), newCheckRequest("/", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.GetStatus().GetCode()).To(BeEquivalentTo(codes.Unauthenticated))
+		Expect(response.GetDeniedResponse().GetStatus().GetCode()).To(Equal(typev3.StatusCode_Found))
+		Expect(headerValues(response.GetDeniedResponse().GetHeaders())).To(HaveKeyWithValue("Location", "/oauth2/sign_in?rd=%2F"))
+	})
+
+	It("denies the requests that are not authorized", func() {
+		s := &server{handler: http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})}
+
+		response, err := s.Check(context.Background(), newCheckRequest("/", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.GetStatus().GetCode()).To(BeEquivalentTo(codes.PermissionDenied))
+		Expect(response.GetDeniedResponse().GetStatus().GetCode()).To(Equal(typev3.StatusCode_Forbidden))
+		Expect(response.GetDeniedResponse().GetBody()).To(Equal("Forbidden\n"))
+	})
+
+	It("serves the checks over gRPC", func() {
+		srv, err := NewServer(Opts{
+			Handler: http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+				rw.WriteHeader(http.StatusAccepted)
+			}),
+			BindAddress: "127.0.0.1:0",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- srv.Start(ctx)
+		}()
+
+		conn, err := grpc.NewClient(srv.(*server).listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		response, err := authv3.NewAuthorizationClient(conn).Check(context.Background(), newCheckRequest("/", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.GetStatus().GetCode()).To(BeEquivalentTo(codes.OK))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
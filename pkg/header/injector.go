@@ -2,33 +2,41 @@ package header
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options/util"
-	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	sessionsjwt "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/jwt"
 )
 
 type Injector interface {
-	Inject(http.Header, *sessionsapi.SessionState)
+	Inject(http.Header, *middlewareapi.RequestScope)
 }
 
 type injector struct {
 	valueInjectors []valueInjector
 }
 
-func (i injector) Inject(header http.Header, session *sessionsapi.SessionState) {
+func (i injector) Inject(header http.Header, scope *middlewareapi.RequestScope) {
 	for _, injector := range i.valueInjectors {
-		injector.inject(header, session)
+		injector.inject(header, scope)
 	}
 }
 
-func NewInjector(headers []options.Header) (Injector, error) {
+// NewInjector creates an Injector for the headers.
+// The keys are used to sign the JWTs of JWTSource values, they may be nil when
+// no such value is configured.
+func NewInjector(headers []options.Header, keys *sessionsjwt.KeySet) (Injector, error) {
 	injectors := []valueInjector{}
 	for _, header := range headers {
 		for _, value := range header.Values {
-			injector, err := newValueinjector(header.Name, value)
+			injector, err := newValueinjector(header.Name, value, keys)
 			if err != nil {
 				return nil, fmt.Errorf("error building injector for header %q: %v", header.Name, err)
 			}
@@ -40,29 +48,31 @@ func NewInjector(headers []options.Header) (Injector, error) {
 }
 
 type valueInjector interface {
-	inject(http.Header, *sessionsapi.SessionState)
+	inject(http.Header, *middlewareapi.RequestScope)
 }
 
-func newValueinjector(name string, value options.HeaderValue) (valueInjector, error) {
+func newValueinjector(name string, value options.HeaderValue, keys *sessionsjwt.KeySet) (valueInjector, error) {
 	switch {
-	case value.SecretSource != nil && value.ClaimSource == nil:
+	case value.SecretSource != nil && value.ClaimSource == nil && value.JWT == nil:
 		return newSecretInjector(name, value.SecretSource)
-	case value.SecretSource == nil && value.ClaimSource != nil:
+	case value.SecretSource == nil && value.ClaimSource != nil && value.JWT == nil:
 		return newClaimInjector(name, value.ClaimSource)
+	case value.SecretSource == nil && value.ClaimSource == nil && value.JWT != nil:
+		return newJWTInjector(name, value.JWT, keys)
 	default:
 		return nil, fmt.Errorf("header %q value has multiple entries: only one entry per value is allowed", name)
 	}
 }
 
 type injectorFunc struct {
-	injectFunc func(http.Header, *sessionsapi.SessionState)
+	injectFunc func(http.Header, *middlewareapi.RequestScope)
 }
 
-func (i *injectorFunc) inject(header http.Header, session *sessionsapi.SessionState) {
-	i.injectFunc(header, session)
+func (i *injectorFunc) inject(header http.Header, scope *middlewareapi.RequestScope) {
+	i.injectFunc(header, scope)
 }
 
-func newInjectorFunc(injectFunc func(header http.Header, session *sessionsapi.SessionState)) valueInjector {
+func newInjectorFunc(injectFunc func(header http.Header, scope *middlewareapi.RequestScope)) valueInjector {
 	return &injectorFunc{injectFunc: injectFunc}
 }
 
@@ -72,7 +82,7 @@ func newSecretInjector(name string, source *options.SecretSource) (valueInjector
 		return nil, fmt.Errorf("error getting secret value: %v", err)
 	}
 
-	return newInjectorFunc(func(header http.Header, _ *sessionsapi.SessionState) {
+	return newInjectorFunc(func(header http.Header, _ *middlewareapi.RequestScope) {
 		header.Add(name, string(value))
 	}), nil
 }
@@ -84,8 +94,8 @@ func newClaimInjector(name string, source *options.ClaimSource) (valueInjector,
 		if err != nil {
 			return nil, fmt.Errorf("error loading basicAuthPassword: %v", err)
 		}
-		return newInjectorFunc(func(header http.Header, session *sessionsapi.SessionState) {
-			claimValues := session.GetClaim(source.Claim)
+		return newInjectorFunc(func(header http.Header, scope *middlewareapi.RequestScope) {
+			claimValues := scope.Session.GetClaim(source.Claim)
 			for _, claim := range claimValues {
 				if claim == "" {
 					continue
@@ -95,8 +105,8 @@ func newClaimInjector(name string, source *options.ClaimSource) (valueInjector,
 			}
 		}), nil
 	case source.Prefix != "":
-		return newInjectorFunc(func(header http.Header, session *sessionsapi.SessionState) {
-			claimValues := session.GetClaim(source.Claim)
+		return newInjectorFunc(func(header http.Header, scope *middlewareapi.RequestScope) {
+			claimValues := scope.Session.GetClaim(source.Claim)
 			for _, claim := range claimValues {
 				if claim == "" {
 					continue
@@ -105,8 +115,8 @@ func newClaimInjector(name string, source *options.ClaimSource) (valueInjector,
 			}
 		}), nil
 	default:
-		return newInjectorFunc(func(header http.Header, session *sessionsapi.SessionState) {
-			claimValues := session.GetClaim(source.Claim)
+		return newInjectorFunc(func(header http.Header, scope *middlewareapi.RequestScope) {
+			claimValues := scope.Session.GetClaim(source.Claim)
 			for _, claim := range claimValues {
 				if claim == "" {
 					continue
@@ -116,3 +126,42 @@ func newClaimInjector(name string, source *options.ClaimSource) (valueInjector,
 		}), nil
 	}
 }
+
+func newJWTInjector(name string, source *options.JWTSource, keys *sessionsjwt.KeySet) (valueInjector, error) {
+	if keys == nil {
+		return nil, errors.New("jwt values require a jwt-session-key or jwt-session-key-file to sign the tokens")
+	}
+
+	lifetime := options.DefaultJWTSourceLifetime
+	if source.Lifetime != nil {
+		lifetime = source.Lifetime.Duration()
+	}
+
+	return newInjectorFunc(func(header http.Header, scope *middlewareapi.RequestScope) {
+		if scope.Session == nil {
+			return
+		}
+
+		audience := source.Audience
+		if audience == "" {
+			audience = scope.Upstream
+		}
+
+		now := time.Now()
+		claims := sessionsjwt.NewClaims(scope.Session)
+		claims.Issuer = source.Issuer
+		claims.IssuedAt = jwt.NewNumericDate(now)
+		claims.NotBefore = jwt.NewNumericDate(now)
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(lifetime))
+		if audience != "" {
+			claims.Audience = jwt.ClaimStrings{audience}
+		}
+
+		token, err := keys.Sign(claims)
+		if err != nil {
+			logger.Errorf("Error signing JWT for header %q: %v", name, err)
+			return
+		}
+		header.Add(name, source.Prefix+token)
+	}), nil
+}
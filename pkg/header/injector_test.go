@@ -1,16 +1,28 @@
 package header
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	sessionsjwt "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/jwt"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+var jwtLifetime = options.Duration(time.Minute)
+
 var _ = Describe("Injector Suite", func() {
 	Context("NewInjector", func() {
 		type newInjectorTableInput struct {
@@ -23,7 +35,7 @@ var _ = Describe("Injector Suite", func() {
 
 		DescribeTable("creates an injector",
 			func(in newInjectorTableInput) {
-				injector, err := NewInjector(in.headers)
+				injector, err := NewInjector(in.headers, nil)
 				if in.expectedErr != nil {
 					Expect(err).To(MatchError(in.expectedErr))
 					Expect(injector).To(BeNil())
@@ -34,7 +46,7 @@ var _ = Describe("Injector Suite", func() {
 				Expect(injector).ToNot(BeNil())
 
 				headers := in.initialHeaders.Clone()
-				injector.Inject(headers, in.session)
+				injector.Inject(headers, &middlewareapi.RequestScope{Session: in.session})
 				Expect(headers).To(Equal(in.expectedHeaders))
 			},
 			Entry("with no configured headers", newInjectorTableInput{
@@ -413,4 +425,112 @@ var _ = Describe("Injector Suite", func() {
 			}),
 		)
 	})
+
+	Context("with a JWT source", func() {
+		var keys *sessionsjwt.KeySet
+
+		BeforeEach(func() {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			keys, err = sessionsjwt.NewKeySet(options.JWTStoreOptions{
+				JWTKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		session := &sessionsapi.SessionState{
+			User:   "user-123",
+			Email:  "user@example.com",
+			Groups: []string{"group-a", "group-b"},
+			Tenant: "tenant-a",
+		}
+
+		type jwtInjectorTableInput struct {
+			source           *options.JWTSource
+			upstream         string
+			expectedPrefix   string
+			expectedAudience jwt.ClaimStrings
+			expectedLifetime time.Duration
+		}
+
+		DescribeTable("injects a signed JWT",
+			func(in jwtInjectorTableInput) {
+				injector, err := NewInjector([]options.Header{
+					{
+						Name:   "X-Auth-Request-Jwt",
+						Values: []options.HeaderValue{{JWT: in.source}},
+					},
+				}, keys)
+				Expect(err).ToNot(HaveOccurred())
+
+				headers := http.Header{}
+				injector.Inject(headers, &middlewareapi.RequestScope{Session: session, Upstream: in.upstream})
+				Expect(headers).To(HaveKey("X-Auth-Request-Jwt"))
+				Expect(headers["X-Auth-Request-Jwt"]).To(HaveLen(1))
+
+				value := headers.Get("X-Auth-Request-Jwt")
+				Expect(value).To(HavePrefix(in.expectedPrefix))
+
+				claims := &sessionsjwt.Claims{}
+				_, err = keys.Parse(strings.TrimPrefix(value, in.expectedPrefix), claims)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(claims.Subject).To(Equal("user-123"))
+				Expect(claims.Mail).To(Equal("user@example.com"))
+				Expect(claims.Groups).To(Equal([]string{"group-a", "group-b"}))
+				Expect(claims.Tenant).To(Equal("tenant-a"))
+				Expect(claims.Issuer).To(Equal(in.source.Issuer))
+				Expect(claims.Audience).To(Equal(in.expectedAudience))
+				Expect(claims.ExpiresAt.Sub(claims.IssuedAt.Time)).To(Equal(in.expectedLifetime))
+			},
+			Entry("with the default options", jwtInjectorTableInput{
+				source:           &options.JWTSource{},
+				expectedLifetime: options.DefaultJWTSourceLifetime,
+			}),
+			Entry("with the upstream as audience", jwtInjectorTableInput{
+				source:           &options.JWTSource{},
+				upstream:         "backend",
+				expectedAudience: jwt.ClaimStrings{"backend"},
+				expectedLifetime: options.DefaultJWTSourceLifetime,
+			}),
+			Entry("with all options", jwtInjectorTableInput{
+				source: &options.JWTSource{
+					Issuer:   "https://proxy.example.com",
+					Audience: "api",
+					Lifetime: &jwtLifetime,
+					Prefix:   "Bearer ",
+				},
+				upstream:         "backend",
+				expectedPrefix:   "Bearer ",
+				expectedAudience: jwt.ClaimStrings{"api"},
+				expectedLifetime: time.Minute,
+			}),
+		)
+
+		It("does not inject a JWT without a session", func() {
+			injector, err := NewInjector([]options.Header{
+				{
+					Name:   "X-Auth-Request-Jwt",
+					Values: []options.HeaderValue{{JWT: &options.JWTSource{}}},
+				},
+			}, keys)
+			Expect(err).ToNot(HaveOccurred())
+
+			headers := http.Header{}
+			injector.Inject(headers, &middlewareapi.RequestScope{})
+			Expect(headers).To(BeEmpty())
+		})
+
+		It("fails without keys", func() {
+			_, err := NewInjector([]options.Header{
+				{
+					Name:   "X-Auth-Request-Jwt",
+					Values: []options.HeaderValue{{JWT: &options.JWTSource{}}},
+				},
+			}, nil)
+			Expect(err).To(MatchError("error building injector for header \"X-Auth-Request-Jwt\": jwt values require a jwt-session-key or jwt-session-key-file to sign the tokens"))
+		})
+	})
 })
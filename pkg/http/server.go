@@ -38,6 +38,10 @@ type Opts struct {
 	// TLS is the TLS configuration for the server.
 	TLS *options.TLS
 
+	// RequestClientCertificate asks the TLS clients for a certificate, which
+	// is optional and not verified.
+	RequestClientCertificate bool
+
 	// Let testing infrastructure circumvent parsing file descriptors
 	fdFiles []*os.File
 }
@@ -148,6 +152,10 @@ func (s *server) setupTLSListener(opts Opts) error {
 	}
 	config.Certificates = []tls.Certificate{cert}
 
+	if opts.RequestClientCertificate {
+		config.ClientAuth = tls.RequestClientCert
+	}
+
 	if len(opts.TLS.CipherSuites) > 0 {
 		cipherSuites, err := parseCipherSuites(opts.TLS.CipherSuites)
 		if err != nil {
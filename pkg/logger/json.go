@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONLoggingFormat selects the JSON output instead of a template when it is
+// given as the format of a log type
+const JSONLoggingFormat = "json"
+
+// These are the JSON documents written for each log type when the JSON
+// output is selected. They hold the same values as the template containers,
+// with typed values instead of pre-formatted strings.
+type stdLogMessageJSON struct {
+	Timestamp time.Time `json:"timestamp"`
+	File      string    `json:"file"`
+	Message   string    `json:"message"`
+}
+
+type authLogMessageJSON struct {
+	Client        string    `json:"client"`
+	Host          string    `json:"host"`
+	Protocol      string    `json:"protocol"`
+	RequestID     string    `json:"request_id"`
+	RequestMethod string    `json:"request_method"`
+	Timestamp     time.Time `json:"timestamp"`
+	UserAgent     string    `json:"user_agent"`
+	Username      string    `json:"username"`
+	Status        string    `json:"status"`
+	Message       string    `json:"message"`
+}
+
+type reqLogMessageJSON struct {
+	Client          string    `json:"client"`
+	Host            string    `json:"host"`
+	Protocol        string    `json:"protocol"`
+	RequestID       string    `json:"request_id"`
+	RequestDuration float64   `json:"request_duration"`
+	RequestMethod   string    `json:"request_method"`
+	RequestURI      string    `json:"request_uri"`
+	ResponseSize    int       `json:"response_size"`
+	StatusCode      int       `json:"status_code"`
+	Timestamp       time.Time `json:"timestamp"`
+	Upstream        string    `json:"upstream"`
+	UserAgent       string    `json:"user_agent"`
+	Username        string    `json:"username"`
+}
+
+// writeJSON writes the message as a single JSON line.
+// User controlled values, e.g. the user agent, are escaped by the encoder so
+// that they can never break the line or the document.
+func writeJSON(w io.Writer, message interface{}) error {
+	// The encoder terminates the document with a newline
+	return json.NewEncoder(w).Encode(message)
+}
+
+// jsonTimestamp returns the time of a JSON message, honouring the LUTC flag
+func (l *Logger) jsonTimestamp(ts time.Time) time.Time {
+	if l.flag&LUTC != 0 {
+		return ts.UTC()
+	}
+	return ts
+}
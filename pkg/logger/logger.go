@@ -110,6 +110,10 @@ type Logger struct {
 	stdLogTemplate *template.Template
 	authTemplate   *template.Template
 	reqTemplate    *template.Template
+	// The templates are not used for the log types with JSON output
+	stdJSON  bool
+	authJSON bool
+	reqJSON  bool
 }
 
 // New creates a new Standarderr Logger.
@@ -140,6 +144,18 @@ func (l *Logger) formatLogMessage(calldepth int, message string) []byte {
 	}
 
 	var logBuff = new(bytes.Buffer)
+	if l.stdJSON {
+		err := writeJSON(logBuff, stdLogMessageJSON{
+			Timestamp: l.jsonTimestamp(now),
+			File:      file,
+			Message:   message,
+		})
+		if err != nil {
+			panic(err)
+		}
+		return logBuff.Bytes()
+	}
+
 	err := l.stdLogTemplate.Execute(logBuff, stdLogMessageData{
 		Timestamp: FormatTimestamp(now),
 		File:      file,
@@ -199,6 +215,25 @@ func (l *Logger) PrintAuthf(username string, req *http.Request, status AuthStatu
 	defer l.mu.Unlock()
 
 	scope := middlewareapi.GetRequestScope(req)
+	if l.authJSON {
+		err := writeJSON(l.writer, authLogMessageJSON{
+			Client:        client,
+			Host:          requestutil.GetRequestHost(req),
+			Protocol:      req.Proto,
+			RequestID:     scope.RequestID,
+			RequestMethod: req.Method,
+			Timestamp:     l.jsonTimestamp(now),
+			UserAgent:     req.UserAgent(),
+			Username:      username,
+			Status:        string(status),
+			Message:       fmt.Sprintf(format, a...),
+		})
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	err := l.authTemplate.Execute(l.writer, authLogMessageData{
 		Client:        client,
 		Host:          requestutil.GetRequestHost(req),
@@ -255,6 +290,28 @@ func (l *Logger) PrintReq(username, upstream string, req *http.Request, url url.
 	defer l.mu.Unlock()
 
 	scope := middlewareapi.GetRequestScope(req)
+	if l.reqJSON {
+		err := writeJSON(l.writer, reqLogMessageJSON{
+			Client:          client,
+			Host:            requestutil.GetRequestHost(req),
+			Protocol:        req.Proto,
+			RequestID:       scope.RequestID,
+			RequestDuration: duration,
+			RequestMethod:   req.Method,
+			RequestURI:      url.RequestURI(),
+			ResponseSize:    size,
+			StatusCode:      status,
+			Timestamp:       l.jsonTimestamp(ts),
+			Upstream:        upstream,
+			UserAgent:       req.UserAgent(),
+			Username:        username,
+		})
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	err := l.reqTemplate.Execute(l.writer, reqLogMessageData{
 		Client:          client,
 		Host:            requestutil.GetRequestHost(req),
@@ -370,6 +427,15 @@ func (l *Logger) SetGetClientFunc(f GetClientFunc) {
 	l.getClientFunc = f
 }
 
+// GetClient returns the apparent "real client IP" of the request, as it is
+// logged.
+func (l *Logger) GetClient(req *http.Request) string {
+	l.mu.Lock()
+	getClientFunc := l.getClientFunc
+	l.mu.Unlock()
+	return getClientFunc(req)
+}
+
 // SetExcludePaths sets the paths to exclude from logging.
 func (l *Logger) SetExcludePaths(s []string) {
 	l.mu.Lock()
@@ -381,24 +447,36 @@ func (l *Logger) SetExcludePaths(s []string) {
 }
 
 // SetStandardTemplate sets the template for standard logging.
+// JSONLoggingFormat selects the JSON output.
 func (l *Logger) SetStandardTemplate(t string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.stdLogTemplate = template.Must(template.New("std-log").Parse(t))
+	l.stdJSON = t == JSONLoggingFormat
+	if !l.stdJSON {
+		l.stdLogTemplate = template.Must(template.New("std-log").Parse(t))
+	}
 }
 
 // SetAuthTemplate sets the template for auth logging.
+// JSONLoggingFormat selects the JSON output.
 func (l *Logger) SetAuthTemplate(t string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.authTemplate = template.Must(template.New("auth-log").Parse(t))
+	l.authJSON = t == JSONLoggingFormat
+	if !l.authJSON {
+		l.authTemplate = template.Must(template.New("auth-log").Parse(t))
+	}
 }
 
 // SetReqTemplate sets the template for request logging.
+// JSONLoggingFormat selects the JSON output.
 func (l *Logger) SetReqTemplate(t string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.reqTemplate = template.Must(template.New("req-log").Parse(t))
+	l.reqJSON = t == JSONLoggingFormat
+	if !l.reqJSON {
+		l.reqTemplate = template.Must(template.New("req-log").Parse(t))
+	}
 }
 
 // These functions utilize the standard logger.
@@ -462,6 +540,12 @@ func SetGetClientFunc(f GetClientFunc) {
 	std.SetGetClientFunc(f)
 }
 
+// GetClient returns the apparent IP address of the request, as it is logged
+// by the standard logger.
+func GetClient(req *http.Request) string {
+	return std.GetClient(req)
+}
+
 // SetExcludePaths sets the path to exclude from logging, eg: health checks
 func SetExcludePaths(s []string) {
 	std.SetExcludePaths(s)
@@ -0,0 +1,85 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	. "github.com/onsi/gomega"
+)
+
+func setupLogger(t *testing.T) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
+	logger.SetOutput(buf)
+	t.Cleanup(func() {
+		logger.SetOutput(os.Stdout)
+		logger.SetStandardTemplate(logger.DefaultStandardLoggingFormat)
+		logger.SetAuthTemplate(logger.DefaultAuthLoggingFormat)
+	})
+	return buf
+}
+
+func TestJSONStandardLogging(t *testing.T) {
+	g := NewWithT(t)
+	buf := setupLogger(t)
+	logger.SetStandardTemplate(logger.JSONLoggingFormat)
+
+	logger.Printf("message with %q\nand a new line", "quotes")
+
+	g.Expect(bytes.Count(buf.Bytes(), []byte("\n"))).To(Equal(1))
+	var message struct {
+		Timestamp time.Time `json:"timestamp"`
+		File      string    `json:"file"`
+		Message   string    `json:"message"`
+	}
+	g.Expect(json.Unmarshal(buf.Bytes(), &message)).To(Succeed())
+	g.Expect(message.Timestamp).To(BeTemporally("~", time.Now(), time.Minute))
+	g.Expect(message.File).To(MatchRegexp(`^logger_test\.go:\d+$`))
+	g.Expect(message.Message).To(Equal("message with \"quotes\"\nand a new line"))
+}
+
+func TestJSONAuthLogging(t *testing.T) {
+	g := NewWithT(t)
+	buf := setupLogger(t)
+	logger.SetAuthTemplate(logger.JSONLoggingFormat)
+
+	req := httptest.NewRequest("GET", "/oauth2/callback", nil)
+	req.RemoteAddr = "127.0.0.1"
+	req.Header.Set("User-Agent", "<script>\"agent\"\n")
+	req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{RequestID: "request-id"})
+
+	logger.PrintAuthf("", req, logger.AuthSuccess, "Authenticated via OAuth2: %s", "user")
+
+	g.Expect(buf.String()).ToNot(ContainSubstring("<script>"))
+	g.Expect(bytes.Count(buf.Bytes(), []byte("\n"))).To(Equal(1))
+	var message map[string]interface{}
+	g.Expect(json.Unmarshal(buf.Bytes(), &message)).To(Succeed())
+	g.Expect(message).To(HaveKey("timestamp"))
+	delete(message, "timestamp")
+	g.Expect(message).To(Equal(map[string]interface{}{
+		"client":         "127.0.0.1",
+		"host":           "example.com",
+		"protocol":       "HTTP/1.1",
+		"request_id":     "request-id",
+		"request_method": "GET",
+		"user_agent":     "<script>\"agent\"\n",
+		"username":       "-",
+		"status":         "AuthSuccess",
+		"message":        "Authenticated via OAuth2: user",
+	}))
+}
+
+func TestSwitchingBackToTemplates(t *testing.T) {
+	g := NewWithT(t)
+	buf := setupLogger(t)
+	logger.SetStandardTemplate(logger.JSONLoggingFormat)
+	logger.SetStandardTemplate("{{.Message}}")
+
+	logger.Print("plain")
+	g.Expect(buf.String()).To(Equal("plain\n"))
+}
@@ -9,10 +9,13 @@ import (
 	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/header"
+	sessionsjwt "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/jwt"
 )
 
-func NewRequestHeaderInjector(headers []options.Header) (alice.Constructor, error) {
-	headerInjector, err := newRequestHeaderInjector(headers)
+// NewRequestHeaderInjector creates a middleware injecting the headers in the
+// request to the upstream. The keys sign the JWTs of JWTSource values.
+func NewRequestHeaderInjector(headers []options.Header, keys *sessionsjwt.KeySet) (alice.Constructor, error) {
+	headerInjector, err := newRequestHeaderInjector(headers, keys)
 	if err != nil {
 		return nil, fmt.Errorf("error building request header injector: %v", err)
 	}
@@ -64,8 +67,8 @@ func stripHeaders(headers []options.Header, next http.Handler) http.Handler {
 	})
 }
 
-func newRequestHeaderInjector(headers []options.Header) (alice.Constructor, error) {
-	injector, err := header.NewInjector(headers)
+func newRequestHeaderInjector(headers []options.Header, keys *sessionsjwt.KeySet) (alice.Constructor, error) {
+	injector, err := header.NewInjector(headers, keys)
 	if err != nil {
 		return nil, fmt.Errorf("error building request injector: %v", err)
 	}
@@ -81,7 +84,7 @@ func injectRequestHeaders(injector header.Injector, next http.Handler) http.Hand
 
 		// If scope is nil, this will panic.
 		// A scope should always be injected before this handler is called.
-		injector.Inject(req.Header, scope.Session)
+		injector.Inject(req.Header, scope)
 		flattenHeaders(req.Header)
 		next.ServeHTTP(rw, req)
 	})
@@ -97,7 +100,8 @@ func NewResponseHeaderInjector(headers []options.Header) (alice.Constructor, err
 }
 
 func newResponseHeaderInjector(headers []options.Header) (alice.Constructor, error) {
-	injector, err := header.NewInjector(headers)
+	// JWTs are only minted for the upstreams
+	injector, err := header.NewInjector(headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error building response injector: %v", err)
 	}
@@ -113,7 +117,7 @@ func injectResponseHeaders(injector header.Injector, next http.Handler) http.Han
 
 		// If scope is nil, this will panic.
 		// A scope should always be injected before this handler is called.
-		injector.Inject(rw.Header(), scope.Session)
+		injector.Inject(rw.Header(), scope)
 		flattenHeaders(rw.Header())
 		next.ServeHTTP(rw, req)
 	})
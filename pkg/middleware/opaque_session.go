@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/justinas/alice"
+	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/providers"
+	k8serrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// opaqueTokenCacheSize is the maximum number of validation results in the
+// cache, so that clients sending random tokens cannot grow it without limit
+const opaqueTokenCacheSize = 10000
+
+// NewOpaqueSessionLoader creates a new middleware that loads sessions from
+// opaque (non-JWT) bearer tokens, which can only be validated by asking the
+// server that issued them.
+// The result of the validation, valid or rejected, is cached for the cacheTTL
+// so that the server is not called on every request made with a token.
+func NewOpaqueSessionLoader(sessionLoaders []middlewareapi.TokenToSessionFunc, cacheTTL time.Duration, bearerTokenLoginFallback bool) alice.Constructor {
+	loader := &opaqueSessionLoader{
+		jwtRegex:          regexp.MustCompile(jwtRegexFormat),
+		sessionLoaders:    sessionLoaders,
+		cacheTTL:          cacheTTL,
+		cache:             make(map[[sha256.Size]byte]opaqueTokenResult),
+		denyInvalidTokens: !bearerTokenLoginFallback,
+	}
+	return loader.loadSession
+}
+
+// opaqueTokenResult is the cached result of the validation of a token
+type opaqueTokenResult struct {
+	session   *sessionsapi.SessionState
+	err       error
+	expiresAt time.Time
+}
+
+// opaqueSessionLoader is responsible for loading sessions from opaque tokens
+// in Authorization headers.
+type opaqueSessionLoader struct {
+	jwtRegex          *regexp.Regexp
+	sessionLoaders    []middlewareapi.TokenToSessionFunc
+	denyInvalidTokens bool
+
+	cacheTTL  time.Duration
+	cacheLock sync.Mutex
+	// cache holds the validation results by the hash of the token, so that
+	// the tokens are not kept in memory
+	cache     map[[sha256.Size]byte]opaqueTokenResult
+	lastPrune time.Time
+}
+
+// loadSession attempts to load a session from an opaque bearer token stored in
+// an Authorization header within the request.
+// If no bearer token is found, or the token is a JWT, no session will be
+// loaded and the request will be passed to the next handler.
+// Or if the token is invalid and denyInvalidTokens, return 403 now.
+// If a session was loaded by a previous handler, it will not be replaced.
+func (o *opaqueSessionLoader) loadSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		scope := middlewareapi.GetRequestScope(req)
+		// If scope is nil, this will panic.
+		// A scope should always be injected before this handler is called.
+		if scope.Session != nil {
+			// The session was already loaded, pass to the next handler
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		token := o.findTokenFromHeader(req.Header.Get("Authorization"))
+		if token == "" {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		session, err := o.getSession(req, token)
+		if err != nil {
+			logger.Errorf("Error retrieving session from opaque token in Authorization header: %v", err)
+			if o.denyInvalidTokens {
+				http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+
+		// Add the session to the scope if it was found
+		scope.Session = session
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// findTokenFromHeader returns the bearer token of the Authorization header
+// when it is not a JWT, JWTs are left to the JWT session loader.
+func (o *opaqueSessionLoader) findTokenFromHeader(header string) string {
+	if header == "" {
+		return ""
+	}
+	tokenType, token, err := splitAuthHeader(header)
+	if err != nil || tokenType != "Bearer" || o.jwtRegex.MatchString(token) {
+		return ""
+	}
+	return token
+}
+
+// getSession validates the token with the session loaders, unless the result
+// of a previous validation is still cached.
+// Only the tokens rejected by every session loader are cached as invalid,
+// errors reaching a server are retried on the next request.
+func (o *opaqueSessionLoader) getSession(req *http.Request, token string) (*sessionsapi.SessionState, error) {
+	key := sha256.Sum256([]byte(token))
+	if result, ok := o.cached(key); ok {
+		return result.session.Clone(), result.err
+	}
+
+	// This leading error message only occurs if all session loaders fail
+	errs := []error{errors.New("unable to verify opaque bearer token")}
+	rejected := true
+	for _, loader := range o.sessionLoaders {
+		session, err := loader(req.Context(), token)
+		if err != nil {
+			errs = append(errs, err)
+			rejected = rejected && isRejection(err)
+			continue
+		}
+		o.store(key, opaqueTokenResult{session: session})
+		return session.Clone(), nil
+	}
+
+	err := k8serrors.NewAggregate(errs)
+	if rejected {
+		o.store(key, opaqueTokenResult{err: err})
+	}
+	return nil, err
+}
+
+// isRejection returns whether the error means the token is not valid for the
+// session loader, rather than it failing to validate it
+func isRejection(err error) bool {
+	return errors.Is(err, providers.ErrSessionRejected) || errors.Is(err, providers.ErrNotImplemented)
+}
+
+func (o *opaqueSessionLoader) cached(key [sha256.Size]byte) (opaqueTokenResult, bool) {
+	o.cacheLock.Lock()
+	defer o.cacheLock.Unlock()
+
+	result, ok := o.cache[key]
+	if !ok || time.Now().After(result.expiresAt) {
+		return opaqueTokenResult{}, false
+	}
+	return result, true
+}
+
+func (o *opaqueSessionLoader) store(key [sha256.Size]byte, result opaqueTokenResult) {
+	if o.cacheTTL <= 0 {
+		return
+	}
+
+	o.cacheLock.Lock()
+	defer o.cacheLock.Unlock()
+
+	now := time.Now()
+	// Drop the expired results once per TTL, or when the cache is full, so
+	// that the cache does not grow with every token ever seen
+	if now.Sub(o.lastPrune) > o.cacheTTL || len(o.cache) >= opaqueTokenCacheSize {
+		for k, r := range o.cache {
+			if now.After(r.expiresAt) {
+				delete(o.cache, k)
+			}
+		}
+		o.lastPrune = now
+	}
+	if _, ok := o.cache[key]; !ok && len(o.cache) >= opaqueTokenCacheSize {
+		o.evict()
+	}
+
+	result.expiresAt = now.Add(o.cacheTTL)
+	// Sessions are not cached past the expiry of their token
+	if result.session != nil && result.session.ExpiresOn != nil && result.session.ExpiresOn.Before(result.expiresAt) {
+		result.expiresAt = *result.session.ExpiresOn
+	}
+	o.cache[key] = result
+}
+
+// evict removes a result from the full cache: the rejected token expiring
+// first, so that random tokens cannot push the valid ones out of the cache,
+// or the valid token expiring first when every token is valid
+func (o *opaqueSessionLoader) evict() {
+	var (
+		evictKey    [sha256.Size]byte
+		evictResult *opaqueTokenResult
+	)
+	for k, r := range o.cache {
+		switch {
+		case evictResult == nil,
+			r.session == nil && evictResult.session != nil,
+			(r.session == nil) == (evictResult.session == nil) && r.expiresAt.Before(evictResult.expiresAt):
+			evictKey, evictResult = k, &r
+		}
+	}
+	delete(o.cache, evictKey)
+}
@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
@@ -120,4 +122,46 @@ var _ = Describe("Request logger suite", func() {
 			ExcludePaths:       []string{"/ping"},
 		}),
 	)
+
+	It("logs requests as JSON", func() {
+		buf := bytes.NewBuffer(nil)
+		logger.SetOutput(buf)
+		logger.SetReqTemplate(logger.JSONLoggingFormat)
+		defer logger.SetReqTemplate(logger.DefaultRequestLoggingFormat)
+		logger.SetExcludePaths(nil)
+
+		req, err := http.NewRequest("GET", "/foo/bar?q=%22%0A", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.RemoteAddr = "127.0.0.1"
+		req.Host = "test-server"
+		req.Header.Set("User-Agent", "agent\" \n{\"injected\":true}")
+		req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{
+			RequestID: "11111111-2222-4333-8444-555555555555",
+			Session:   &sessions.SessionState{User: "json.user"},
+		})
+
+		handler := NewRequestLogger()(testUpstreamHandler("standard"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		Expect(strings.Count(buf.String(), "\n")).To(Equal(1))
+		var message map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &message)).To(Succeed())
+		Expect(message).To(HaveKey("timestamp"))
+		Expect(message).To(HaveKeyWithValue("request_duration", BeNumerically(">=", 0)))
+		delete(message, "timestamp")
+		delete(message, "request_duration")
+		Expect(message).To(Equal(map[string]interface{}{
+			"client":         "127.0.0.1",
+			"host":           "test-server",
+			"protocol":       "HTTP/1.1",
+			"request_id":     "11111111-2222-4333-8444-555555555555",
+			"request_method": "GET",
+			"request_uri":    "/foo/bar?q=%22%0A",
+			"response_size":  float64(4),
+			"status_code":    float64(200),
+			"upstream":       "standard",
+			"user_agent":     "agent\" \n{\"injected\":true}",
+			"username":       "json.user",
+		}))
+	})
 })
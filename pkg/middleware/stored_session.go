@@ -9,8 +9,11 @@ import (
 
 	"github.com/justinas/alice"
 	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/providers"
 )
 
@@ -31,9 +34,13 @@ const (
 	sessionRefreshRetryPeriod = 10 * time.Millisecond
 )
 
+// errBindingMismatch is returned for the sessions that are used by another
+// client than the one they are bound to, when they are denied
+var errBindingMismatch = errors.New("session used by another client")
+
 // StoredSessionLoaderOptions contains all of the requirements to construct
 // a stored session loader.
-// All options must be provided.
+// All options must be provided, except the RevocationList.
 type StoredSessionLoaderOptions struct {
 	// Session storage backend
 	SessionStore sessionsapi.SessionStore
@@ -48,6 +55,30 @@ type StoredSessionLoaderOptions struct {
 	// If the sesssion is older than `RefreshPeriod` but the provider doesn't
 	// refresh it, we must re-validate using this validation.
 	ValidateSession func(context.Context, *sessionsapi.SessionState) bool
+
+	// Sessions revoked by a back-channel logout of the provider.
+	// Revoked sessions are removed from the session store when loaded.
+	RevocationList sessionsapi.RevocationList
+
+	// Maximum time since the user authenticated, however often the session
+	// is refreshed. Zero disables it.
+	MaxAge time.Duration
+
+	// Maximum time since the last activity of the session. Zero disables it.
+	IdleTimeout time.Duration
+
+	// How often the last activity of the session is saved when the
+	// IdleTimeout is enabled, to not write to the session store on every
+	// request.
+	ActivityUpdateInterval time.Duration
+
+	// Verifies the requests come from the client the session is bound to.
+	// Nil disables it.
+	ClientBinder *sessions.ClientBinder
+
+	// What happens to the requests of other clients: the session is ignored,
+	// ended, or the mismatch only logged.
+	BindingMismatchAction string
 }
 
 // NewStoredSessionLoader creates a new storedSessionLoader which loads
@@ -60,6 +91,12 @@ func NewStoredSessionLoader(opts *StoredSessionLoaderOptions) alice.Constructor
 		refreshPeriod:    opts.RefreshPeriod,
 		sessionRefresher: opts.RefreshSession,
 		sessionValidator: opts.ValidateSession,
+		revocationList:   opts.RevocationList,
+		maxAge:           opts.MaxAge,
+		idleTimeout:      opts.IdleTimeout,
+		activityInterval: opts.ActivityUpdateInterval,
+		clientBinder:     opts.ClientBinder,
+		bindingAction:    opts.BindingMismatchAction,
 	}
 	return ss.loadSession
 }
@@ -71,6 +108,12 @@ type storedSessionLoader struct {
 	refreshPeriod    time.Duration
 	sessionRefresher func(context.Context, *sessionsapi.SessionState) (bool, error)
 	sessionValidator func(context.Context, *sessionsapi.SessionState) bool
+	revocationList   sessionsapi.RevocationList
+	maxAge           time.Duration
+	idleTimeout      time.Duration
+	activityInterval time.Duration
+	clientBinder     *sessions.ClientBinder
+	bindingAction    string
 }
 
 // loadSession attempts to load a session as identified by the request cookies.
@@ -88,7 +131,10 @@ func (s *storedSessionLoader) loadSession(next http.Handler) http.Handler {
 		}
 
 		session, err := s.getValidatedSession(rw, req)
-		if err != nil && !errors.Is(err, http.ErrNoCookie) {
+		if errors.Is(err, errBindingMismatch) {
+			// The session is kept for the client it is bound to
+			logger.Errorf("Ignoring cookied session: %v", err)
+		} else if err != nil && !errors.Is(err, http.ErrNoCookie) {
 			// In the case when there was an error loading the session,
 			// we should clear the session
 			logger.Errorf("Error loading cookied session: %v, removing session", err)
@@ -113,14 +159,118 @@ func (s *storedSessionLoader) getValidatedSession(rw http.ResponseWriter, req *h
 		return nil, err
 	}
 
+	if s.revocationList != nil {
+		revoked, err := s.revocationList.IsRevoked(req.Context(), session)
+		if err != nil {
+			return nil, fmt.Errorf("error checking revocation of session (%s): %v", session, err)
+		}
+		if revoked {
+			return nil, fmt.Errorf("session (%s) has been revoked by the provider", session)
+		}
+	}
+
+	if err := s.checkBinding(req, session); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkTimeouts(session); err != nil {
+		return nil, fmt.Errorf("session (%s) has timed out: %v", session, err)
+	}
+
 	err = s.refreshSessionIfNeeded(rw, req, session)
 	if err != nil {
 		return nil, fmt.Errorf("error refreshing access token for session (%s): %v", session, err)
 	}
 
+	if err := s.updateActivityIfNeeded(rw, req, session); err != nil {
+		// The session is still valid, the activity is saved by a later request
+		logger.Errorf("Unable to save the activity of session (%s): %v", session, err)
+	}
+
 	return session, nil
 }
 
+// checkBinding verifies the request comes from the client the session is
+// bound to. The mismatches only logged are not returned, the sessions denied
+// are returned as errBindingMismatch so that they are not cleared.
+func (s *storedSessionLoader) checkBinding(req *http.Request, session *sessionsapi.SessionState) error {
+	if s.clientBinder == nil {
+		return nil
+	}
+	err := s.clientBinder.Verify(req, session.Binding)
+	if err == nil {
+		return nil
+	}
+
+	switch s.bindingAction {
+	case options.LogSessionBindingAction:
+		logger.Printf("Session (%s) is used by another client: %v", session, err)
+		return nil
+	case options.ReauthenticateSessionBindingAction:
+		return fmt.Errorf("session (%s) is used by another client: %v", session, err)
+	default:
+		return fmt.Errorf("%w (%s): %v", errBindingMismatch, session, err)
+	}
+}
+
+// checkTimeouts ends the sessions older than the maximum age, since the
+// authentication of the user, or unused for longer than the idle timeout.
+func (s *storedSessionLoader) checkTimeouts(session *sessionsapi.SessionState) error {
+	if s.maxAge > 0 && session.AuthenticationAge() > s.maxAge {
+		return fmt.Errorf("authenticated %s ago, more than the maximum age of %s", session.AuthenticationAge().Truncate(time.Second), s.maxAge)
+	}
+	if s.idleTimeout > 0 && session.IdleTime() > s.idleTimeout {
+		return fmt.Errorf("idle for %s, more than the idle timeout of %s", session.IdleTime().Truncate(time.Second), s.idleTimeout)
+	}
+	return nil
+}
+
+// needsActivityUpdate determines whether the last activity of the session
+// should be saved. It is only saved once per activity interval, so that the
+// session store is not written on every request.
+func (s *storedSessionLoader) needsActivityUpdate(session *sessionsapi.SessionState) bool {
+	return s.idleTimeout > 0 && (session.LastActivity == nil || session.IdleTime() >= s.activityInterval)
+}
+
+// updateActivityIfNeeded saves the last activity of the session.
+// The session is reloaded under lock so that a concurrent refresh is not
+// overwritten. When another request holds the lock, the activity is left to
+// a later request rather than waiting for it.
+func (s *storedSessionLoader) updateActivityIfNeeded(rw http.ResponseWriter, req *http.Request, session *sessionsapi.SessionState) error {
+	if !s.needsActivityUpdate(session) {
+		return nil
+	}
+
+	err := session.ObtainLock(req.Context(), sessionRefreshLockDuration)
+	if errors.Is(err, sessionsapi.ErrLockNotObtained) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error occurred while trying to obtain lock: %v", err)
+	}
+	defer func() {
+		if err := session.ReleaseLock(req.Context()); err != nil {
+			logger.Errorf("unable to release lock: %v", err)
+		}
+	}()
+
+	freshSession, err := s.store.Load(req)
+	if err != nil {
+		return fmt.Errorf("could not load session: %v", err)
+	}
+	if freshSession == nil {
+		return errors.New("session no longer exists, it may have been removed by another request")
+	}
+	if !s.needsActivityUpdate(freshSession) {
+		// Another request saved the activity while we were loading it
+		return nil
+	}
+
+	freshSession.ActivityNow()
+	session.LastActivity = freshSession.LastActivity
+	return s.store.Save(rw, req, freshSession)
+}
+
 // refreshSessionIfNeeded will attempt to refresh a session if the session
 // is older than the refresh period.
 // Success or fail, we will then validate the session.
@@ -188,6 +338,11 @@ func (s *storedSessionLoader) refreshSessionIfNeeded(rw http.ResponseWriter, req
 	// We are holding the lock and the session needs a refresh
 	logger.Printf("Refreshing session - User: %s; SessionAge: %s", session.User, session.Age())
 	if err := s.refreshSession(rw, req, session); err != nil {
+		// The provider explicitly rejected the session, there is no point
+		// in validating it.
+		if errors.Is(err, providers.ErrSessionRejected) {
+			return err
+		}
 		// If a preemptive refresh fails, we still keep the session
 		// if validateSession succeeds.
 		logger.Errorf("Unable to refresh session: %v", err)
@@ -205,7 +360,12 @@ func needsRefresh(refreshPeriod time.Duration, session *sessionsapi.SessionState
 // refreshSession attempts to refresh the session with the provider
 // and will save the session if it was updated.
 func (s *storedSessionLoader) refreshSession(rw http.ResponseWriter, req *http.Request, session *sessionsapi.SessionState) error {
-	refreshed, err := s.sessionRefresher(req.Context(), session)
+	ctx, span := tracing.StartSpan(req.Context(), "provider.refresh_session")
+	refreshed, err := s.sessionRefresher(ctx, session)
+	tracing.EndSpan(span, err)
+	if errors.Is(err, providers.ErrSessionRejected) {
+		return err
+	}
 	if err != nil && !errors.Is(err, providers.ErrNotImplemented) {
 		return fmt.Errorf("error refreshing tokens: %v", err)
 	}
@@ -229,6 +389,12 @@ func (s *storedSessionLoader) refreshSession(rw http.ResponseWriter, req *http.R
 	// (In case underlying provider implementations forget)
 	session.CreatedAtNow()
 
+	// Save the activity along with the refreshed session, instead of saving
+	// the session again afterwards
+	if s.idleTimeout > 0 {
+		session.ActivityNow()
+	}
+
 	// Because the session was refreshed, make sure to save it
 	err = s.store.Save(rw, req, session)
 	if err != nil {
@@ -246,7 +412,10 @@ func (s *storedSessionLoader) validateSession(ctx context.Context, session *sess
 		return errors.New("session is expired")
 	}
 
-	if !s.sessionValidator(ctx, session) {
+	ctx, span := tracing.StartSpan(ctx, "provider.validate_session")
+	valid := s.sessionValidator(ctx, session)
+	span.End()
+	if !valid {
 		return errors.New("session is invalid")
 	}
 
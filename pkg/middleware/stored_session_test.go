@@ -10,7 +10,9 @@ import (
 	"time"
 
 	middlewareapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/middleware"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/providers"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -86,6 +88,7 @@ var _ = Describe("Stored Session Suite", func() {
 		refreshed      = "Refreshed"
 		noRefresh      = "NoRefresh"
 		notImplemented = "NotImplemented"
+		rejected       = "Rejected"
 	)
 
 	var ctx = context.Background()
@@ -340,6 +343,265 @@ var _ = Describe("Stored Session Suite", func() {
 			}),
 		)
 
+		Context("with a revocation list", func() {
+			var cleared bool
+
+			revocationList := &fakeRevocationList{
+				IsRevokedFunc: func(_ context.Context, ss *sessionsapi.SessionState) (bool, error) {
+					switch ss.User {
+					case "revoked":
+						return true, nil
+					case "error":
+						return false, errors.New("revocation list unavailable")
+					default:
+						return false, nil
+					}
+				},
+			}
+
+			loadSession := func(user string) *sessionsapi.SessionState {
+				cleared = false
+				store := &fakeSessionStore{
+					LoadFunc: func(_ *http.Request) (*sessionsapi.SessionState, error) {
+						return &sessionsapi.SessionState{
+							User:      user,
+							CreatedAt: &createdPast,
+							ExpiresOn: &createdFuture,
+							Clock:     clock,
+						}, nil
+					},
+					ClearFunc: func(_ http.ResponseWriter, _ *http.Request) error {
+						cleared = true
+						return nil
+					},
+				}
+
+				req := httptest.NewRequest("", "/", nil)
+				req.Header.Set("Cookie", "_oauth2_proxy=Session")
+				req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{})
+
+				var gotSession *sessionsapi.SessionState
+				handler := NewStoredSessionLoader(&StoredSessionLoaderOptions{
+					SessionStore:    store,
+					RefreshPeriod:   10 * time.Minute,
+					RefreshSession:  defaultRefreshFunc,
+					ValidateSession: defaultValidateFunc,
+					RevocationList:  revocationList,
+				})(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					gotSession = middlewareapi.GetRequestScope(r).Session
+				}))
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+				return gotSession
+			}
+
+			It("keeps sessions that have not been revoked", func() {
+				session := loadSession("active")
+				Expect(session).ToNot(BeNil())
+				Expect(session.User).To(Equal("active"))
+				Expect(cleared).To(BeFalse())
+			})
+
+			It("removes revoked sessions", func() {
+				Expect(loadSession("revoked")).To(BeNil())
+				Expect(cleared).To(BeTrue())
+			})
+
+			It("removes sessions when the revocation list fails", func() {
+				Expect(loadSession("error")).To(BeNil())
+				Expect(cleared).To(BeTrue())
+			})
+		})
+
+		Context("with session timeouts", func() {
+			var cleared bool
+			var saved []*sessionsapi.SessionState
+
+			loadSession := func(stored *sessionsapi.SessionState) *sessionsapi.SessionState {
+				cleared = false
+				saved = nil
+				store := &fakeSessionStore{
+					LoadFunc: func(_ *http.Request) (*sessionsapi.SessionState, error) {
+						// Each load returns a copy, as a session store does
+						session := *stored
+						return &session, nil
+					},
+					SaveFunc: func(_ http.ResponseWriter, _ *http.Request, ss *sessionsapi.SessionState) error {
+						saved = append(saved, ss)
+						return nil
+					},
+					ClearFunc: func(_ http.ResponseWriter, _ *http.Request) error {
+						cleared = true
+						return nil
+					},
+				}
+
+				req := httptest.NewRequest("", "/", nil)
+				req.Header.Set("Cookie", "_oauth2_proxy=Session")
+				req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{})
+
+				var gotSession *sessionsapi.SessionState
+				handler := NewStoredSessionLoader(&StoredSessionLoaderOptions{
+					SessionStore:           store,
+					RefreshPeriod:          10 * time.Minute,
+					RefreshSession:         defaultRefreshFunc,
+					ValidateSession:        defaultValidateFunc,
+					MaxAge:                 time.Hour,
+					IdleTimeout:            15 * time.Minute,
+					ActivityUpdateInterval: time.Minute,
+				})(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					gotSession = middlewareapi.GetRequestScope(r).Session
+				}))
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+				return gotSession
+			}
+
+			timePtr := func(t time.Time) *time.Time { return &t }
+
+			It("keeps sessions within the limits without saving a recent activity", func() {
+				session := loadSession(&sessionsapi.SessionState{
+					AuthenticatedAt: timePtr(now.Add(-30 * time.Minute)),
+					CreatedAt:       &createdPast,
+					ExpiresOn:       &createdFuture,
+					LastActivity:    timePtr(now.Add(-30 * time.Second)),
+					Clock:           clock,
+				})
+				Expect(session).ToNot(BeNil())
+				Expect(cleared).To(BeFalse())
+				Expect(saved).To(BeEmpty())
+			})
+
+			It("saves the activity once the update interval has passed", func() {
+				session := loadSession(&sessionsapi.SessionState{
+					AuthenticatedAt: timePtr(now.Add(-30 * time.Minute)),
+					CreatedAt:       &createdPast,
+					ExpiresOn:       &createdFuture,
+					LastActivity:    timePtr(now.Add(-2 * time.Minute)),
+					Clock:           clock,
+				})
+				Expect(session).ToNot(BeNil())
+				Expect(*session.LastActivity).To(Equal(now))
+				Expect(saved).To(HaveLen(1))
+				Expect(*saved[0].LastActivity).To(Equal(now))
+			})
+
+			It("saves the activity along with a refresh", func() {
+				session := loadSession(&sessionsapi.SessionState{
+					AuthenticatedAt: timePtr(now.Add(-30 * time.Minute)),
+					CreatedAt:       timePtr(now.Add(-11 * time.Minute)),
+					ExpiresOn:       &createdFuture,
+					LastActivity:    timePtr(now.Add(-2 * time.Minute)),
+					RefreshToken:    refresh,
+					Clock:           clock,
+				})
+				Expect(session).ToNot(BeNil())
+				Expect(session.RefreshToken).To(Equal(refreshed))
+				Expect(saved).To(HaveLen(1))
+				Expect(*saved[0].LastActivity).To(Equal(now))
+			})
+
+			It("removes sessions older than the maximum age, even when refreshed", func() {
+				Expect(loadSession(&sessionsapi.SessionState{
+					AuthenticatedAt: timePtr(now.Add(-2 * time.Hour)),
+					CreatedAt:       &createdPast,
+					ExpiresOn:       &createdFuture,
+					LastActivity:    timePtr(now.Add(-30 * time.Second)),
+					Clock:           clock,
+				})).To(BeNil())
+				Expect(cleared).To(BeTrue())
+			})
+
+			It("removes idle sessions", func() {
+				Expect(loadSession(&sessionsapi.SessionState{
+					AuthenticatedAt: timePtr(now.Add(-30 * time.Minute)),
+					CreatedAt:       &createdPast,
+					ExpiresOn:       &createdFuture,
+					LastActivity:    timePtr(now.Add(-20 * time.Minute)),
+					Clock:           clock,
+				})).To(BeNil())
+				Expect(cleared).To(BeTrue())
+			})
+		})
+
+		Context("with client binding", func() {
+			var cleared bool
+
+			binder := sessions.NewClientBinder(&options.SessionOptions{
+				Bind:           []string{options.IPSessionBinding, options.UserAgentSessionBinding},
+				BindIPv4Prefix: 24,
+				BindIPv6Prefix: 64,
+			}, nil)
+
+			newRequest := func(remoteAddr string) *http.Request {
+				req := httptest.NewRequest("", "/", nil)
+				req.RemoteAddr = remoteAddr
+				req.Header.Set("User-Agent", "Mozilla/5.0")
+				req.Header.Set("Cookie", "_oauth2_proxy=Session")
+				return middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{})
+			}
+
+			loadSession := func(action string, req *http.Request, binding *sessionsapi.ClientBinding) *sessionsapi.SessionState {
+				cleared = false
+				store := &fakeSessionStore{
+					LoadFunc: func(_ *http.Request) (*sessionsapi.SessionState, error) {
+						return &sessionsapi.SessionState{
+							CreatedAt: &createdPast,
+							ExpiresOn: &createdFuture,
+							Binding:   binding,
+						}, nil
+					},
+					ClearFunc: func(_ http.ResponseWriter, _ *http.Request) error {
+						cleared = true
+						return nil
+					},
+				}
+
+				var gotSession *sessionsapi.SessionState
+				handler := NewStoredSessionLoader(&StoredSessionLoaderOptions{
+					SessionStore:          store,
+					RefreshSession:        defaultRefreshFunc,
+					ValidateSession:       defaultValidateFunc,
+					ClientBinder:          binder,
+					BindingMismatchAction: action,
+				})(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					gotSession = middlewareapi.GetRequestScope(r).Session
+				}))
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+				return gotSession
+			}
+
+			var binding *sessionsapi.ClientBinding
+			BeforeEach(func() {
+				var err error
+				binding, err = binder.Bind(newRequest("10.0.0.1:4180"))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("loads the session from the subnet it is bound to", func() {
+				Expect(loadSession(options.DenySessionBindingAction, newRequest("10.0.0.200:4180"), binding)).ToNot(BeNil())
+				Expect(cleared).To(BeFalse())
+			})
+
+			It("ignores the session from another client without clearing it", func() {
+				Expect(loadSession(options.DenySessionBindingAction, newRequest("10.0.1.1:4180"), binding)).To(BeNil())
+				Expect(cleared).To(BeFalse())
+			})
+
+			It("clears the session from another client when reauthenticating", func() {
+				Expect(loadSession(options.ReauthenticateSessionBindingAction, newRequest("10.0.1.1:4180"), binding)).To(BeNil())
+				Expect(cleared).To(BeTrue())
+			})
+
+			It("loads the session from another client when only logging", func() {
+				Expect(loadSession(options.LogSessionBindingAction, newRequest("10.0.1.1:4180"), binding)).ToNot(BeNil())
+				Expect(cleared).To(BeFalse())
+			})
+
+			It("ignores the sessions saved before they were bound", func() {
+				Expect(loadSession(options.DenySessionBindingAction, newRequest("10.0.0.1:4180"), nil)).To(BeNil())
+				Expect(cleared).To(BeFalse())
+			})
+		})
+
 		type storedSessionLoaderConcurrentTableInput struct {
 			existingSession *sessionsapi.SessionState
 			refreshPeriod   time.Duration
@@ -490,6 +752,8 @@ var _ = Describe("Stored Session Suite", func() {
 							return false, nil
 						case notImplemented:
 							return false, providers.ErrNotImplemented
+						case rejected:
+							return false, providers.ErrSessionRejected
 						default:
 							return false, errors.New("error refreshing session")
 						}
@@ -633,6 +897,19 @@ var _ = Describe("Stored Session Suite", func() {
 				expectValidated:      true,
 				expectedLockObtained: true,
 			}),
+			Entry("when the provider rejects the session", refreshSessionIfNeededTableInput{
+				refreshPeriod: 1 * time.Minute,
+				session: &sessionsapi.SessionState{
+					RefreshToken: rejected,
+					CreatedAt:    &createdPast,
+					ExpiresOn:    &createdFuture,
+					Lock:         &testLock{},
+				},
+				expectedErr:          providers.ErrSessionRejected,
+				expectRefreshed:      true,
+				expectValidated:      false,
+				expectedLockObtained: true,
+			}),
 		)
 	})
 
@@ -801,3 +1078,15 @@ func (f *fakeSessionStore) Clear(rw http.ResponseWriter, req *http.Request) erro
 func (f *fakeSessionStore) VerifyConnection(_ context.Context) error {
 	return nil
 }
+
+type fakeRevocationList struct {
+	IsRevokedFunc func(context.Context, *sessionsapi.SessionState) (bool, error)
+}
+
+func (f *fakeRevocationList) Revoke(_ context.Context, _ string, _ string, _ time.Duration) error {
+	return nil
+}
+
+func (f *fakeRevocationList) IsRevoked(ctx context.Context, s *sessionsapi.SessionState) (bool, error) {
+	return f.IsRevokedFunc(ctx, s)
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"github.com/justinas/alice"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
+)
+
+// NewTracing creates a new tracing middleware that starts a server span for
+// each request. Provider calls, session store operations and the upstream hop
+// made while serving the request are traced as its children.
+// The span is a no-op unless tracing has been enabled.
+func NewTracing() alice.Constructor {
+	return tracing.NewHandler
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests"
 	k8serrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
@@ -132,7 +133,7 @@ func getVerifierBuilder(ctx context.Context, opts ProviderVerifierOptions) (veri
 		var err error
 
 		if opts.JWKsURL != "" {
-			keySet = oidc.NewRemoteKeySet(ctx, opts.JWKsURL)
+			keySet = oidc.NewRemoteKeySet(keySetContext(ctx), opts.JWKsURL)
 		} else {
 			keySet, err = newKeySetFromStatic(opts.PublicKeyFiles)
 			if err != nil {
@@ -154,11 +155,17 @@ func getVerifierBuilder(ctx context.Context, opts ProviderVerifierOptions) (veri
 
 	return newVerifierBuilder(
 		opts.IssuerURL,
-		oidc.NewRemoteKeySet(ctx, provider.Endpoints().JWKsURL),
+		oidc.NewRemoteKeySet(keySetContext(ctx), provider.Endpoints().JWKsURL),
 		provider.SupportedSigningAlgs(),
 	), provider, nil
 }
 
+// keySetContext makes remote key sets fetch the JWKS with the default HTTP
+// client, so that the requests are traced like other provider calls
+func keySetContext(ctx context.Context) context.Context {
+	return oidc.ClientContext(ctx, requests.DefaultHTTPClient)
+}
+
 // GetPublicKeyFromBytes parses a PEM-encoded public key from a byte array
 // and returns a crypto.PublicKey object.
 func getPublicKeyFromBytes(bytes []byte) (crypto.PublicKey, error) {
@@ -6,6 +6,7 @@ import (
 	"reflect"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 )
 
 // idTokenVerifier allows an ID Token to be verified against the issue and provided keys.
@@ -43,7 +44,10 @@ func NewVerifier(iv *oidc.IDTokenVerifier, vo IDTokenVerificationOptions) IDToke
 
 // Verify verifies incoming ID Token
 func (v *idTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*oidc.IDToken, error) {
+	// The signature verification may fetch the JWKS of the issuer
+	ctx, span := tracing.StartSpan(ctx, "oidc.verify_token")
 	token, err := v.verifier.Verify(ctx, rawIDToken)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify token: %v", err)
 	}
@@ -3,6 +3,7 @@ package requests
 import (
 	"net/http"
 
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/version"
 )
 
@@ -18,7 +19,7 @@ func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error
 }
 
 var DefaultHTTPClient = &http.Client{Transport: &userAgentTransport{
-	next:      DefaultTransport,
+	next:      tracing.NewTransport(DefaultTransport),
 	userAgent: "oauth2-proxy/" + version.VERSION,
 }}
 
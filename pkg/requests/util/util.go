@@ -9,9 +9,10 @@ import (
 )
 
 const (
-	XForwardedProto = "X-Forwarded-Proto"
-	XForwardedHost  = "X-Forwarded-Host"
-	XForwardedURI   = "X-Forwarded-Uri"
+	XForwardedProto  = "X-Forwarded-Proto"
+	XForwardedHost   = "X-Forwarded-Host"
+	XForwardedURI    = "X-Forwarded-Uri"
+	XForwardedMethod = "X-Forwarded-Method"
 )
 
 // GetRequestProto returns the request scheme or X-Forwarded-Proto if present
@@ -34,6 +35,16 @@ func GetRequestHost(req *http.Request) string {
 	return host
 }
 
+// GetRequestMethod returns the request method or X-Forwarded-Method if
+// present and the request is proxied.
+func GetRequestMethod(req *http.Request) string {
+	method := req.Header.Get(XForwardedMethod)
+	if !IsProxied(req) || method == "" {
+		method = req.Method
+	}
+	return method
+}
+
 // GetRequestURI return the request URI or X-Forwarded-Uri if present and the
 // request is proxied.
 func GetRequestURI(req *http.Request) string {
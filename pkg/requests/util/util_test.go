@@ -96,6 +96,36 @@ var _ = Describe("Util Suite", func() {
 		})
 	})
 
+	Context("GetRequestMethod", func() {
+		Context("IsProxied is false", func() {
+			BeforeEach(func() {
+				req = middleware.AddRequestScope(req, &middleware.RequestScope{})
+			})
+
+			It("ignores X-Forwarded-Method and returns the method", func() {
+				req.Header.Add("X-Forwarded-Method", "POST")
+				Expect(util.GetRequestMethod(req)).To(Equal("GET"))
+			})
+		})
+
+		Context("IsProxied is true", func() {
+			BeforeEach(func() {
+				req = middleware.AddRequestScope(req, &middleware.RequestScope{
+					ReverseProxy: true,
+				})
+			})
+
+			It("returns the method if X-Forwarded-Method is not present", func() {
+				Expect(util.GetRequestMethod(req)).To(Equal("GET"))
+			})
+
+			It("returns the X-Forwarded-Method when present", func() {
+				req.Header.Add("X-Forwarded-Method", "POST")
+				Expect(util.GetRequestMethod(req)).To(Equal("POST"))
+			})
+		})
+	})
+
 	Context("GetRequestURI", func() {
 		Context("IsProxied is false", func() {
 			BeforeEach(func() {
@@ -0,0 +1,123 @@
+package sessions
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	ipapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/ip"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/ip"
+)
+
+// ClientBinder captures the attributes of the client a session is bound to
+// at login, and verifies the later requests of the session come from the
+// same client
+type ClientBinder struct {
+	ip          bool
+	userAgent   bool
+	certificate bool
+	ipv4Mask    net.IPMask
+	ipv6Mask    net.IPMask
+	ipParser    ipapi.RealClientIPParser
+}
+
+// NewClientBinder creates a ClientBinder for the attributes of the session
+// options. It returns nil when the sessions are not bound to their client.
+func NewClientBinder(opts *options.SessionOptions, ipParser ipapi.RealClientIPParser) *ClientBinder {
+	if len(opts.Bind) == 0 {
+		return nil
+	}
+
+	b := &ClientBinder{
+		ipv4Mask: net.CIDRMask(opts.BindIPv4Prefix, 8*net.IPv4len),
+		ipv6Mask: net.CIDRMask(opts.BindIPv6Prefix, 8*net.IPv6len),
+		ipParser: ipParser,
+	}
+	for _, attribute := range opts.Bind {
+		switch attribute {
+		case options.IPSessionBinding:
+			b.ip = true
+		case options.UserAgentSessionBinding:
+			b.userAgent = true
+		case options.ClientCertificateSessionBinding:
+			b.certificate = true
+		}
+	}
+	return b
+}
+
+// RequiresClientCertificate returns whether the TLS clients must be asked
+// for their certificate
+func (b *ClientBinder) RequiresClientCertificate() bool {
+	return b != nil && b.certificate
+}
+
+// Bind captures the attributes of the client of the request
+func (b *ClientBinder) Bind(req *http.Request) (*sessions.ClientBinding, error) {
+	binding := &sessions.ClientBinding{}
+	if b.ip {
+		clientIP, err := ip.GetClientIP(b.ipParser, req)
+		if err != nil {
+			return nil, fmt.Errorf("error getting the client ip: %v", err)
+		}
+		if clientIP == nil {
+			return nil, errors.New("unable to get the client ip")
+		}
+		binding.IP = b.subnet(clientIP).String()
+	}
+	if b.userAgent {
+		hash := sha256.Sum256([]byte(req.UserAgent()))
+		binding.UserAgent = base64.RawURLEncoding.EncodeToString(hash[:])
+	}
+	if b.certificate {
+		if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+			return nil, errors.New("the client did not present a certificate")
+		}
+		thumbprint := sha256.Sum256(req.TLS.PeerCertificates[0].Raw)
+		binding.Certificate = hex.EncodeToString(thumbprint[:])
+	}
+	return binding, nil
+}
+
+// Verify returns an error describing the attributes of the client of the
+// request that differ from the binding of the session. Sessions saved
+// before they were bound to their client have no binding and always differ.
+func (b *ClientBinder) Verify(req *http.Request, binding *sessions.ClientBinding) error {
+	if binding == nil {
+		return errors.New("the session is not bound to a client")
+	}
+	current, err := b.Bind(req)
+	if err != nil {
+		return err
+	}
+
+	mismatches := []string{}
+	if current.IP != binding.IP {
+		mismatches = append(mismatches, fmt.Sprintf("ip %s is not in %s", current.IP, binding.IP))
+	}
+	if current.UserAgent != binding.UserAgent {
+		mismatches = append(mismatches, "the user agent differs")
+	}
+	if current.Certificate != binding.Certificate {
+		mismatches = append(mismatches, "the client certificate differs")
+	}
+	if len(mismatches) > 0 {
+		return errors.New(strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// subnet returns the subnet of the configured prefix the IP belongs to
+func (b *ClientBinder) subnet(clientIP net.IP) *net.IPNet {
+	if ipv4 := clientIP.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4.Mask(b.ipv4Mask), Mask: b.ipv4Mask}
+	}
+	return &net.IPNet{IP: clientIP.Mask(b.ipv6Mask), Mask: b.ipv6Mask}
+}
@@ -0,0 +1,83 @@
+package sessions_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientBinder", func() {
+	newBinder := func(bind ...string) *sessions.ClientBinder {
+		return sessions.NewClientBinder(&options.SessionOptions{
+			Bind:           bind,
+			BindIPv4Prefix: 24,
+			BindIPv6Prefix: 64,
+		}, nil)
+	}
+
+	newRequest := func(remoteAddr string, userAgent string, cert []byte) *http.Request {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("User-Agent", userAgent)
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: cert}}}
+		}
+		return req
+	}
+
+	It("is disabled without attributes to bind to", func() {
+		binder := newBinder()
+		Expect(binder).To(BeNil())
+		Expect(binder.RequiresClientCertificate()).To(BeFalse())
+	})
+
+	It("binds the sessions to the subnet of the client", func() {
+		binder := newBinder(options.IPSessionBinding)
+
+		binding, err := binder.Bind(newRequest("192.168.1.10:4180", "", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(binding.IP).To(Equal("192.168.1.0/24"))
+
+		Expect(binder.Verify(newRequest("192.168.1.99:4180", "", nil), binding)).To(Succeed())
+		Expect(binder.Verify(newRequest("192.168.2.10:4180", "", nil), binding)).
+			To(MatchError("ip 192.168.2.0/24 is not in 192.168.1.0/24"))
+	})
+
+	It("binds the sessions to the IPv6 subnet of the client", func() {
+		binder := newBinder(options.IPSessionBinding)
+
+		binding, err := binder.Bind(newRequest("[2001:db8:1:2::10]:4180", "", nil))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(binding.IP).To(Equal("2001:db8:1:2::/64"))
+		Expect(binder.Verify(newRequest("[2001:db8:1:2::99]:4180", "", nil), binding)).To(Succeed())
+	})
+
+	It("binds the sessions to the user agent and client certificate", func() {
+		binder := newBinder(options.UserAgentSessionBinding, options.ClientCertificateSessionBinding)
+		Expect(binder.RequiresClientCertificate()).To(BeTrue())
+
+		binding, err := binder.Bind(newRequest("192.168.1.10:4180", "Mozilla/5.0", []byte("certificate")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(binding.IP).To(BeEmpty())
+		Expect(binding.UserAgent).ToNot(BeEmpty())
+		Expect(binding.Certificate).To(HaveLen(64))
+
+		Expect(binder.Verify(newRequest("10.0.0.1:4180", "Mozilla/5.0", []byte("certificate")), binding)).To(Succeed())
+		Expect(binder.Verify(newRequest("10.0.0.1:4180", "curl/8.0", []byte("other")), binding)).
+			To(MatchError("the user agent differs, the client certificate differs"))
+		Expect(binder.Verify(newRequest("10.0.0.1:4180", "Mozilla/5.0", nil), binding)).
+			To(MatchError("the client did not present a certificate"))
+	})
+
+	It("does not verify the sessions without a binding", func() {
+		binder := newBinder(options.UserAgentSessionBinding)
+		Expect(binder.Verify(newRequest("10.0.0.1:4180", "Mozilla/5.0", nil), nil)).
+			To(MatchError("the session is not bound to a client"))
+	})
+})
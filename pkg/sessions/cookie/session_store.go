@@ -30,7 +30,10 @@ var _ sessions.SessionStore = &SessionStore{}
 type SessionStore struct {
 	Cookie       *options.Cookie
 	CookieCipher encryption.Cipher
-	Minimal      bool
+	// PreviousCookieCiphers decrypt the cookies encrypted with the previous
+	// cookie secrets, in the same order
+	PreviousCookieCiphers []encryption.Cipher
+	Minimal               bool
 }
 
 // Save takes a sessions.SessionState and stores the information from it
@@ -55,17 +58,22 @@ func (s *SessionStore) Load(req *http.Request) (*sessions.SessionState, error) {
 		return nil, err
 	}
 
-	secret, err := s.Cookie.GetSecret()
+	secrets, err := s.Cookie.GetSecrets()
 	if err != nil {
 		return nil, fmt.Errorf("error getting cookie secret: %v", err)
 	}
 
-	val, _, ok := encryption.Validate(c, secret, s.Cookie.Expire)
+	val, _, secret, ok := encryption.ValidateAny(c, secrets, s.Cookie.Expire)
 	if !ok {
 		return nil, errors.New("cookie signature not valid")
 	}
 
-	return sessions.DecodeSessionState(val, s.CookieCipher, true)
+	// The session is encrypted with the cookie secret again when it is saved
+	cipher := s.CookieCipher
+	if secret > 0 {
+		cipher = s.PreviousCookieCiphers[secret-1]
+	}
+	return sessions.DecodeSessionState(val, cipher, true)
 }
 
 // Clear clears any saved session information by writing a cookie to
@@ -160,11 +168,20 @@ func NewCookieSessionStore(opts *options.SessionOptions, cookieOpts *options.Coo
 	if err != nil {
 		return nil, fmt.Errorf("error initialising cipher: %v", err)
 	}
+	previousCiphers := make([]encryption.Cipher, 0, len(cookieOpts.PreviousSecrets))
+	for _, previousSecret := range cookieOpts.PreviousSecrets {
+		previousCipher, err := encryption.NewCFBCipher(encryption.SecretBytes(previousSecret))
+		if err != nil {
+			return nil, fmt.Errorf("error initialising cipher of a previous cookie secret: %v", err)
+		}
+		previousCiphers = append(previousCiphers, previousCipher)
+	}
 
 	return &SessionStore{
-		CookieCipher: cipher,
-		Cookie:       cookieOpts,
-		Minimal:      opts.Cookie.Minimal,
+		CookieCipher:          cipher,
+		PreviousCookieCiphers: previousCiphers,
+		Cookie:                cookieOpts,
+		Minimal:               opts.Cookie.Minimal,
 	}, nil
 }
 
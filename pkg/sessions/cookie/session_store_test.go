@@ -4,6 +4,7 @@ import (
 	"fmt"
 	mathrand "math/rand"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -32,6 +33,54 @@ var _ = Describe("Cookie SessionStore Tests", func() {
 			opts.Type = options.CookieSessionStoreType
 			return NewCookieSessionStore(opts, cookieOpts)
 		}, nil)
+
+	It("loads the sessions encrypted with a previous cookie secret and encrypts them with the new one when saved", func() {
+		previousOpts := &options.Cookie{
+			Name:   "_oauth2_proxy",
+			Secret: "0123456789abcdefghijklmnopqrstuv",
+			Expire: time.Hour,
+		}
+		rotatedOpts := &options.Cookie{
+			Name:            "_oauth2_proxy",
+			Secret:          "vutsrqponmlkjihgfedcba9876543210",
+			PreviousSecrets: []string{previousOpts.Secret},
+			Expire:          time.Hour,
+		}
+		previous, err := NewCookieSessionStore(&options.SessionOptions{}, previousOpts)
+		Expect(err).ToNot(HaveOccurred())
+		rotated, err := NewCookieSessionStore(&options.SessionOptions{}, rotatedOpts)
+		Expect(err).ToNot(HaveOccurred())
+		current, err := NewCookieSessionStore(&options.SessionOptions{}, &options.Cookie{
+			Name:   "_oauth2_proxy",
+			Secret: rotatedOpts.Secret,
+			Expire: time.Hour,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		withCookies := func(rw *httptest.ResponseRecorder) *http.Request {
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			for _, c := range rw.Result().Cookies() {
+				req.AddCookie(c)
+			}
+			return req
+		}
+
+		rw := httptest.NewRecorder()
+		Expect(previous.Save(rw, httptest.NewRequest("GET", "http://example.com/", nil), &sessionsapi.SessionState{User: "john.doe"})).To(Succeed())
+		req := withCookies(rw)
+		_, err = current.Load(req)
+		Expect(err).To(MatchError("cookie signature not valid"))
+
+		session, err := rotated.Load(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(session.User).To(Equal("john.doe"))
+
+		rw = httptest.NewRecorder()
+		Expect(rotated.Save(rw, req, session)).To(Succeed())
+		session, err = current.Load(withCookies(rw))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(session.User).To(Equal("john.doe"))
+	})
 })
 
 func Test_copyCookie(t *testing.T) {
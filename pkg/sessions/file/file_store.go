@@ -0,0 +1,405 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// openTimeout is how long to wait for the lock of a database file
+	// already opened by another process
+	openTimeout = time.Second
+	// shrinkTxMaxSize is the size of the transactions copying the entries
+	// to the shrunk database file
+	shrinkTxMaxSize = 64 * 1024
+)
+
+var (
+	sessionsBucket    = []byte("sessions")
+	locksBucket       = []byte("locks")
+	userIndexBucket   = []byte("user-index")
+	revocationsBucket = []byte("revocations")
+
+	errNotFound = errors.New("session does not exist")
+)
+
+var (
+	_ persistence.Lister    = (*SessionStore)(nil)
+	_ persistence.UserIndex = (*SessionStore)(nil)
+)
+
+// SessionStore is an implementation of the persistence.Store
+// interface that stores sessions in an embedded database file
+type SessionStore struct {
+	db *bolt.DB
+	// now is overridden in tests to fast forward the expiration
+	now func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewFileSessionStore initialises a new instance of the SessionStore and wraps
+// it in a persistence.Manager
+func NewFileSessionStore(opts *options.SessionOptions, cookieOpts *options.Cookie) (sessions.SessionStore, error) {
+	store, err := newSessionStore(opts.File)
+	if err != nil {
+		return nil, err
+	}
+	return persistence.NewManager(store, cookieOpts), nil
+}
+
+func newSessionStore(opts options.FileStoreOptions) (*SessionStore, error) {
+	db, err := bolt.Open(opts.Path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("error opening session database %q: %v", opts.Path, err)
+	}
+
+	shrunk, err := shrink(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error shrinking session database %q: %v", opts.Path, err)
+	}
+	db = shrunk
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{sessionsBucket, locksBucket, userIndexBucket, revocationsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initialising session database %q: %v", opts.Path, err)
+	}
+
+	store := &SessionStore{
+		db:   db,
+		now:  time.Now,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go store.compactPeriodically(opts.CompactionInterval)
+	return store, nil
+}
+
+// Save stores the value with its expiration
+func (store *SessionStore) Save(_ context.Context, key string, value []byte, exp time.Duration) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Put([]byte(key), encodeEntry(store.now().Add(exp), value))
+	})
+	if err != nil {
+		return fmt.Errorf("error saving file session: %v", err)
+	}
+	return nil
+}
+
+// Load reads the value of the key, unless it has expired
+func (store *SessionStore) Load(_ context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := store.db.View(func(tx *bolt.Tx) error {
+		expiresAt, data, ok := decodeEntry(tx.Bucket(sessionsBucket).Get([]byte(key)))
+		if !ok || !store.now().Before(expiresAt) {
+			return errNotFound
+		}
+		// The data is only valid during the transaction
+		value = bytes.Clone(data)
+		return nil
+	})
+	if errors.Is(err, errNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading file session: %v", err)
+	}
+	return value, nil
+}
+
+// Clear removes the key
+func (store *SessionStore) Clear(_ context.Context, key string) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("error clearing the file session: %v", err)
+	}
+	return nil
+}
+
+// Lock creates a lock object for sessions.SessionState
+func (store *SessionStore) Lock(key string) sessions.Lock {
+	return &Lock{
+		store: store,
+		key:   []byte(key),
+	}
+}
+
+// VerifyConnection verifies the database file is still open
+func (store *SessionStore) VerifyConnection(_ context.Context) error {
+	return store.db.View(func(*bolt.Tx) error { return nil })
+}
+
+// List returns the keys starting with the prefix that have not expired
+func (store *SessionStore) List(_ context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		now := store.now()
+		c := tx.Bucket(sessionsBucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if expiresAt, _, ok := decodeEntry(v); ok && now.Before(expiresAt) {
+				keys = append(keys, string(k))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the file sessions: %v", err)
+	}
+	return keys, nil
+}
+
+// AddUserTicket adds the ticket to the index of the user, stored as a nested
+// bucket of tickets with their expiration and the creation of their session
+func (store *SessionStore) AddUserTicket(_ context.Context, user string, ticketID string, createdAt time.Time, exp time.Duration) error {
+	created := make([]byte, 8)
+	binary.BigEndian.PutUint64(created, uint64(createdAt.UnixNano()))
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		userBucket, err := tx.Bucket(userIndexBucket).CreateBucketIfNotExists([]byte(user))
+		if err != nil {
+			return err
+		}
+		return userBucket.Put([]byte(ticketID), encodeEntry(store.now().Add(exp), created))
+	})
+	if err != nil {
+		return fmt.Errorf("error adding the session to the user index: %v", err)
+	}
+	return nil
+}
+
+// UserTickets returns the tickets of the user that have not expired yet, the
+// earliest created first. The tickets indexed before their creation was
+// recorded come first.
+func (store *SessionStore) UserTickets(_ context.Context, user string) ([]string, error) {
+	type userTicket struct {
+		id        string
+		createdAt int64
+	}
+	tickets := []userTicket{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket(userIndexBucket).Bucket([]byte(user))
+		if userBucket == nil {
+			return nil
+		}
+		now := store.now()
+		return userBucket.ForEach(func(k, v []byte) error {
+			expiresAt, created, ok := decodeEntry(v)
+			if !ok || !now.Before(expiresAt) {
+				return nil
+			}
+			ticket := userTicket{id: string(k)}
+			if len(created) == 8 {
+				ticket.createdAt = int64(binary.BigEndian.Uint64(created))
+			}
+			tickets = append(tickets, ticket)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sessions of the user index: %v", err)
+	}
+
+	sort.SliceStable(tickets, func(i, j int) bool {
+		return tickets[i].createdAt < tickets[j].createdAt
+	})
+	ticketIDs := make([]string, 0, len(tickets))
+	for _, ticket := range tickets {
+		ticketIDs = append(ticketIDs, ticket.id)
+	}
+	return ticketIDs, nil
+}
+
+// RemoveUserTickets removes the tickets from the index of the user
+func (store *SessionStore) RemoveUserTickets(_ context.Context, user string, ticketIDs ...string) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket(userIndexBucket).Bucket([]byte(user))
+		if userBucket == nil {
+			return nil
+		}
+		for _, ticketID := range ticketIDs {
+			if err := userBucket.Delete([]byte(ticketID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error removing the sessions from the user index: %v", err)
+	}
+	return nil
+}
+
+// Close stops the compaction and closes the database file
+func (store *SessionStore) Close() error {
+	store.stopOnce.Do(func() {
+		close(store.stop)
+		<-store.done
+	})
+	return store.db.Close()
+}
+
+// compactPeriodically removes the expired entries at every interval, until
+// the store is closed
+func (store *SessionStore) compactPeriodically(interval time.Duration) {
+	defer close(store.done)
+	if interval <= 0 {
+		<-store.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := store.compact(); err != nil {
+				logger.Errorf("Error compacting the file session store: %v", err)
+			}
+		case <-store.stop:
+			return
+		}
+	}
+}
+
+// compact removes the expired sessions, locks, revocations and user index
+// entries. The space they used is reused by the next writes, the file itself
+// only shrinks when the store is opened again.
+func (store *SessionStore) compact() error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		now := store.now()
+		for _, bucket := range [][]byte{sessionsBucket, locksBucket, revocationsBucket} {
+			if err := deleteExpired(tx.Bucket(bucket), now); err != nil {
+				return err
+			}
+		}
+
+		index := tx.Bucket(userIndexBucket)
+		emptyUsers := [][]byte{}
+		err := index.ForEachBucket(func(user []byte) error {
+			userBucket := index.Bucket(user)
+			if err := deleteExpired(userBucket, now); err != nil {
+				return err
+			}
+			if k, _ := userBucket.Cursor().First(); k == nil {
+				emptyUsers = append(emptyUsers, user)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, user := range emptyUsers {
+			if err := index.DeleteBucket(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// shrink rewrites the database file without the pages freed by the deleted
+// entries, which bbolt never gives back to the file system, when they are at
+// least half of the file. The copy replaces the file and is opened before the
+// original is closed, so that the file stays locked for other processes.
+// The database is returned as is when it is not worth shrinking.
+func shrink(db *bolt.DB) (*bolt.DB, error) {
+	path := db.Path()
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	pages := info.Size() / int64(db.Info().PageSize)
+	if int64(db.Stats().FreePageN)*2 < pages {
+		return db, nil
+	}
+
+	tmpPath := path + ".shrink"
+	dst, err := bolt.Open(tmpPath, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+	if err := bolt.Compact(dst, db, shrinkTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	shrunk, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+	db.Close()
+	return shrunk, nil
+}
+
+// deleteExpired deletes the expired entries of the bucket. They are collected
+// before being deleted, as deleting from a cursor while iterating skips keys.
+func deleteExpired(bucket *bolt.Bucket, now time.Time) error {
+	expired := [][]byte{}
+	c := bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if v == nil {
+			// Nested bucket
+			continue
+		}
+		if expiresAt, _, ok := decodeEntry(v); !ok || !now.Before(expiresAt) {
+			expired = append(expired, bytes.Clone(k))
+		}
+	}
+
+	for _, k := range expired {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeEntry prefixes the value with its expiration in Unix nanoseconds
+func encodeEntry(expiresAt time.Time, value []byte) []byte {
+	entry := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(expiresAt.UnixNano()))
+	copy(entry[8:], value)
+	return entry
+}
+
+func decodeEntry(entry []byte) (time.Time, []byte, bool) {
+	if len(entry) < 8 {
+		return time.Time{}, nil, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(entry))), entry[8:], true
+}
@@ -0,0 +1,226 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/tests"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("File SessionStore Tests", func() {
+	// offset fast forwards the clock of the stores
+	var offset time.Duration
+	var stores []*SessionStore
+
+	now := func() time.Time {
+		return time.Now().Add(offset)
+	}
+
+	BeforeEach(func() {
+		offset = 0
+		stores = nil
+	})
+
+	JustAfterEach(func() {
+		// Release the database files immediately after the test ends
+		for _, store := range stores {
+			Expect(store.Close()).To(Succeed())
+		}
+	})
+
+	tests.RunSessionStoreTests(
+		func(opts *options.SessionOptions, cookieOpts *options.Cookie) (sessionsapi.SessionStore, error) {
+			opts.Type = options.FileSessionStoreType
+			opts.File.Path = filepath.Join(GinkgoT().TempDir(), "sessions.db")
+
+			ss, err := NewFileSessionStore(opts, cookieOpts)
+			if err != nil {
+				return nil, err
+			}
+			store := ss.(*persistence.Manager).Store.(*SessionStore)
+			store.now = now
+			stores = append(stores, store)
+			return ss, nil
+		},
+		func(d time.Duration) error {
+			offset += d
+			return nil
+		},
+	)
+
+	Describe("File RevocationList", func() {
+		tests.RunRevocationListTests(
+			func() sessionsapi.RevocationList {
+				store, err := newSessionStore(options.FileStoreOptions{
+					Path: filepath.Join(GinkgoT().TempDir(), "sessions.db"),
+				})
+				Expect(err).ToNot(HaveOccurred())
+				store.now = now
+				stores = append(stores, store)
+				return store
+			},
+			func(d time.Duration) error {
+				offset += d
+				return nil
+			},
+		)
+	})
+
+	Context("with a store", func() {
+		var store *SessionStore
+		ctx := context.Background()
+
+		BeforeEach(func() {
+			var err error
+			store, err = newSessionStore(options.FileStoreOptions{
+				Path: filepath.Join(GinkgoT().TempDir(), "sessions.db"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			store.now = now
+			stores = append(stores, store)
+		})
+
+		It("fails to open a database already opened", func() {
+			_, err := newSessionStore(options.FileStoreOptions{
+				Path: store.db.Path(),
+			})
+			Expect(err).To(MatchError(ContainSubstring("error opening session database")))
+		})
+
+		It("does not load expired sessions", func() {
+			Expect(store.Save(ctx, "key", []byte("value"), time.Minute)).To(Succeed())
+			Expect(store.Load(ctx, "key")).To(Equal([]byte("value")))
+
+			offset = 2 * time.Minute
+			_, err := store.Load(ctx, "key")
+			Expect(err).To(MatchError("session does not exist"))
+		})
+
+		It("lists the keys with the prefix that have not expired", func() {
+			Expect(store.Save(ctx, "prefix-a", []byte("a"), time.Minute)).To(Succeed())
+			Expect(store.Save(ctx, "prefix-b", []byte("b"), time.Hour)).To(Succeed())
+			Expect(store.Save(ctx, "other", []byte("c"), time.Hour)).To(Succeed())
+
+			offset = 2 * time.Minute
+			Expect(store.List(ctx, "prefix-")).To(ConsistOf("prefix-b"))
+		})
+
+		It("compacts the expired entries", func() {
+			Expect(store.Save(ctx, "expired", []byte("value"), time.Minute)).To(Succeed())
+			Expect(store.Save(ctx, "valid", []byte("value"), time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "expired-user", "ticket", time.Now(), time.Minute)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "ticket", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.Lock("expired").Obtain(ctx, time.Minute)).To(Succeed())
+
+			offset = 2 * time.Minute
+			Expect(store.compact()).To(Succeed())
+
+			offset = 0
+			_, err := store.Load(ctx, "expired")
+			Expect(err).To(MatchError("session does not exist"))
+			Expect(store.Load(ctx, "valid")).To(Equal([]byte("value")))
+			Expect(store.UserTickets(ctx, "expired-user")).To(BeEmpty())
+			Expect(store.UserTickets(ctx, "user")).To(ConsistOf("ticket"))
+			Expect(store.Lock("expired").Peek(ctx)).To(BeFalse())
+		})
+
+		It("compacts consecutive expired entries", func() {
+			now := time.Now()
+			keys := 0
+			Expect(store.db.Update(func(tx *bolt.Tx) error {
+				bucket := tx.Bucket(sessionsBucket)
+				for i := 0; i < 100; i++ {
+					Expect(bucket.Put([]byte(fmt.Sprintf("expired-%03d", i)), encodeEntry(now, []byte("value")))).To(Succeed())
+				}
+				Expect(bucket.Put([]byte("valid"), encodeEntry(now.Add(time.Hour), []byte("value")))).To(Succeed())
+
+				// The entries written in the same transaction are all deleted
+				if err := deleteExpired(bucket, now); err != nil {
+					return err
+				}
+				return bucket.ForEach(func(_, _ []byte) error {
+					keys++
+					return nil
+				})
+			})).To(Succeed())
+			Expect(keys).To(Equal(1))
+		})
+
+		It("keeps the revocations across restarts", func() {
+			createdAt := time.Now().Add(-time.Minute)
+			session := &sessionsapi.SessionState{User: "user", CreatedAt: &createdAt}
+			Expect(store.Revoke(ctx, "user", "", time.Hour)).To(Succeed())
+			path := store.db.Path()
+			Expect(store.Close()).To(Succeed())
+
+			reopened, err := newSessionStore(options.FileStoreOptions{Path: path})
+			Expect(err).ToNot(HaveOccurred())
+			stores = []*SessionStore{reopened}
+			Expect(reopened.IsRevoked(ctx, session)).To(BeTrue())
+		})
+
+		It("shrinks the file of the compacted entries when it is opened again", func() {
+			value := make([]byte, 4096)
+			for i := 0; i < 500; i++ {
+				Expect(store.Save(ctx, fmt.Sprintf("key-%d", i), value, time.Minute)).To(Succeed())
+			}
+			Expect(store.Save(ctx, "valid", []byte("value"), time.Hour)).To(Succeed())
+			offset = 2 * time.Minute
+			Expect(store.compact()).To(Succeed())
+			offset = 0
+			path := store.db.Path()
+			Expect(store.Close()).To(Succeed())
+
+			before, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			reopened, err := newSessionStore(options.FileStoreOptions{Path: path})
+			Expect(err).ToNot(HaveOccurred())
+			stores = []*SessionStore{reopened}
+
+			after, err := os.Stat(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(after.Size()).To(BeNumerically("<", before.Size()/4))
+			Expect(reopened.Load(ctx, "valid")).To(Equal([]byte("value")))
+		})
+
+		Context("with a lock", func() {
+			var lock sessionsapi.Lock
+
+			BeforeEach(func() {
+				lock = store.Lock("key")
+				Expect(lock.Obtain(ctx, time.Minute)).To(Succeed())
+			})
+
+			It("is not obtained twice", func() {
+				Expect(store.Lock("key").Obtain(ctx, time.Minute)).To(Equal(sessionsapi.ErrLockNotObtained))
+			})
+
+			It("is obtained once expired", func() {
+				offset = 2 * time.Minute
+				Expect(store.Lock("key").Obtain(ctx, time.Minute)).To(Succeed())
+				Expect(lock.Refresh(ctx, time.Minute)).To(Equal(sessionsapi.ErrNotLocked))
+				Expect(lock.Release(ctx)).To(Equal(sessionsapi.ErrNotLocked))
+			})
+
+			It("is refreshed and released", func() {
+				Expect(lock.Refresh(ctx, time.Hour)).To(Succeed())
+				offset = 2 * time.Minute
+				Expect(lock.Peek(ctx)).To(BeTrue())
+
+				Expect(lock.Release(ctx)).To(Succeed())
+				Expect(lock.Peek(ctx)).To(BeFalse())
+				Expect(lock.Release(ctx)).To(Equal(sessionsapi.ErrNotLocked))
+			})
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Lock is a lock on a session, stored in the database with an expiration
+// and a random token identifying its holder
+type Lock struct {
+	store *SessionStore
+	key   []byte
+	token []byte
+}
+
+// Obtain obtains the lock unless it is held and has not expired.
+func (l *Lock) Obtain(_ context.Context, expiration time.Duration) error {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return fmt.Errorf("error creating lock token: %v", err)
+	}
+
+	err := l.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+		if expiresAt, _, ok := decodeEntry(bucket.Get(l.key)); ok && l.store.now().Before(expiresAt) {
+			return sessions.ErrLockNotObtained
+		}
+		return bucket.Put(l.key, encodeEntry(l.store.now().Add(expiration), token))
+	})
+	if err != nil {
+		return err
+	}
+	l.token = token
+	return nil
+}
+
+// Refresh extends the expiration of the lock, if it is still held.
+func (l *Lock) Refresh(_ context.Context, expiration time.Duration) error {
+	if l.token == nil {
+		return sessions.ErrNotLocked
+	}
+	return l.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+		if !l.held(bucket) {
+			return sessions.ErrNotLocked
+		}
+		return bucket.Put(l.key, encodeEntry(l.store.now().Add(expiration), l.token))
+	})
+}
+
+// Peek returns true, if the lock is still applied.
+func (l *Lock) Peek(_ context.Context) (bool, error) {
+	locked := false
+	err := l.store.db.View(func(tx *bolt.Tx) error {
+		expiresAt, _, ok := decodeEntry(tx.Bucket(locksBucket).Get(l.key))
+		locked = ok && l.store.now().Before(expiresAt)
+		return nil
+	})
+	return locked, err
+}
+
+// Release removes the lock, if it is still held.
+func (l *Lock) Release(_ context.Context) error {
+	if l.token == nil {
+		return sessions.ErrNotLocked
+	}
+	err := l.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(locksBucket)
+		if !l.held(bucket) {
+			return sessions.ErrNotLocked
+		}
+		return bucket.Delete(l.key)
+	})
+	if errors.Is(err, sessions.ErrNotLocked) {
+		return err
+	}
+	l.token = nil
+	return err
+}
+
+// held returns whether the lock in the bucket is the one obtained by this
+// Lock and has not expired
+func (l *Lock) held(bucket *bolt.Bucket) bool {
+	expiresAt, token, ok := decodeEntry(bucket.Get(l.key))
+	return ok && l.store.now().Before(expiresAt) && bytes.Equal(token, l.token)
+}
@@ -0,0 +1,94 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	revokedUserKeyPrefix      = "user-"
+	revokedSessionIDKeyPrefix = "sid-"
+)
+
+var _ sessions.RevocationList = (*SessionStore)(nil)
+
+// Revoke stores the revocation in the database file so that it is kept
+// across restarts. A revocation without expiration never expires.
+func (store *SessionStore) Revoke(_ context.Context, user string, sessionID string, expiration time.Duration) error {
+	key := revokedUserKey(user)
+	if sessionID != "" {
+		key = revokedSessionIDKey(sessionID)
+	}
+
+	now := store.now()
+	expiresAt := time.Unix(0, math.MaxInt64)
+	if expiration > 0 {
+		expiresAt = now.Add(expiration)
+	}
+	revokedAt := make([]byte, 8)
+	binary.BigEndian.PutUint64(revokedAt, uint64(now.UnixNano()))
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(revocationsBucket).Put(key, encodeEntry(expiresAt, revokedAt))
+	})
+	if err != nil {
+		return fmt.Errorf("error saving session revocation: %v", err)
+	}
+	return nil
+}
+
+// IsRevoked checks whether the session ID of the session, or every session of
+// its user created before the revocation, has been revoked
+func (store *SessionStore) IsRevoked(_ context.Context, s *sessions.SessionState) (bool, error) {
+	revoked := false
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(revocationsBucket)
+		now := store.now()
+
+		if s.SessionID != "" {
+			if expiresAt, _, ok := decodeEntry(bucket.Get(revokedSessionIDKey(s.SessionID))); ok && now.Before(expiresAt) {
+				revoked = true
+				return nil
+			}
+		}
+
+		if s.User == "" {
+			return nil
+		}
+		expiresAt, value, ok := decodeEntry(bucket.Get(revokedUserKey(s.User)))
+		if !ok || !now.Before(expiresAt) {
+			return nil
+		}
+		if len(value) != 8 {
+			return fmt.Errorf("invalid session revocation")
+		}
+		revokedAt := time.Unix(0, int64(binary.BigEndian.Uint64(value)))
+		revoked = s.CreatedAt == nil || !s.CreatedAt.After(revokedAt)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("error loading session revocation: %v", err)
+	}
+	return revoked, nil
+}
+
+// The user and session ID are hashed as they are not under our control
+func revokedUserKey(user string) []byte {
+	return hashKey(revokedUserKeyPrefix, user)
+}
+
+func revokedSessionIDKey(sessionID string) []byte {
+	return hashKey(revokedSessionIDKeyPrefix, sessionID)
+}
+
+func hashKey(prefix, value string) []byte {
+	sum := sha256.Sum256([]byte(value))
+	return append([]byte(prefix), sum[:]...)
+}
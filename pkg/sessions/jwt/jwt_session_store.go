@@ -2,16 +2,15 @@ package jwt
 
 import (
 	"context"
-	"crypto/rsa"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	pkgcookies "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/cookies"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/encryption"
 )
 
 // Ensure CookieSessionStore implements the interface
@@ -20,50 +19,52 @@ var _ sessions.SessionStore = &SessionStore{}
 // SessionStore is an implementation of the sessions.SessionStore
 // interface that stores sessions in client side cookies
 type SessionStore struct {
-	Cookie *options.Cookie
-	JWTKey *rsa.PrivateKey
+	Cookie   *options.Cookie
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+	// TokensCipher encrypts the OAuth tokens of the session in the tokens
+	// claim, tokens are not stored when it is nil
+	TokensCipher encryption.Cipher
+	// PreviousTokensCiphers decrypt the tokens encrypted with the previous
+	// cookie secrets
+	PreviousTokensCiphers []encryption.Cipher
 }
 
 // NewJWTSessionStore initialises a new instance of the SessionStore from
 // the configuration given
 func NewJWTSessionStore(opts *options.SessionOptions, cookieOpts *options.Cookie) (sessions.SessionStore, error) {
-	signKey, err := parseJWTKey(opts.JWT)
+	keys, err := NewKeySet(opts.JWT)
 	if err != nil {
 		return nil, err
 	}
 
-	return &SessionStore{
-		Cookie: cookieOpts,
-		JWTKey: signKey,
-	}, nil
-}
-
-func parseJWTKey(o options.JWTStoreOptions) (*rsa.PrivateKey, error) {
-	switch {
-	case o.JWTKey != "" && o.JWTKeyFile != "":
-		return nil, fmt.Errorf("cannot set both jwt-session-key and jwt-session-key-file options")
-	case o.JWTKey == "" && o.JWTKeyFile == "":
-		return nil, fmt.Errorf("jwt session store requires a private key for signing JWTs")
-	case o.JWTKey != "":
-		// The JWT Key is in the commandline argument
-		signKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(o.JWTKey))
+	var tokensCiphers []encryption.Cipher
+	if opts.JWT.JWTTokens {
+		secrets, err := cookieOpts.GetSecrets()
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error getting cookie secret: %v", err)
 		}
-		return signKey, nil
-	// o.JWTKeyFile != "":
-	default:
-		// The JWT key is in the filesystem
-		keyData, err := os.ReadFile(o.JWTKeyFile)
-		if err != nil {
-			return nil, err
-		}
-		signKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyData)
-		if err != nil {
-			return nil, err
+		for _, secret := range secrets {
+			cipher, err := encryption.NewCFBCipher(encryption.SecretBytes(secret))
+			if err != nil {
+				return nil, fmt.Errorf("error initialising cipher: %v", err)
+			}
+			tokensCiphers = append(tokensCiphers, encryption.NewBase64Cipher(cipher))
 		}
-		return signKey, nil
 	}
+
+	store := &SessionStore{
+		Cookie:   cookieOpts,
+		Keys:     keys,
+		Issuer:   opts.JWT.JWTIssuer,
+		Audience: opts.JWT.JWTAudience,
+	}
+	if len(tokensCiphers) > 0 {
+		store.TokensCipher = tokensCiphers[0]
+		store.PreviousTokensCiphers = tokensCiphers[1:]
+	}
+	return store, nil
 }
 
 // Save takes a sessions.SessionState and stores the information from it
@@ -97,13 +98,24 @@ type Claims struct {
 	Tenant   string   `json:"tenant"`
 	Groups   []string `json:"groups"`
 	Tenants  []string `json:"tenants"`
+	// SID is the session of the identity provider, used by back-channel logouts
+	SID string `json:"sid,omitempty"`
+	// Tokens holds the encrypted OAuth tokens of the session
+	Tokens string `json:"tokens,omitempty"`
+	// AuthTime is when the user authenticated, as the OIDC claim of the same
+	// name, and LastActivity when the session was last used
+	AuthTime     *jwt.NumericDate `json:"auth_time,omitempty"`
+	LastActivity *jwt.NumericDate `json:"last_activity,omitempty"`
+	// Binding is the client the session is bound to
+	Binding *sessions.ClientBinding `json:"client_binding,omitempty"`
 }
 
-func (s *SessionStore) tokenFromSession(ss *sessions.SessionState) (string, error) {
-	claims := Claims{
+// NewClaims returns the claims describing the user of the session, the
+// registered claims other than the subject are left to the caller
+func NewClaims(ss *sessions.SessionState) Claims {
+	return Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			NotBefore: jwt.NewNumericDate(*ss.CreatedAt),
-			ExpiresAt: jwt.NewNumericDate(*ss.ExpiresOn),
+			Subject: ss.User,
 		},
 		UID:      ss.User,
 		CN:       ss.PreferredUsername,
@@ -112,15 +124,76 @@ func (s *SessionStore) tokenFromSession(ss *sessions.SessionState) (string, erro
 		Tenant:   ss.Tenant,
 		Groups:   ss.Groups,
 		Tenants:  ss.Tenants,
+		SID:      ss.SessionID,
+	}
+}
+
+func (s *SessionStore) tokenFromSession(ss *sessions.SessionState) (string, error) {
+	claims := NewClaims(ss)
+	claims.Issuer = s.Issuer
+	claims.NotBefore = jwt.NewNumericDate(*ss.CreatedAt)
+	claims.IssuedAt = jwt.NewNumericDate(*ss.CreatedAt)
+	claims.ExpiresAt = jwt.NewNumericDate(s.expiresAt(ss))
+	if ss.AuthenticatedAt != nil {
+		claims.AuthTime = jwt.NewNumericDate(*ss.AuthenticatedAt)
+	}
+	if ss.LastActivity != nil {
+		claims.LastActivity = jwt.NewNumericDate(*ss.LastActivity)
+	}
+	claims.Binding = ss.Binding
+	if s.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.Audience}
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = "secret"
-	tokenString, err := token.SignedString(s.JWTKey)
+
+	if s.TokensCipher != nil {
+		tokens, err := s.encryptTokens(ss)
+		if err != nil {
+			return "", err
+		}
+		claims.Tokens = tokens
+	}
+
+	return s.Keys.Sign(claims)
+}
+
+// expiresAt returns the expiry of the JWT.
+// When the tokens are stored the JWT lives as long as the cookie, like the
+// cookie store sessions, so that it can still be refreshed once the access
+// token has expired. Otherwise it expires with the access token.
+func (s *SessionStore) expiresAt(ss *sessions.SessionState) time.Time {
+	if ss.ExpiresOn == nil || (s.TokensCipher != nil && s.Cookie.Expire > 0) {
+		return time.Now().Add(s.Cookie.Expire)
+	}
+	return *ss.ExpiresOn
+}
+
+// encryptTokens encodes the OAuth tokens of the session and their expiry
+// with the cookie secret so that they are not readable from the JWT
+func (s *SessionStore) encryptTokens(ss *sessions.SessionState) (string, error) {
+	tokens := &sessions.SessionState{
+		AccessToken:  ss.AccessToken,
+		IDToken:      ss.IDToken,
+		RefreshToken: ss.RefreshToken,
+		ExpiresOn:    ss.ExpiresOn,
+	}
+	encrypted, err := tokens.EncodeSessionState(s.TokensCipher, true)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("error encrypting session tokens: %v", err)
 	}
+	return string(encrypted), nil
+}
 
-	return tokenString, nil
+// decodeTokens decrypts the tokens claim with the cookie secret, or the
+// previous cookie secrets for the sessions saved before it was rotated
+func (s *SessionStore) decodeTokens(encrypted string) (*sessions.SessionState, error) {
+	tokens, err := sessions.DecodeSessionState([]byte(encrypted), s.TokensCipher, true)
+	for _, cipher := range s.PreviousTokensCiphers {
+		if err == nil {
+			break
+		}
+		tokens, err = sessions.DecodeSessionState([]byte(encrypted), cipher, true)
+	}
+	return tokens, err
 }
 
 func (s *SessionStore) makeCookie(req *http.Request, name string, value string, expiration time.Duration) *http.Cookie {
@@ -147,16 +220,28 @@ func (s *SessionStore) Load(req *http.Request) (*sessions.SessionState, error) {
 }
 
 func (s *SessionStore) sessionFromToken(tokenString string) (*sessions.SessionState, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return &s.JWTKey.PublicKey, nil
-	})
+	var parserOpts []jwt.ParserOption
+	if s.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(s.Issuer))
+	}
+	if s.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(s.Audience))
+	}
+
+	token, err := s.Keys.Parse(tokenString, &Claims{}, parserOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return &sessions.SessionState{
-			CreatedAt:         &claims.NotBefore.Time,
+		// Tokens issued before the iat claim was added only have nbf
+		createdAt := claims.NotBefore
+		if claims.IssuedAt != nil {
+			createdAt = claims.IssuedAt
+		}
+
+		ss := &sessions.SessionState{
+			CreatedAt:         &createdAt.Time,
 			ExpiresOn:         &claims.ExpiresAt.Time,
 			User:              claims.UID,
 			PreferredUsername: claims.CN,
@@ -165,7 +250,27 @@ func (s *SessionStore) sessionFromToken(tokenString string) (*sessions.SessionSt
 			Tenant:            claims.Tenant,
 			Groups:            claims.Groups,
 			Tenants:           claims.Tenants,
-		}, nil
+			SessionID:         claims.SID,
+			Binding:           claims.Binding,
+		}
+		if claims.AuthTime != nil {
+			ss.AuthenticatedAt = &claims.AuthTime.Time
+		}
+		if claims.LastActivity != nil {
+			ss.LastActivity = &claims.LastActivity.Time
+		}
+
+		if s.TokensCipher != nil && claims.Tokens != "" {
+			tokens, err := s.decodeTokens(claims.Tokens)
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting session tokens: %v", err)
+			}
+			ss.AccessToken = tokens.AccessToken
+			ss.IDToken = tokens.IDToken
+			ss.RefreshToken = tokens.RefreshToken
+			ss.ExpiresOn = tokens.ExpiresOn
+		}
+		return ss, nil
 	}
 	return nil, err
 }
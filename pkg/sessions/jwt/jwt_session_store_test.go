@@ -2,7 +2,9 @@ package jwt
 
 import (
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
@@ -55,4 +57,190 @@ var _ = Describe("JWT SessionStore Tests", func() {
 			opts.JWT.JWTKey = JWTKey
 			return NewJWTSessionStore(opts, cookieOpts)
 		}, nil)
+
+	Context("with the tokens claim", func() {
+		tests.RunSessionStoreTests(
+			func(opts *options.SessionOptions, cookieOpts *options.Cookie) (sessionsapi.SessionStore, error) {
+				opts.Type = options.JWTSessionStoreType
+				opts.JWT.JWTKey = JWTKey
+				opts.JWT.JWTTokens = true
+				return NewJWTSessionStore(opts, cookieOpts)
+			}, nil)
+	})
+
+	Context("claims", func() {
+		var (
+			cookieOpts *options.Cookie
+			session    *sessionsapi.SessionState
+		)
+
+		BeforeEach(func() {
+			cookieOpts = &options.Cookie{
+				Name:   "_oauth2_proxy",
+				Path:   "/",
+				Expire: 168 * time.Hour,
+				Secret: "secretthirtytwobytes+abcdefghijk",
+			}
+
+			createdAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+			expiresOn := time.Now().Add(-time.Second).Truncate(time.Second)
+			session = &sessionsapi.SessionState{
+				CreatedAt:    &createdAt,
+				ExpiresOn:    &expiresOn,
+				AccessToken:  "AccessToken",
+				IDToken:      "IDToken",
+				RefreshToken: "RefreshToken",
+				User:         "john.doe",
+				Email:        "john.doe@example.com",
+			}
+		})
+
+		newStore := func(jwtOpts options.JWTStoreOptions) *SessionStore {
+			jwtOpts.JWTKey = JWTKey
+			ss, err := NewJWTSessionStore(&options.SessionOptions{
+				Type: options.JWTSessionStoreType,
+				JWT:  jwtOpts,
+			}, cookieOpts)
+			Expect(err).ToNot(HaveOccurred())
+			return ss.(*SessionStore)
+		}
+
+		parseClaims := func(tokenString string) *Claims {
+			claims := &Claims{}
+			_, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+			Expect(err).ToNot(HaveOccurred())
+			return claims
+		}
+
+		It("sets the registered claims", func() {
+			ss := newStore(options.JWTStoreOptions{
+				JWTIssuer:   "https://proxy.example.com",
+				JWTAudience: "upstream",
+			})
+			tokenString, err := ss.tokenFromSession(session)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims := parseClaims(tokenString)
+			Expect(claims.Issuer).To(Equal("https://proxy.example.com"))
+			Expect(claims.Audience).To(Equal(jwt.ClaimStrings{"upstream"}))
+			Expect(claims.Subject).To(Equal("john.doe"))
+			Expect(claims.IssuedAt.Time).To(Equal(*session.CreatedAt))
+			Expect(claims.ExpiresAt.Time).To(Equal(*session.ExpiresOn))
+			Expect(claims.Tokens).To(BeEmpty())
+		})
+
+		It("keeps the authentication time and last activity", func() {
+			authenticatedAt := session.CreatedAt.Add(-time.Hour)
+			lastActivity := session.CreatedAt.Add(30 * time.Second)
+			session.AuthenticatedAt = &authenticatedAt
+			session.LastActivity = &lastActivity
+
+			ss := newStore(options.JWTStoreOptions{})
+			// The expired session is still parsed, for the comparison
+			session.ExpiresOn = nil
+			tokenString, err := ss.tokenFromSession(session)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims := parseClaims(tokenString)
+			Expect(claims.AuthTime.Time).To(Equal(authenticatedAt))
+			Expect(claims.LastActivity.Time).To(Equal(lastActivity))
+
+			loaded, err := ss.sessionFromToken(tokenString)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*loaded.AuthenticatedAt).To(Equal(authenticatedAt))
+			Expect(*loaded.LastActivity).To(Equal(lastActivity))
+		})
+
+		It("keeps the client binding", func() {
+			session.Binding = &sessionsapi.ClientBinding{IP: "10.0.0.0/24", UserAgent: "6MBmq4Xpyh5Shgd0M5AVT1N1tR6DnsH3xPnm42Tbd2E"}
+
+			ss := newStore(options.JWTStoreOptions{})
+			session.ExpiresOn = nil
+			tokenString, err := ss.tokenFromSession(session)
+			Expect(err).ToNot(HaveOccurred())
+
+			loaded, err := ss.sessionFromToken(tokenString)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.Binding).To(Equal(session.Binding))
+		})
+
+		DescribeTable("validates the issuer and audience on load",
+			func(signOpts options.JWTStoreOptions, loadOpts options.JWTStoreOptions, expectedError string) {
+				// The session must not be expired for the claims to be checked
+				expiresOn := time.Now().Add(time.Hour)
+				session.ExpiresOn = &expiresOn
+
+				tokenString, err := newStore(signOpts).tokenFromSession(session)
+				Expect(err).ToNot(HaveOccurred())
+
+				loaded, err := newStore(loadOpts).sessionFromToken(tokenString)
+				if expectedError == "" {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(loaded.User).To(Equal("john.doe"))
+				} else {
+					Expect(err).To(MatchError(ContainSubstring(expectedError)))
+				}
+			},
+			Entry("without issuer and audience",
+				options.JWTStoreOptions{},
+				options.JWTStoreOptions{},
+				""),
+			Entry("with the expected issuer and audience",
+				options.JWTStoreOptions{JWTIssuer: "proxy", JWTAudience: "upstream"},
+				options.JWTStoreOptions{JWTIssuer: "proxy", JWTAudience: "upstream"},
+				""),
+			Entry("with another issuer",
+				options.JWTStoreOptions{JWTIssuer: "other"},
+				options.JWTStoreOptions{JWTIssuer: "proxy"},
+				"token has invalid issuer"),
+			Entry("with another audience",
+				options.JWTStoreOptions{JWTAudience: "other"},
+				options.JWTStoreOptions{JWTAudience: "upstream"},
+				"token has invalid audience"),
+			Entry("without the audience",
+				options.JWTStoreOptions{},
+				options.JWTStoreOptions{JWTAudience: "upstream"},
+				"aud claim is required"),
+		)
+
+		It("stores the tokens encrypted", func() {
+			ss := newStore(options.JWTStoreOptions{JWTTokens: true})
+			tokenString, err := ss.tokenFromSession(session)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims := parseClaims(tokenString)
+			Expect(claims.Tokens).ToNot(BeEmpty())
+			Expect(claims.Tokens).ToNot(ContainSubstring("AccessToken"))
+			// The JWT outlives the expired access token so that it can be refreshed
+			Expect(claims.ExpiresAt.Time).To(BeTemporally("~", time.Now().Add(cookieOpts.Expire), time.Minute))
+
+			loaded, err := ss.sessionFromToken(tokenString)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.AccessToken).To(Equal("AccessToken"))
+			Expect(loaded.IDToken).To(Equal("IDToken"))
+			Expect(loaded.RefreshToken).To(Equal("RefreshToken"))
+			Expect(*loaded.ExpiresOn).To(Equal(*session.ExpiresOn))
+			Expect(loaded.IsExpired()).To(BeTrue())
+		})
+
+		It("fails to decrypt the tokens with another cookie secret", func() {
+			tokenString, err := newStore(options.JWTStoreOptions{JWTTokens: true}).tokenFromSession(session)
+			Expect(err).ToNot(HaveOccurred())
+
+			cookieOpts.Secret = "anothersecretthirtytwobytes+abcd"
+			_, err = newStore(options.JWTStoreOptions{JWTTokens: true}).sessionFromToken(tokenString)
+			Expect(err).To(MatchError(ContainSubstring("error decrypting session tokens")))
+		})
+
+		It("decrypts the tokens with a previous cookie secret", func() {
+			tokenString, err := newStore(options.JWTStoreOptions{JWTTokens: true}).tokenFromSession(session)
+			Expect(err).ToNot(HaveOccurred())
+
+			cookieOpts.PreviousSecrets = []string{cookieOpts.Secret}
+			cookieOpts.Secret = "anothersecretthirtytwobytes+abcd"
+			loaded, err := newStore(options.JWTStoreOptions{JWTTokens: true}).sessionFromToken(tokenString)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.AccessToken).To(Equal("AccessToken"))
+		})
+	})
 })
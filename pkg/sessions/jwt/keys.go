@@ -0,0 +1,340 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+)
+
+// Key is a single key used to sign or verify session JWTs
+type Key struct {
+	// ID is the `kid` of the key, derived from its public part
+	ID string
+	// Method is the signing method the key is used with
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds the keys session JWTs are signed and verified with.
+// New tokens are always signed with the active key, while every key in the
+// set is accepted when verifying a token, so that keys can be rotated
+// without invalidating the sessions signed with the previous ones.
+type KeySet struct {
+	active *Key
+	keys   []*Key
+}
+
+// NewKeySet builds a KeySet from the JWT session store options.
+// The key given in jwt-session-key or jwt-session-key-file becomes the active
+// signing key, any jwt-session-verify-key-file is only used for verification.
+// The signing algorithm is taken from jwt-session-algorithm, or derived from
+// the type of the signing key when it is not set.
+func NewKeySet(o options.JWTStoreOptions) (*KeySet, error) {
+	signKeyData, err := loadSigningKeyData(o)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := parseSigningKey(signKeyData, o.JWTAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse jwt session signing key: %v", err)
+	}
+
+	keys := &KeySet{
+		active: active,
+		keys:   []*Key{active},
+	}
+
+	for _, file := range o.JWTVerifyKeyFiles {
+		keyData, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseVerificationKey(keyData, active.Method)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse jwt session verification key %q: %v", file, err)
+		}
+		if keys.Get(key.ID) != nil {
+			// The same key was configured twice, e.g. the active key is still
+			// listed as a verification key after a rotation
+			continue
+		}
+		keys.keys = append(keys.keys, key)
+	}
+
+	return keys, nil
+}
+
+func loadSigningKeyData(o options.JWTStoreOptions) ([]byte, error) {
+	switch {
+	case o.JWTKey != "" && o.JWTKeyFile != "":
+		return nil, errors.New("cannot set both jwt-session-key and jwt-session-key-file options")
+	case o.JWTKey == "" && o.JWTKeyFile == "":
+		return nil, errors.New("jwt session store requires a private key for signing JWTs")
+	case o.JWTKey != "":
+		// The JWT Key is in the commandline argument
+		return []byte(o.JWTKey), nil
+	// o.JWTKeyFile != "":
+	default:
+		// The JWT key is in the filesystem
+		return os.ReadFile(o.JWTKeyFile)
+	}
+}
+
+// signingMethod returns the signing method for an algorithm name,
+// the unsecured `none` algorithm is never accepted
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("unsupported jwt session algorithm %q", alg)
+	}
+	return method, nil
+}
+
+// defaultSigningMethod returns the signing method used with a key when no
+// algorithm is configured
+func defaultSigningMethod(verifyKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := verifyKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", verifyKey)
+}
+
+// checkKeyType ensures a key can be used with the signing method
+func checkKeyType(method jwt.SigningMethod, verifyKey crypto.PublicKey) error {
+	var ok bool
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok = verifyKey.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		key, isECDSA := verifyKey.(*ecdsa.PublicKey)
+		ok = isECDSA && key.Curve.Params().BitSize == m.CurveBits
+	case *jwt.SigningMethodEd25519:
+		_, ok = verifyKey.(ed25519.PublicKey)
+	}
+	if !ok {
+		return fmt.Errorf("key of type %T cannot be used with the %s algorithm", verifyKey, method.Alg())
+	}
+	return nil
+}
+
+func parseSigningKey(keyData []byte, alg string) (*Key, error) {
+	var method jwt.SigningMethod
+	if alg != "" {
+		var err error
+		method, err = signingMethod(alg)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+			return parseSecret(method, keyData)
+		}
+	}
+
+	signKey, err := parsePrivateKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+	verifyKey := signKey.Public()
+
+	if method == nil {
+		method, err = defaultSigningMethod(verifyKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkKeyType(method, verifyKey); err != nil {
+		return nil, err
+	}
+	return newKey(method, signKey, verifyKey)
+}
+
+// parseVerificationKey accepts either a private or a public key, only the
+// public part is kept. The key must be usable with the signing method of the
+// active key, as tokens signed with any other algorithm are rejected.
+func parseVerificationKey(keyData []byte, method jwt.SigningMethod) (*Key, error) {
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		return parseSecret(method, keyData)
+	}
+
+	var verifyKey crypto.PublicKey
+	if signKey, err := parsePrivateKey(keyData); err == nil {
+		verifyKey = signKey.Public()
+	} else {
+		verifyKey, err = parsePublicKey(keyData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkKeyType(method, verifyKey); err != nil {
+		return nil, err
+	}
+	return newKey(method, nil, verifyKey)
+}
+
+// parseSecret uses the key data as the shared secret of an HMAC algorithm.
+// As recommended by RFC 7518, the secret must be at least as long as the
+// output of the hash function.
+func parseSecret(method jwt.SigningMethod, keyData []byte) (*Key, error) {
+	secret := bytes.TrimSpace(keyData)
+	if minLength := method.(*jwt.SigningMethodHMAC).Hash.Size(); len(secret) < minLength {
+		return nil, fmt.Errorf("the %s algorithm requires a secret of at least %d bytes", method.Alg(), minLength)
+	}
+	return newKey(method, secret, secret)
+}
+
+func parsePrivateKey(keyData []byte) (crypto.Signer, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(keyData); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPrivateKeyFromPEM(keyData); err == nil {
+		return key, nil
+	}
+	key, err := jwt.ParseEdPrivateKeyFromPEM(keyData)
+	if err != nil {
+		return nil, errors.New("key must be a PEM encoded RSA, ECDSA or Ed25519 private key")
+	}
+	return key.(crypto.Signer), nil
+}
+
+func parsePublicKey(keyData []byte) (crypto.PublicKey, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(keyData); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(keyData); err == nil {
+		return key, nil
+	}
+	key, err := jwt.ParseEdPublicKeyFromPEM(keyData)
+	if err != nil {
+		return nil, errors.New("key must be a PEM encoded RSA, ECDSA or Ed25519 private or public key")
+	}
+	return key, nil
+}
+
+func newKey(method jwt.SigningMethod, signKey interface{}, verifyKey interface{}) (*Key, error) {
+	kid, err := keyID(verifyKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		ID:        kid,
+		Method:    method,
+		signKey:   signKey,
+		verifyKey: verifyKey,
+	}, nil
+}
+
+// keyID derives the `kid` of a key from its RFC 7638 JWK thumbprint so that
+// it is stable across restarts and replicas sharing the same key
+func keyID(verifyKey interface{}) (string, error) {
+	var thumbprint []byte
+	if secret, ok := verifyKey.([]byte); ok {
+		// go-jose does not compute thumbprints of symmetric keys
+		sum := sha256.Sum256([]byte(fmt.Sprintf(`{"k":%q,"kty":"oct"}`, base64.RawURLEncoding.EncodeToString(secret))))
+		thumbprint = sum[:]
+	} else {
+		var err error
+		jwk := jose.JSONWebKey{Key: verifyKey}
+		thumbprint, err = jwk.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return "", fmt.Errorf("could not compute key thumbprint: %v", err)
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// Active returns the key new tokens are signed with
+func (k *KeySet) Active() *Key {
+	return k.active
+}
+
+// Get returns the key with the given ID or nil if it is not part of the set
+func (k *KeySet) Get(kid string) *Key {
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// Sign creates a JWT from the claims signed with the active key
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.signKey)
+}
+
+// Parse verifies the token with the key identified by its `kid` header and
+// decodes it into the claims. Tokens signed with an algorithm other than the
+// one of the active key are rejected.
+// Tokens without a known `kid` (e.g. issued before key IDs were introduced)
+// are verified against every key of the set.
+func (k *KeySet) Parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if kid, ok := token.Header["kid"].(string); ok {
+			if key := k.Get(kid); key != nil {
+				return key.verifyKey, nil
+			}
+		}
+
+		verifyKeys := make([]jwt.VerificationKey, 0, len(k.keys))
+		for _, key := range k.keys {
+			verifyKeys = append(verifyKeys, key.verifyKey)
+		}
+		return jwt.VerificationKeySet{Keys: verifyKeys}, nil
+	}, append(opts, jwt.WithValidMethods([]string{k.active.Method.Alg()}))...)
+}
+
+// Symmetric returns whether the tokens are signed with a shared secret, in
+// which case there is no public key to publish
+func (k *KeySet) Symmetric() bool {
+	_, ok := k.active.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// JWKS returns the public keys of the set as a JSON Web Key Set so that
+// other services can verify the tokens themselves
+func (k *KeySet) JWKS() jose.JSONWebKeySet {
+	jwks := jose.JSONWebKeySet{}
+	for _, key := range k.keys {
+		if _, ok := key.verifyKey.([]byte); ok {
+			// Shared secrets must never be published
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
+			Key:       key.verifyKey,
+			KeyID:     key.ID,
+			Algorithm: key.Method.Alg(),
+			Use:       "sig",
+		})
+	}
+	return jwks
+}
@@ -0,0 +1,268 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JWT KeySet Tests", func() {
+	var (
+		previousKey     *rsa.PrivateKey
+		previousKeyFile string
+		previousPubFile string
+	)
+
+	BeforeEach(func() {
+		var err error
+		previousKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := GinkgoT().TempDir()
+		previousKeyFile = filepath.Join(dir, "previous.pem")
+		Expect(os.WriteFile(previousKeyFile, pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(previousKey),
+		}), 0600)).To(Succeed())
+
+		pubBytes, err := x509.MarshalPKIXPublicKey(&previousKey.PublicKey)
+		Expect(err).ToNot(HaveOccurred())
+		previousPubFile = filepath.Join(dir, "previous.pub")
+		Expect(os.WriteFile(previousPubFile, pem.EncodeToMemory(&pem.Block{
+			Type:  "PUBLIC KEY",
+			Bytes: pubBytes,
+		}), 0600)).To(Succeed())
+	})
+
+	signWithPreviousKey := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{Subject: "user"})
+		token.Header["kid"] = kid
+		tokenString, err := token.SignedString(previousKey)
+		Expect(err).ToNot(HaveOccurred())
+		return tokenString
+	}
+
+	It("derives a stable key ID from the key", func() {
+		keys, err := NewKeySet(options.JWTStoreOptions{JWTKey: JWTKey})
+		Expect(err).ToNot(HaveOccurred())
+		otherKeys, err := NewKeySet(options.JWTStoreOptions{JWTKey: JWTKey})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(keys.Active().ID).ToNot(BeEmpty())
+		Expect(keys.Active().ID).ToNot(Equal("secret"))
+		Expect(keys.Active().ID).To(Equal(otherKeys.Active().ID))
+	})
+
+	It("signs tokens with the active key ID", func() {
+		keys, err := NewKeySet(options.JWTStoreOptions{JWTKey: JWTKey})
+		Expect(err).ToNot(HaveOccurred())
+
+		tokenString, err := keys.Sign(jwt.RegisteredClaims{Subject: "user"})
+		Expect(err).ToNot(HaveOccurred())
+
+		token, err := keys.Parse(tokenString, &jwt.RegisteredClaims{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.Header["kid"]).To(Equal(keys.Active().ID))
+	})
+
+	DescribeTable("verifies tokens signed with a previous key",
+		func(verifyKeyFile func() string, kid func(*KeySet) string, expectValid bool) {
+			o := options.JWTStoreOptions{JWTKey: JWTKey}
+			if file := verifyKeyFile(); file != "" {
+				o.JWTVerifyKeyFiles = []string{file}
+			}
+			keys, err := NewKeySet(o)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims := &jwt.RegisteredClaims{}
+			_, err = keys.Parse(signWithPreviousKey(kid(keys)), claims)
+			if expectValid {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(claims.Subject).To(Equal("user"))
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("when the previous private key is configured",
+			func() string { return previousKeyFile },
+			func(k *KeySet) string { return k.keys[1].ID },
+			true),
+		Entry("when the previous public key is configured",
+			func() string { return previousPubFile },
+			func(k *KeySet) string { return k.keys[1].ID },
+			true),
+		Entry("when the token has a legacy key ID",
+			func() string { return previousPubFile },
+			func(_ *KeySet) string { return "secret" },
+			true),
+		Entry("when the previous key is not configured",
+			func() string { return "" },
+			func(_ *KeySet) string { return "secret" },
+			false),
+		Entry("when the token claims the active key ID",
+			func() string { return previousPubFile },
+			func(k *KeySet) string { return k.Active().ID },
+			false),
+	)
+
+	It("publishes every public key in the JWKS", func() {
+		keys, err := NewKeySet(options.JWTStoreOptions{
+			JWTKey:            JWTKey,
+			JWTVerifyKeyFiles: []string{previousKeyFile, previousPubFile},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		jwks := keys.JWKS()
+		// The private and public previous key are the same key
+		Expect(jwks.Keys).To(HaveLen(2))
+		for _, jwk := range jwks.Keys {
+			Expect(jwk.IsPublic()).To(BeTrue())
+			Expect(jwk.Algorithm).To(Equal("RS256"))
+			Expect(jwk.Use).To(Equal("sig"))
+			Expect(keys.Get(jwk.KeyID)).ToNot(BeNil())
+		}
+		Expect(jwks.Keys[0].KeyID).To(Equal(keys.Active().ID))
+	})
+
+	It("fails with an invalid verification key file", func() {
+		invalidFile := filepath.Join(GinkgoT().TempDir(), "invalid.pem")
+		Expect(os.WriteFile(invalidFile, []byte("not a key"), 0600)).To(Succeed())
+
+		_, err := NewKeySet(options.JWTStoreOptions{
+			JWTKey:            JWTKey,
+			JWTVerifyKeyFiles: []string{invalidFile},
+		})
+		Expect(err).To(MatchError(ContainSubstring("could not parse jwt session verification key")))
+	})
+
+	Context("with signing algorithms", func() {
+		marshalPrivateKey := func(key interface{}) string {
+			keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+			return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}))
+		}
+
+		generateKey := func(keyType string) string {
+			switch keyType {
+			case "P-256":
+				key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+				return marshalPrivateKey(key)
+			case "P-384":
+				key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+				return marshalPrivateKey(key)
+			case "Ed25519":
+				_, key, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+				return marshalPrivateKey(key)
+			case "secret":
+				return "a shared secret that is long enough for HS256"
+			default:
+				return JWTKey
+			}
+		}
+
+		DescribeTable("signs and verifies tokens",
+			func(keyType string, algorithm string, expectedAlgorithm string) {
+				keys, err := NewKeySet(options.JWTStoreOptions{
+					JWTKey:       generateKey(keyType),
+					JWTAlgorithm: algorithm,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keys.Active().Method.Alg()).To(Equal(expectedAlgorithm))
+				Expect(keys.Symmetric()).To(Equal(keyType == "secret"))
+
+				tokenString, err := keys.Sign(jwt.RegisteredClaims{Subject: "user"})
+				Expect(err).ToNot(HaveOccurred())
+
+				claims := &jwt.RegisteredClaims{}
+				token, err := keys.Parse(tokenString, claims)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(token.Header["alg"]).To(Equal(expectedAlgorithm))
+				Expect(claims.Subject).To(Equal("user"))
+			},
+			Entry("with an RSA key", "RSA", "", "RS256"),
+			Entry("with an RSA key and an explicit algorithm", "RSA", "PS384", "PS384"),
+			Entry("with a P-256 key", "P-256", "", "ES256"),
+			Entry("with a P-384 key", "P-384", "", "ES384"),
+			Entry("with an Ed25519 key", "Ed25519", "", "EdDSA"),
+			Entry("with a shared secret", "secret", "HS256", "HS256"),
+		)
+
+		DescribeTable("rejects invalid configurations",
+			func(keyType string, algorithm string, expectedError string) {
+				_, err := NewKeySet(options.JWTStoreOptions{
+					JWTKey:       generateKey(keyType),
+					JWTAlgorithm: algorithm,
+				})
+				Expect(err).To(MatchError(ContainSubstring(expectedError)))
+			},
+			Entry("with an unknown algorithm", "RSA", "XS256", `unsupported jwt session algorithm "XS256"`),
+			Entry("with the none algorithm", "RSA", "none", `unsupported jwt session algorithm "none"`),
+			Entry("with an RSA key for ES256", "RSA", "ES256", "cannot be used with the ES256 algorithm"),
+			Entry("with a P-256 key for ES384", "P-256", "ES384", "cannot be used with the ES384 algorithm"),
+			Entry("with a secret without algorithm", "secret", "", "key must be a PEM encoded"),
+			Entry("with a short secret", "secret", "HS512", "requires a secret of at least 64 bytes"),
+		)
+
+		It("rejects verification keys of another type", func() {
+			_, err := NewKeySet(options.JWTStoreOptions{
+				JWTKey:            generateKey("P-256"),
+				JWTVerifyKeyFiles: []string{previousKeyFile},
+			})
+			Expect(err).To(MatchError(ContainSubstring("cannot be used with the ES256 algorithm")))
+		})
+
+		It("does not publish shared secrets", func() {
+			keys, err := NewKeySet(options.JWTStoreOptions{
+				JWTKey:       generateKey("secret"),
+				JWTAlgorithm: "HS256",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys.JWKS().Keys).To(BeEmpty())
+		})
+
+		It("rejects tokens signed with another algorithm", func() {
+			keys, err := NewKeySet(options.JWTStoreOptions{JWTKey: JWTKey})
+			Expect(err).ToNot(HaveOccurred())
+
+			signKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(JWTKey))
+			Expect(err).ToNot(HaveOccurred())
+			token := jwt.NewWithClaims(jwt.SigningMethodRS512, jwt.RegisteredClaims{Subject: "user"})
+			token.Header["kid"] = keys.Active().ID
+			tokenString, err := token.SignedString(signKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = keys.Parse(tokenString, &jwt.RegisteredClaims{})
+			Expect(err).To(MatchError(ContainSubstring("signing method RS512 is invalid")))
+		})
+
+		It("rejects tokens signed with the public key as HMAC secret", func() {
+			keys, err := NewKeySet(options.JWTStoreOptions{JWTKey: JWTKey})
+			Expect(err).ToNot(HaveOccurred())
+
+			jwks := keys.JWKS()
+			publicKey, err := x509.MarshalPKIXPublicKey(jwks.Keys[0].Key)
+			Expect(err).ToNot(HaveOccurred())
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "user"})
+			token.Header["kid"] = keys.Active().ID
+			tokenString, err := token.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = keys.Parse(tokenString, &jwt.RegisteredClaims{})
+			Expect(err).To(MatchError(ContainSubstring("signing method HS256 is invalid")))
+		})
+	})
+})
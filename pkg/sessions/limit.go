@@ -0,0 +1,65 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+)
+
+// ErrSessionLimitReached is returned when a user already has the maximum
+// number of concurrent sessions and their new session is rejected
+var ErrSessionLimitReached = errors.New("maximum number of concurrent sessions reached")
+
+// LimitUserSessions makes room for the session about to be saved for the
+// user of the request, so that the user has at most limit sessions.
+// When evict is true, the earliest created sessions of the user are
+// cleared and their IDs returned, otherwise ErrSessionLimitReached is
+// returned. The session of the request itself, reused when saving, is not
+// counted. Stores that cannot find the sessions of a user are not limited.
+func LimitUserSessions(req *http.Request, store sessions.SessionStore, user string, limit int, evict bool) ([]string, error) {
+	userStore, ok := store.(sessions.UserSessionStore)
+	if !ok || limit <= 0 {
+		return nil, nil
+	}
+
+	ids, err := otherUserSessionIDs(req, userStore, user)
+	if err != nil {
+		return nil, err
+	}
+	// The new session takes one of the places
+	excess := len(ids) - limit + 1
+	if excess <= 0 {
+		return nil, nil
+	}
+	if !evict {
+		return nil, ErrSessionLimitReached
+	}
+
+	evicted := ids[:excess]
+	for _, id := range evicted {
+		if err := userStore.ClearUserSession(req.Context(), user, id); err != nil {
+			return nil, fmt.Errorf("error evicting a session of the user: %v", err)
+		}
+	}
+	return evicted, nil
+}
+
+// otherUserSessionIDs returns the IDs of the sessions of the user, the
+// earliest created first, other than the session of the request
+func otherUserSessionIDs(req *http.Request, store sessions.UserSessionStore, user string) ([]string, error) {
+	ids, err := store.UserSessionIDs(req.Context(), user)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sessions of the user: %v", err)
+	}
+
+	current := store.SessionID(req)
+	others := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != current {
+			others = append(others, id)
+		}
+	}
+	return others, nil
+}
@@ -0,0 +1,121 @@
+package sessions_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/tests"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LimitUserSessions", func() {
+	var mockStore *tests.MockStore
+	var store *persistence.Manager
+	// requests hold the cookies of the sessions of the user, oldest first
+	var requests []*http.Request
+	// created is the creation time of the next session saved
+	var firstCreated, created time.Time
+
+	saveSession := func(req *http.Request, user string) *http.Request {
+		rw := httptest.NewRecorder()
+		createdAt := created
+		Expect(store.Save(rw, req, &sessionsapi.SessionState{User: user, CreatedAt: &createdAt})).To(Succeed())
+
+		sessionReq := httptest.NewRequest("GET", "http://example.com/", nil)
+		for _, c := range rw.Result().Cookies() {
+			sessionReq.AddCookie(c)
+		}
+		return sessionReq
+	}
+
+	newRequest := func() *http.Request {
+		return httptest.NewRequest("GET", "http://example.com/oauth2/callback", nil)
+	}
+
+	BeforeEach(func() {
+		mockStore = tests.NewMockStore()
+		store = persistence.NewManager(mockStore, &options.Cookie{
+			Name:   "_oauth2_proxy",
+			Secret: "0123456789abcdefghijklmnopqrstuv",
+			Expire: time.Hour,
+		})
+
+		requests = nil
+		firstCreated = time.Now()
+		created = firstCreated
+		for i := 0; i < 3; i++ {
+			requests = append(requests, saveSession(newRequest(), "john.doe"))
+			mockStore.FastForward(time.Minute)
+			created = created.Add(time.Minute)
+		}
+		saveSession(newRequest(), "jane.doe")
+	})
+
+	loaded := func(req *http.Request) bool {
+		session, err := store.Load(req)
+		return err == nil && session != nil
+	}
+
+	It("does nothing below the limit", func() {
+		evicted, err := sessions.LimitUserSessions(newRequest(), store, "john.doe", 4, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evicted).To(BeEmpty())
+		for _, req := range requests {
+			Expect(loaded(req)).To(BeTrue())
+		}
+	})
+
+	It("evicts the oldest sessions of the user at the limit", func() {
+		evicted, err := sessions.LimitUserSessions(newRequest(), store, "john.doe", 2, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evicted).To(ConsistOf(store.SessionID(requests[0]), store.SessionID(requests[1])))
+
+		Expect(loaded(requests[0])).To(BeFalse())
+		Expect(loaded(requests[1])).To(BeFalse())
+		Expect(loaded(requests[2])).To(BeTrue())
+		Expect(store.UserSessionIDs(requests[2].Context(), "john.doe")).To(ConsistOf(store.SessionID(requests[2])))
+		Expect(store.UserSessionIDs(requests[2].Context(), "jane.doe")).To(HaveLen(1))
+	})
+
+	It("evicts the earliest created sessions of the user even after they were saved again", func() {
+		// Refreshing a session saves it again, with its creation time
+		created = firstCreated
+		saveSession(requests[0], "john.doe")
+
+		evicted, err := sessions.LimitUserSessions(newRequest(), store, "john.doe", 3, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evicted).To(Equal([]string{store.SessionID(requests[0])}))
+		Expect(loaded(requests[0])).To(BeFalse())
+		Expect(loaded(requests[1])).To(BeTrue())
+	})
+
+	It("rejects the new session at the limit", func() {
+		evicted, err := sessions.LimitUserSessions(newRequest(), store, "john.doe", 3, false)
+		Expect(err).To(Equal(sessions.ErrSessionLimitReached))
+		Expect(evicted).To(BeEmpty())
+		for _, req := range requests {
+			Expect(loaded(req)).To(BeTrue())
+		}
+	})
+
+	It("does not count the session of the request, saved again", func() {
+		evicted, err := sessions.LimitUserSessions(requests[0], store, "john.doe", 3, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evicted).To(BeEmpty())
+	})
+
+	It("does not limit stores that cannot find the sessions of a user", func() {
+		cookieStore, err := sessions.NewSessionStore(&options.SessionOptions{Type: options.CookieSessionStoreType}, store.Options)
+		Expect(err).ToNot(HaveOccurred())
+
+		evicted, err := sessions.LimitUserSessions(newRequest(), cookieStore, "john.doe", 1, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(evicted).To(BeEmpty())
+	})
+})
@@ -17,3 +17,26 @@ type Store interface {
 	Lock(key string) sessions.Lock
 	VerifyConnection(context.Context) error
 }
+
+// UserIndex is implemented by the Stores that keep an index of the session
+// tickets of each user, allowing the persistence.Manager to clear every
+// session of a user.
+// The tickets are removed from the index when they expire.
+type UserIndex interface {
+	// AddUserTicket adds the ticket of a session created at createdAt to the
+	// index of the user for the expiration
+	AddUserTicket(ctx context.Context, user string, ticketID string, createdAt time.Time, exp time.Duration) error
+	// UserTickets returns the tickets of the user that have not expired, the
+	// earliest created first
+	UserTickets(ctx context.Context, user string) ([]string, error)
+	// RemoveUserTickets removes the tickets from the index of the user
+	RemoveUserTickets(ctx context.Context, user string, ticketIDs ...string) error
+}
+
+// Lister is implemented by the Stores that can list the keys they hold,
+// allowing the persistence.Manager to list the sessions for their
+// administration.
+type Lister interface {
+	// List returns the keys starting with the prefix that have not expired
+	List(ctx context.Context, prefix string) ([]string, error)
+}
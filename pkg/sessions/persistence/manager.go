@@ -3,6 +3,7 @@ package persistence
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -49,6 +50,16 @@ func (m *Manager) Save(rw http.ResponseWriter, req *http.Request, s *sessions.Se
 		return err
 	}
 
+	if index, ok := m.Store.(UserIndex); ok && s.User != "" {
+		if err := index.AddUserTicket(req.Context(), s.User, tckt.id, *s.CreatedAt, m.Options.Expire); err != nil {
+			return fmt.Errorf("error indexing the session of the user: %v", err)
+		}
+	}
+
+	if err := m.saveMetadata(req, tckt.id, s); err != nil {
+		return err
+	}
+
 	return tckt.setCookie(rw, req, s)
 }
 
@@ -87,12 +98,103 @@ func (m *Manager) Clear(rw http.ResponseWriter, req *http.Request) error {
 	}
 
 	tckt.clearCookie(rw, req)
-	return tckt.clearSession(func(key string) error {
+
+	// The session is loaded to find the user index the ticket is in
+	index, indexed := m.Store.(UserIndex)
+	var user string
+	if indexed {
+		if s, err := m.Load(req); err == nil {
+			user = s.User
+		}
+	}
+
+	err = tckt.clearSession(func(key string) error {
 		return m.Store.Clear(req.Context(), key)
 	})
+	if err != nil {
+		return err
+	}
+	if err := m.clearMetadata(req.Context(), tckt.id); err != nil {
+		return err
+	}
+
+	if user != "" {
+		if err := index.RemoveUserTickets(req.Context(), user, tckt.id); err != nil {
+			return fmt.Errorf("error removing the session from the user index: %v", err)
+		}
+	}
+	return nil
+}
+
+// ClearUserSessions clears every session of the user found in the user index
+// of the Store. Stores without a user index have no sessions to clear.
+func (m *Manager) ClearUserSessions(ctx context.Context, user string) error {
+	ticketIDs, err := m.UserSessionIDs(ctx, user)
+	if err != nil {
+		return err
+	}
+	for _, ticketID := range ticketIDs {
+		if err := m.ClearUserSession(ctx, user, ticketID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserSessionIDs returns the tickets of the user in the user index of the
+// Store, the earliest created first. Stores without a user index have no
+// sessions to list.
+func (m *Manager) UserSessionIDs(ctx context.Context, user string) ([]string, error) {
+	index, ok := m.Store.(UserIndex)
+	if !ok {
+		return []string{}, nil
+	}
+
+	ticketIDs, err := index.UserTickets(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sessions of the user: %v", err)
+	}
+	return ticketIDs, nil
+}
+
+// ClearUserSession clears the session of the ticket, along with its metadata
+// and its entry in the user index
+func (m *Manager) ClearUserSession(ctx context.Context, user string, ticketID string) error {
+	if err := m.Store.Clear(ctx, ticketID); err != nil {
+		return err
+	}
+	if err := m.clearMetadata(ctx, ticketID); err != nil {
+		return err
+	}
+
+	if index, ok := m.Store.(UserIndex); ok {
+		if err := index.RemoveUserTickets(ctx, user, ticketID); err != nil {
+			return fmt.Errorf("error removing the session from the user index: %v", err)
+		}
+	}
+	return nil
+}
+
+// SessionID returns the ticket of the session cookie of the request, or an
+// empty string when it has none
+func (m *Manager) SessionID(req *http.Request) string {
+	tckt, err := decodeTicketFromRequest(req, m.Options)
+	if err != nil {
+		return ""
+	}
+	return tckt.id
 }
 
 // VerifyConnection validates the underlying store is ready and connected
 func (m *Manager) VerifyConnection(ctx context.Context) error {
 	return m.Store.VerifyConnection(ctx)
 }
+
+// Close releases the underlying store when it holds resources, such as the
+// database of the file and sql stores
+func (m *Manager) Close() error {
+	if closer, ok := m.Store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
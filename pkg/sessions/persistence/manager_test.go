@@ -1,12 +1,15 @@
 package persistence
 
 import (
+	"net/http/httptest"
 	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/encryption"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/tests"
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("Persistence Manager Tests", func() {
@@ -23,3 +26,104 @@ var _ = Describe("Persistence Manager Tests", func() {
 			return nil
 		})
 })
+
+var _ = Describe("Persistence Manager Close", func() {
+	It("closes the store", func() {
+		store := &closedStore{MockStore: tests.NewMockStore()}
+		Expect(NewManager(store, &options.Cookie{}).Close()).To(Succeed())
+		Expect(store.closed).To(BeTrue())
+	})
+
+	It("ignores the stores without resources to release", func() {
+		Expect(NewManager(tests.NewMockStore(), &options.Cookie{}).Close()).To(Succeed())
+	})
+})
+
+type closedStore struct {
+	*tests.MockStore
+	closed bool
+}
+
+func (s *closedStore) Close() error {
+	s.closed = true
+	return nil
+}
+
+var _ = Describe("Persistence Manager with a rotated cookie secret", func() {
+	It("loads the sessions signed with the previous secret and signs them with the new one when saved", func() {
+		ms := tests.NewMockStore()
+		previousOpts := &options.Cookie{
+			Name:   "_oauth2_proxy",
+			Secret: "0123456789abcdefghijklmnopqrstuv",
+			Expire: time.Hour,
+		}
+		rotatedOpts := &options.Cookie{
+			Name:            "_oauth2_proxy",
+			Secret:          "vutsrqponmlkjihgfedcba9876543210",
+			PreviousSecrets: []string{previousOpts.Secret},
+			Expire:          time.Hour,
+		}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		Expect(NewManager(ms, previousOpts).Save(rw, req, &sessionsapi.SessionState{User: "john.doe"})).To(Succeed())
+
+		sessionReq := httptest.NewRequest("GET", "http://example.com/", nil)
+		for _, c := range rw.Result().Cookies() {
+			sessionReq.AddCookie(c)
+		}
+		rotated := NewManager(ms, rotatedOpts)
+		session, err := rotated.Load(sessionReq)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(session.User).To(Equal("john.doe"))
+
+		rw = httptest.NewRecorder()
+		Expect(rotated.Save(rw, sessionReq, session)).To(Succeed())
+		cookies := rw.Result().Cookies()
+		Expect(cookies).To(HaveLen(1))
+		_, _, ok := encryption.Validate(cookies[0], rotatedOpts.Secret, rotatedOpts.Expire)
+		Expect(ok).To(BeTrue())
+		Expect(rotated.SessionID(sessionReq)).ToNot(BeEmpty())
+	})
+})
+
+var _ = Describe("Persistence Manager session metadata", func() {
+	It("encrypts the metadata with the cookie secret and decrypts it after a rotation", func() {
+		ms := tests.NewMockStore()
+		previousOpts := &options.Cookie{
+			Name:   "_oauth2_proxy",
+			Secret: "0123456789abcdefghijklmnopqrstuv",
+			Expire: time.Hour,
+		}
+		rotatedOpts := &options.Cookie{
+			Name:            "_oauth2_proxy",
+			Secret:          "vutsrqponmlkjihgfedcba9876543210",
+			PreviousSecrets: []string{previousOpts.Secret},
+			Expire:          time.Hour,
+		}
+
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.Header.Set("User-Agent", "test-agent")
+		Expect(NewManager(ms, previousOpts).Save(httptest.NewRecorder(), req, &sessionsapi.SessionState{
+			User:  "john.doe",
+			Email: "john.doe@example.com",
+		})).To(Succeed())
+
+		keys, err := ms.List(req.Context(), metadataKeyPrefix)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(HaveLen(1))
+		value, err := ms.Load(req.Context(), keys[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(value)).ToNot(ContainSubstring("john.doe"))
+		Expect(string(value)).ToNot(ContainSubstring("test-agent"))
+
+		list, err := NewManager(ms, rotatedOpts).ListSessions(req.Context())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Email).To(Equal("john.doe@example.com"))
+
+		list, err = NewManager(ms, &options.Cookie{Name: "_oauth2_proxy", Secret: "abcdefghijklmnopqrstuv0123456789"}).ListSessions(req.Context())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list).To(BeEmpty())
+	})
+})
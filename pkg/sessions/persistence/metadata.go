@@ -0,0 +1,180 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/encryption"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+)
+
+// metadataKeyPrefix prefixes the keys of the session metadata. The sessions
+// are encrypted with the secret of their ticket, only known by the client, so
+// the metadata needed to administrate them is stored aside, encrypted with the
+// cookie secret.
+const metadataKeyPrefix = "oauth2-proxy-session-metadata-"
+
+var _ sessions.SessionAdmin = (*Manager)(nil)
+
+// errListNotSupported is returned when the Store does not implement Lister
+var errListNotSupported = errors.New("the session store cannot list its sessions")
+
+// ListSessions returns the metadata of every session in the Store, ordered by
+// creation
+func (m *Manager) ListSessions(ctx context.Context) ([]sessions.SessionMetadata, error) {
+	lister, ok := m.Store.(Lister)
+	if !ok {
+		return nil, errListNotSupported
+	}
+
+	keys, err := lister.List(ctx, metadataKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sessions: %v", err)
+	}
+
+	list := make([]sessions.SessionMetadata, 0, len(keys))
+	for _, key := range keys {
+		metadata, err := m.loadMetadata(ctx, strings.TrimPrefix(key, metadataKeyPrefix))
+		if err != nil {
+			// The session may have expired or been cleared since it was listed
+			continue
+		}
+		list = append(list, *metadata)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt != nil && (list[j].CreatedAt == nil || list[i].CreatedAt.Before(*list[j].CreatedAt))
+	})
+	return list, nil
+}
+
+// ClearSession clears the session with the given ticket ID, along with its
+// metadata and user index entry
+func (m *Manager) ClearSession(ctx context.Context, id string) error {
+	if _, ok := m.Store.(Lister); !ok {
+		return errListNotSupported
+	}
+
+	// Only the IDs of sessions with metadata are cleared, so that the ID
+	// cannot be used to delete any other key of the Store
+	metadata, err := m.loadMetadata(ctx, id)
+	if err != nil {
+		return sessions.ErrSessionNotFound
+	}
+
+	if err := m.Store.Clear(ctx, id); err != nil {
+		return err
+	}
+	if err := m.Store.Clear(ctx, metadataKey(id)); err != nil {
+		return err
+	}
+
+	if index, ok := m.Store.(UserIndex); ok && metadata.User != "" {
+		if err := index.RemoveUserTickets(ctx, metadata.User, id); err != nil {
+			return fmt.Errorf("error removing the session from the user index: %v", err)
+		}
+	}
+	return nil
+}
+
+// saveMetadata stores the metadata of the session of the ticket, when the
+// Store can list them. The client IP and user agent are the ones of the last
+// request saving the session.
+func (m *Manager) saveMetadata(req *http.Request, ticketID string, s *sessions.SessionState) error {
+	if _, ok := m.Store.(Lister); !ok {
+		return nil
+	}
+
+	metadata := sessions.SessionMetadata{
+		ID:        ticketID,
+		User:      s.User,
+		Email:     s.Email,
+		Tenant:    s.Tenant,
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: time.Now().Add(m.Options.Expire),
+		ClientIP:  logger.GetClient(req),
+		UserAgent: req.UserAgent(),
+	}
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("error encoding the session metadata: %v", err)
+	}
+	ciphers, err := m.metadataCiphers()
+	if err != nil {
+		return err
+	}
+	value, err = ciphers[0].Encrypt(value)
+	if err != nil {
+		return fmt.Errorf("error encrypting the session metadata: %v", err)
+	}
+
+	if err := m.Store.Save(req.Context(), metadataKey(ticketID), value, m.Options.Expire); err != nil {
+		return fmt.Errorf("error saving the session metadata: %v", err)
+	}
+	return nil
+}
+
+func (m *Manager) loadMetadata(ctx context.Context, ticketID string) (*sessions.SessionMetadata, error) {
+	value, err := m.Store.Load(ctx, metadataKey(ticketID))
+	if err != nil {
+		return nil, err
+	}
+	ciphers, err := m.metadataCiphers()
+	if err != nil {
+		return nil, err
+	}
+
+	// The metadata saved before the cookie secret was rotated is encrypted
+	// with a previous secret
+	for _, c := range ciphers {
+		plaintext, err := c.Decrypt(value)
+		if err != nil {
+			continue
+		}
+		metadata := &sessions.SessionMetadata{}
+		if err := json.Unmarshal(plaintext, metadata); err != nil {
+			return nil, fmt.Errorf("error decoding the session metadata: %v", err)
+		}
+		return metadata, nil
+	}
+	return nil, errors.New("error decrypting the session metadata")
+}
+
+// metadataCiphers makes the AES-GCM ciphers of the cookie secrets, the first
+// one encrypts the metadata
+func (m *Manager) metadataCiphers() ([]encryption.Cipher, error) {
+	secrets, err := m.Options.GetSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("error getting cookie secret: %v", err)
+	}
+
+	ciphers := make([]encryption.Cipher, 0, len(secrets))
+	for _, secret := range secrets {
+		c, err := encryption.NewGCMCipher(encryption.SecretBytes(secret))
+		if err != nil {
+			return nil, fmt.Errorf("error initialising cipher: %v", err)
+		}
+		ciphers = append(ciphers, c)
+	}
+	return ciphers, nil
+}
+
+// clearMetadata clears the metadata of the session of the ticket, when the
+// Store can list them
+func (m *Manager) clearMetadata(ctx context.Context, ticketID string) error {
+	if _, ok := m.Store.(Lister); !ok {
+		return nil
+	}
+	return m.Store.Clear(ctx, metadataKey(ticketID))
+}
+
+func metadataKey(ticketID string) string {
+	return metadataKeyPrefix + ticketID
+}
@@ -145,12 +145,14 @@ func decodeTicketFromRequest(req *http.Request, cookieOpts *options.Cookie) (*ti
 		return nil, err
 	}
 
-	// An existing cookie exists, try to retrieve the ticket
-	secret, err := cookieOpts.GetSecret()
+	// An existing cookie exists, try to retrieve the ticket. The cookies
+	// signed with a previous secret are signed with the cookie secret again
+	// when the session is saved.
+	secrets, err := cookieOpts.GetSecrets()
 	if err != nil {
 		return nil, fmt.Errorf("error getting cookie secret: %v", err)
 	}
-	val, _, ok := encryption.Validate(requestCookie, secret, cookieOpts.Expire)
+	val, _, _, ok := encryption.ValidateAny(requestCookie, secrets, cookieOpts.Expire)
 	if !ok {
 		return nil, fmt.Errorf("session ticket cookie failed validation: %v", err)
 	}
@@ -2,12 +2,16 @@ package redis
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	"github.com/redis/go-redis/v9"
 )
 
+// scanCount is the number of keys examined by each SCAN call
+const scanCount = 100
+
 // Client is wrapper interface for redis.Client and redis.ClusterClient.
 type Client interface {
 	Get(ctx context.Context, key string) ([]byte, error)
@@ -15,6 +19,12 @@ type Client interface {
 	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
 	Del(ctx context.Context, key string) error
 	Ping(ctx context.Context) error
+	ZAdd(ctx context.Context, key string, member string, score float64) error
+	ZRangeByScore(ctx context.Context, key string, min string, max string) ([]string, error)
+	ZRem(ctx context.Context, key string, members ...string) error
+	ZRemRangeByScore(ctx context.Context, key string, min string, max string) error
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	Scan(ctx context.Context, match string) ([]string, error)
 }
 
 var _ Client = (*client)(nil)
@@ -49,6 +59,34 @@ func (c *client) Ping(ctx context.Context) error {
 	return c.Client.Ping(ctx).Err()
 }
 
+func (c *client) ZAdd(ctx context.Context, key string, member string, score float64) error {
+	return c.Client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+func (c *client) ZRangeByScore(ctx context.Context, key string, min string, max string) ([]string, error) {
+	return c.Client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+}
+
+func (c *client) ZRem(ctx context.Context, key string, members ...string) error {
+	values := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		values = append(values, member)
+	}
+	return c.Client.ZRem(ctx, key, values...).Err()
+}
+
+func (c *client) ZRemRangeByScore(ctx context.Context, key string, min string, max string) error {
+	return c.Client.ZRemRangeByScore(ctx, key, min, max).Err()
+}
+
+func (c *client) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return c.Client.Expire(ctx, key, expiration).Err()
+}
+
+func (c *client) Scan(ctx context.Context, match string) ([]string, error) {
+	return scan(ctx, c.Client, match)
+}
+
 var _ Client = (*clusterClient)(nil)
 
 type clusterClient struct {
@@ -80,3 +118,55 @@ func (c *clusterClient) Lock(key string) sessions.Lock {
 func (c *clusterClient) Ping(ctx context.Context) error {
 	return c.ClusterClient.Ping(ctx).Err()
 }
+
+func (c *clusterClient) ZAdd(ctx context.Context, key string, member string, score float64) error {
+	return c.ClusterClient.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+func (c *clusterClient) ZRangeByScore(ctx context.Context, key string, min string, max string) ([]string, error) {
+	return c.ClusterClient.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+}
+
+func (c *clusterClient) ZRem(ctx context.Context, key string, members ...string) error {
+	values := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		values = append(values, member)
+	}
+	return c.ClusterClient.ZRem(ctx, key, values...).Err()
+}
+
+func (c *clusterClient) ZRemRangeByScore(ctx context.Context, key string, min string, max string) error {
+	return c.ClusterClient.ZRemRangeByScore(ctx, key, min, max).Err()
+}
+
+func (c *clusterClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return c.ClusterClient.Expire(ctx, key, expiration).Err()
+}
+
+// Scan scans every primary node, as the keys are spread over the cluster
+func (c *clusterClient) Scan(ctx context.Context, match string) ([]string, error) {
+	var mu sync.Mutex
+	keys := []string{}
+	err := c.ClusterClient.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		nodeKeys, err := scan(ctx, node, match)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, nodeKeys...)
+		return nil
+	})
+	return keys, err
+}
+
+// scan iterates over the keys matching the pattern with SCAN, which unlike
+// KEYS does not block the server
+func scan(ctx context.Context, c *redis.Client, match string) ([]string, error) {
+	keys := []string{}
+	iter := c.Scan(ctx, 0, match, scanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
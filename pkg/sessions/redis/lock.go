@@ -8,6 +8,7 @@ import (
 
 	"github.com/bsm/redislock"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -32,7 +33,9 @@ func NewLock(client redis.Cmdable, key string) sessions.Lock {
 
 // Obtain obtains a distributed lock on Redis for the configured key.
 func (l *Lock) Obtain(ctx context.Context, expiration time.Duration) error {
+	ctx, span := startSpan(ctx, "lock.obtain")
 	lock, err := l.locker.Obtain(ctx, l.lockKey(), expiration, nil)
+	tracing.EndSpan(span, err)
 	if errors.Is(err, redislock.ErrNotObtained) {
 		return sessions.ErrLockNotObtained
 	}
@@ -48,7 +51,9 @@ func (l *Lock) Refresh(ctx context.Context, expiration time.Duration) error {
 	if l.lock == nil {
 		return sessions.ErrNotLocked
 	}
+	ctx, span := startSpan(ctx, "lock.refresh")
 	err := l.lock.Refresh(ctx, expiration, nil)
+	tracing.EndSpan(span, err)
 	if errors.Is(err, redislock.ErrNotObtained) {
 		return sessions.ErrNotLocked
 	}
@@ -72,7 +77,9 @@ func (l *Lock) Release(ctx context.Context) error {
 	if l.lock == nil {
 		return sessions.ErrNotLocked
 	}
+	ctx, span := startSpan(ctx, "lock.release")
 	err := l.lock.Release(ctx)
+	tracing.EndSpan(span, err)
 	if errors.Is(err, redislock.ErrLockNotHeld) {
 		return sessions.ErrNotLocked
 	}
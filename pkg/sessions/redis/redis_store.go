@@ -6,15 +6,21 @@ import (
 	"crypto/x509"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var _ persistence.Lister = (*SessionStore)(nil)
+
 // SessionStore is an implementation of the persistence.Store
 // interface that stores sessions in redis
 type SessionStore struct {
@@ -38,7 +44,9 @@ func NewRedisSessionStore(opts *options.SessionOptions, cookieOpts *options.Cook
 // Save takes a sessions.SessionState and stores the information from it
 // to redis, and adds a new persistence cookie on the HTTP response writer
 func (store *SessionStore) Save(ctx context.Context, key string, value []byte, exp time.Duration) error {
+	ctx, span := startSpan(ctx, "save")
 	err := store.Client.Set(ctx, key, value, exp)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("error saving redis session: %v", err)
 	}
@@ -48,10 +56,14 @@ func (store *SessionStore) Save(ctx context.Context, key string, value []byte, e
 // Load reads sessions.SessionState information from a persistence
 // cookie within the HTTP request object
 func (store *SessionStore) Load(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := startSpan(ctx, "load")
 	value, err := store.Client.Get(ctx, key)
 	if err == redis.Nil {
+		span.End()
 		return nil, fmt.Errorf("session does not exist")
-	} else if err != nil {
+	}
+	tracing.EndSpan(span, err)
+	if err != nil {
 		return nil, fmt.Errorf("error loading redis session: %v", err)
 	}
 
@@ -61,13 +73,26 @@ func (store *SessionStore) Load(ctx context.Context, key string) ([]byte, error)
 // Clear clears any saved session information for a given persistence cookie
 // from redis, and then clears the session
 func (store *SessionStore) Clear(ctx context.Context, key string) error {
+	ctx, span := startSpan(ctx, "clear")
 	err := store.Client.Del(ctx, key)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("error clearing the session from redis: %v", err)
 	}
 	return nil
 }
 
+// List returns the keys starting with the prefix
+func (store *SessionStore) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, span := startSpan(ctx, "list")
+	keys, err := store.Client.Scan(ctx, escapePattern(prefix)+"*")
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the redis keys: %v", err)
+	}
+	return keys, nil
+}
+
 // Lock creates a lock object for sessions.SessionState
 func (store *SessionStore) Lock(key string) sessions.Lock {
 	return store.Client.Lock(key)
@@ -79,6 +104,18 @@ func (store *SessionStore) VerifyConnection(ctx context.Context) error {
 	return store.Client.Ping(ctx)
 }
 
+// escapePattern escapes the special characters of redis glob-style patterns
+func escapePattern(s string) string {
+	return patternEscaper.Replace(s)
+}
+
+var patternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// startSpan starts a span tracing a redis operation of the session store
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.StartClientSpan(ctx, "redis."+operation, attribute.String("db.system", "redis"))
+}
+
 // NewRedisClient makes a redis.Client (either standalone, sentinel aware, or
 // redis cluster)
 func NewRedisClient(opts options.RedisStoreOptions) (Client, error) {
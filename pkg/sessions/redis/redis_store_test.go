@@ -1,6 +1,7 @@
 package redis
 
 import (
+	"context"
 	"time"
 
 	"github.com/Bose/minisentinel"
@@ -245,6 +246,98 @@ var _ = Describe("Redis SessionStore Tests", func() {
 		})
 	})
 
+	Describe("Redis RevocationList", func() {
+		var mr *miniredis.Miniredis
+		var client Client
+
+		BeforeEach(func() {
+			var err error
+			mr, err = miniredis.Run()
+			Expect(err).ToNot(HaveOccurred())
+			client, err = NewRedisClient(options.RedisStoreOptions{ConnectionURL: "redis://" + mr.Addr()})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			mr.Close()
+		})
+
+		tests.RunRevocationListTests(
+			func() sessionsapi.RevocationList {
+				return &SessionStore{Client: client}
+			},
+			func(d time.Duration) error {
+				mr.FastForward(d)
+				return nil
+			},
+		)
+	})
+
+	Describe("Redis UserIndex", func() {
+		var mr *miniredis.Miniredis
+		var store *SessionStore
+		ctx := context.Background()
+
+		BeforeEach(func() {
+			var err error
+			mr, err = miniredis.Run()
+			Expect(err).ToNot(HaveOccurred())
+			client, err := NewRedisClient(options.RedisStoreOptions{ConnectionURL: "redis://" + mr.Addr()})
+			Expect(err).ToNot(HaveOccurred())
+			store = &SessionStore{Client: client}
+		})
+
+		AfterEach(func() {
+			mr.Close()
+		})
+
+		It("lists the tickets added to the index of the user", func() {
+			Expect(store.AddUserTicket(ctx, "user", "ticket-1", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "ticket-2", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "other", "ticket-3", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.UserTickets(ctx, "user")).To(ConsistOf("ticket-1", "ticket-2"))
+		})
+
+		It("lists the tickets the earliest created first", func() {
+			created := time.Now()
+			Expect(store.AddUserTicket(ctx, "user", "ticket-1", created.Add(time.Minute), time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "ticket-2", created, time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "ticket-1", created.Add(time.Minute), 2*time.Hour)).To(Succeed())
+			Expect(store.UserTickets(ctx, "user")).To(Equal([]string{"ticket-2", "ticket-1"}))
+		})
+
+		It("lists the tickets indexed without their creation first", func() {
+			Expect(store.AddUserTicket(ctx, "user", "ticket-1", time.Now(), time.Hour)).To(Succeed())
+			_, err := mr.ZAdd(userSessionsKey("user"), float64(time.Now().Add(time.Hour).Unix()), "ticket-2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(store.UserTickets(ctx, "user")).To(Equal([]string{"ticket-2", "ticket-1"}))
+		})
+
+		It("removes the tickets from the index", func() {
+			Expect(store.AddUserTicket(ctx, "user", "ticket-1", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "ticket-2", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.RemoveUserTickets(ctx, "user", "ticket-1")).To(Succeed())
+			Expect(store.UserTickets(ctx, "user")).To(ConsistOf("ticket-2"))
+		})
+
+		It("does not list the expired tickets", func() {
+			_, err := mr.ZAdd(userSessionsKey("user"), float64(time.Now().Add(-time.Minute).Unix()), "ticket-1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(store.UserTickets(ctx, "user")).To(BeEmpty())
+
+			Expect(store.AddUserTicket(ctx, "user", "ticket-2", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.UserTickets(ctx, "user")).To(ConsistOf("ticket-2"))
+			Expect(mr.ZMembers(userSessionsKey("user"))).To(ConsistOf("ticket-2"))
+			Expect(mr.ZMembers(userSessionsCreatedKey("user"))).To(ConsistOf("ticket-2"))
+		})
+
+		It("expires the index with its last ticket", func() {
+			Expect(store.AddUserTicket(ctx, "user", "ticket-1", time.Now(), time.Hour)).To(Succeed())
+			mr.FastForward(2 * time.Hour)
+			Expect(mr.Keys()).To(BeEmpty())
+		})
+	})
+
 	Describe("Redis URL Parsing", func() {
 		It("should parse valid redis URL", func() {
 			addrs, opts, err := parseRedisURLs([]string{"redis://localhost:6379"})
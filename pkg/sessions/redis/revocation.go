@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	revokedUserKeyPrefix      = "oauth2-proxy-revoked-user-"
+	revokedSessionIDKeyPrefix = "oauth2-proxy-revoked-sid-"
+)
+
+var _ sessions.RevocationList = (*SessionStore)(nil)
+
+// Revoke stores the revocation in redis so that it is shared by every
+// replica using the same redis
+func (store *SessionStore) Revoke(ctx context.Context, user string, sessionID string, expiration time.Duration) error {
+	key := revokedUserKey(user)
+	if sessionID != "" {
+		key = revokedSessionIDKey(sessionID)
+	}
+
+	revokedAt := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := store.Client.Set(ctx, key, []byte(revokedAt), expiration); err != nil {
+		return fmt.Errorf("error saving session revocation: %v", err)
+	}
+	return nil
+}
+
+// IsRevoked checks whether the session ID of the session, or every session of
+// its user created before the revocation, has been revoked
+func (store *SessionStore) IsRevoked(ctx context.Context, s *sessions.SessionState) (bool, error) {
+	if s.SessionID != "" {
+		_, err := store.Client.Get(ctx, revokedSessionIDKey(s.SessionID))
+		switch {
+		case err == nil:
+			return true, nil
+		case err != redis.Nil:
+			return false, fmt.Errorf("error loading session revocation: %v", err)
+		}
+	}
+
+	if s.User == "" {
+		return false, nil
+	}
+	value, err := store.Client.Get(ctx, revokedUserKey(s.User))
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error loading session revocation: %v", err)
+	}
+
+	revokedAt, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid session revocation: %v", err)
+	}
+	return s.CreatedAt == nil || !s.CreatedAt.After(time.Unix(0, revokedAt)), nil
+}
+
+// The user and session ID are hashed as they are not under our control
+func revokedUserKey(user string) string {
+	return revokedUserKeyPrefix + hashKey(user)
+}
+
+func revokedSessionIDKey(sessionID string) string {
+	return revokedSessionIDKeyPrefix + hashKey(sessionID)
+}
+
+func hashKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
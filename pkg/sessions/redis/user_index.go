@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/tracing"
+)
+
+const (
+	userSessionsKeyPrefix        = "oauth2-proxy-user-sessions-"
+	userSessionsCreatedKeyPrefix = "oauth2-proxy-user-sessions-created-"
+)
+
+var _ persistence.UserIndex = (*SessionStore)(nil)
+
+// AddUserTicket adds the ticket to two sorted sets of the user: one scored by
+// the expiry of the ticket, to prune the expired tickets on each addition, and
+// one scored by the creation of its session, to list them in that order.
+// The sets themselves expire with the last ticket added.
+func (store *SessionStore) AddUserTicket(ctx context.Context, user string, ticketID string, createdAt time.Time, exp time.Duration) error {
+	ctx, span := startSpan(ctx, "user_index.add")
+	err := store.addUserTicket(ctx, user, ticketID, createdAt, exp)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return fmt.Errorf("error adding the session to the user index: %v", err)
+	}
+	return nil
+}
+
+func (store *SessionStore) addUserTicket(ctx context.Context, user string, ticketID string, createdAt time.Time, exp time.Duration) error {
+	now := time.Now()
+	key, createdKey := userSessionsKey(user), userSessionsCreatedKey(user)
+
+	expired, err := store.Client.ZRangeByScore(ctx, key, "-inf", score(now))
+	if err != nil {
+		return err
+	}
+	if len(expired) > 0 {
+		if err := store.Client.ZRem(ctx, key, expired...); err != nil {
+			return err
+		}
+		if err := store.Client.ZRem(ctx, createdKey, expired...); err != nil {
+			return err
+		}
+	}
+
+	if err := store.Client.ZAdd(ctx, key, ticketID, float64(now.Add(exp).Unix())); err != nil {
+		return err
+	}
+	if err := store.Client.ZAdd(ctx, createdKey, ticketID, float64(createdAt.UnixNano())); err != nil {
+		return err
+	}
+	if err := store.Client.Expire(ctx, key, exp); err != nil {
+		return err
+	}
+	return store.Client.Expire(ctx, createdKey, exp)
+}
+
+// UserTickets returns the tickets of the user that have not expired yet, the
+// earliest created first. The tickets indexed before their creation was
+// recorded come first.
+func (store *SessionStore) UserTickets(ctx context.Context, user string) ([]string, error) {
+	ctx, span := startSpan(ctx, "user_index.list")
+	ticketIDs, err := store.userTickets(ctx, user)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sessions of the user index: %v", err)
+	}
+	return ticketIDs, nil
+}
+
+func (store *SessionStore) userTickets(ctx context.Context, user string) ([]string, error) {
+	valid, err := store.Client.ZRangeByScore(ctx, userSessionsKey(user), "("+score(time.Now()), "+inf")
+	if err != nil {
+		return nil, err
+	}
+	created, err := store.Client.ZRangeByScore(ctx, userSessionsCreatedKey(user), "-inf", "+inf")
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[string]bool, len(valid))
+	for _, ticketID := range valid {
+		remaining[ticketID] = true
+	}
+	ordered := make([]string, 0, len(valid))
+	for _, ticketID := range created {
+		if remaining[ticketID] {
+			ordered = append(ordered, ticketID)
+			delete(remaining, ticketID)
+		}
+	}
+
+	ticketIDs := make([]string, 0, len(valid))
+	for _, ticketID := range valid {
+		if remaining[ticketID] {
+			ticketIDs = append(ticketIDs, ticketID)
+		}
+	}
+	return append(ticketIDs, ordered...), nil
+}
+
+// RemoveUserTickets removes the tickets from the sorted sets of the user
+func (store *SessionStore) RemoveUserTickets(ctx context.Context, user string, ticketIDs ...string) error {
+	ctx, span := startSpan(ctx, "user_index.remove")
+	err := store.Client.ZRem(ctx, userSessionsKey(user), ticketIDs...)
+	if err == nil {
+		err = store.Client.ZRem(ctx, userSessionsCreatedKey(user), ticketIDs...)
+	}
+	tracing.EndSpan(span, err)
+	if err != nil {
+		return fmt.Errorf("error removing the sessions from the user index: %v", err)
+	}
+	return nil
+}
+
+// The user is hashed as it is not under our control
+func userSessionsKey(user string) string {
+	return userSessionsKeyPrefix + hashKey(user)
+}
+
+func userSessionsCreatedKey(user string) string {
+	return userSessionsCreatedKeyPrefix + hashKey(user)
+}
+
+func score(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
@@ -0,0 +1,112 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+)
+
+// NewRevocationList returns the revocation list for sessions of the session
+// store. Stores that persist the sessions server side keep the revocations
+// along the sessions, the cookie and JWT stores keep them in memory.
+func NewRevocationList(store sessions.SessionStore) sessions.RevocationList {
+	if manager, ok := store.(*persistence.Manager); ok {
+		if list, ok := manager.Store.(sessions.RevocationList); ok {
+			return list
+		}
+	}
+	return NewMemoryRevocationList()
+}
+
+// EndUserSessions ends every session of the user. The sessions found by the
+// session store are cleared, and every session of the user created until now
+// is revoked to also end the sessions the store cannot find, such as the ones
+// kept in cookies.
+func EndUserSessions(ctx context.Context, store sessions.SessionStore, list sessions.RevocationList, user string, expiration time.Duration) error {
+	if userStore, ok := store.(sessions.UserSessionStore); ok {
+		if err := userStore.ClearUserSessions(ctx, user); err != nil {
+			return fmt.Errorf("error clearing the sessions of the user: %v", err)
+		}
+	}
+	if err := list.Revoke(ctx, user, "", expiration); err != nil {
+		return fmt.Errorf("error revoking the sessions of the user: %v", err)
+	}
+	return nil
+}
+
+// MemoryRevocationList keeps the revocations in memory until they expire,
+// they are therefore lost on restart and not shared between replicas
+type MemoryRevocationList struct {
+	mu         sync.Mutex
+	users      map[string]revocation
+	sessionIDs map[string]revocation
+}
+
+type revocation struct {
+	revokedAt time.Time
+	// expiresAt is zero when the revocation never expires
+	expiresAt time.Time
+}
+
+func (r revocation) expired(now time.Time) bool {
+	return !r.expiresAt.IsZero() && now.After(r.expiresAt)
+}
+
+// NewMemoryRevocationList creates an empty MemoryRevocationList
+func NewMemoryRevocationList() *MemoryRevocationList {
+	return &MemoryRevocationList{
+		users:      make(map[string]revocation),
+		sessionIDs: make(map[string]revocation),
+	}
+}
+
+// Revoke stores the revocation of the session ID, or of every session of the
+// user when no session ID is given, and prunes the expired revocations.
+// A revocation without expiration is kept until restart.
+func (l *MemoryRevocationList) Revoke(_ context.Context, user string, sessionID string, expiration time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for key, r := range l.users {
+		if r.expired(now) {
+			delete(l.users, key)
+		}
+	}
+	for key, r := range l.sessionIDs {
+		if r.expired(now) {
+			delete(l.sessionIDs, key)
+		}
+	}
+
+	r := revocation{revokedAt: now}
+	if expiration > 0 {
+		r.expiresAt = now.Add(expiration)
+	}
+	if sessionID != "" {
+		l.sessionIDs[sessionID] = r
+	} else {
+		l.users[user] = r
+	}
+	return nil
+}
+
+// IsRevoked checks whether the session ID of the session, or every session of
+// its user created before the revocation, has been revoked
+func (l *MemoryRevocationList) IsRevoked(_ context.Context, s *sessions.SessionState) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if r, ok := l.sessionIDs[s.SessionID]; ok && s.SessionID != "" && !r.expired(now) {
+		return true, nil
+	}
+	if r, ok := l.users[s.User]; ok && s.User != "" && !r.expired(now) {
+		return s.CreatedAt == nil || !s.CreatedAt.After(r.revokedAt), nil
+	}
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package sessions_test
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/file"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/redis"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/sql"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/tests"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewRevocationList", func() {
+	var cookieOpts *options.Cookie
+
+	BeforeEach(func() {
+		cookieOpts = &options.Cookie{
+			Name:   "_oauth2_proxy",
+			Secret: "0123456789abcdefghijklmnopqrstuv",
+			Expire: time.Duration(168) * time.Hour,
+		}
+	})
+
+	It("keeps the revocations of the cookie store in memory", func() {
+		ss, err := sessions.NewSessionStore(&options.SessionOptions{Type: options.CookieSessionStoreType}, cookieOpts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions.NewRevocationList(ss)).To(BeAssignableToTypeOf(&sessions.MemoryRevocationList{}))
+	})
+
+	It("keeps the revocations of the redis store in redis", func() {
+		opts := &options.SessionOptions{Type: options.RedisSessionStoreType}
+		opts.Redis.ConnectionURL = "redis://"
+		ss, err := sessions.NewSessionStore(opts, cookieOpts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions.NewRevocationList(ss)).To(BeAssignableToTypeOf(&redis.SessionStore{}))
+	})
+
+	It("keeps the revocations of the file store in the database file", func() {
+		opts := &options.SessionOptions{Type: options.FileSessionStoreType}
+		opts.File.Path = filepath.Join(GinkgoT().TempDir(), "sessions.db")
+		ss, err := sessions.NewSessionStore(opts, cookieOpts)
+		Expect(err).NotTo(HaveOccurred())
+		defer ss.(*persistence.Manager).Store.(*file.SessionStore).Close()
+		Expect(sessions.NewRevocationList(ss)).To(BeAssignableToTypeOf(&file.SessionStore{}))
+	})
+
+	It("keeps the revocations of the sql store in the database", func() {
+		opts := &options.SessionOptions{Type: options.SQLSessionStoreType}
+		opts.SQL.Dialect = options.SQLiteSQLDialect
+		opts.SQL.DSN = "file:" + filepath.Join(GinkgoT().TempDir(), "sessions.db")
+		ss, err := sessions.NewSessionStore(opts, cookieOpts)
+		Expect(err).NotTo(HaveOccurred())
+		defer ss.(*persistence.Manager).Store.(*sql.SessionStore).Close()
+		Expect(sessions.NewRevocationList(ss)).To(BeAssignableToTypeOf(&sql.SessionStore{}))
+	})
+})
+
+var _ = Describe("MemoryRevocationList", func() {
+	tests.RunRevocationListTests(
+		func() sessionsapi.RevocationList {
+			return sessions.NewMemoryRevocationList()
+		},
+		func(d time.Duration) error {
+			time.Sleep(d)
+			return nil
+		},
+	)
+})
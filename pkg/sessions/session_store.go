@@ -6,8 +6,10 @@ import (
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/cookie"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/file"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/jwt"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/redis"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/sql"
 )
 
 // NewSessionStore creates a SessionStore from the provided configuration
@@ -19,6 +21,10 @@ func NewSessionStore(opts *options.SessionOptions, cookieOpts *options.Cookie) (
 		return redis.NewRedisSessionStore(opts, cookieOpts)
 	case options.JWTSessionStoreType:
 		return jwt.NewJWTSessionStore(opts, cookieOpts)
+	case options.FileSessionStoreType:
+		return file.NewFileSessionStore(opts, cookieOpts)
+	case options.SQLSessionStoreType:
+		return sql.NewSQLSessionStore(opts, cookieOpts)
 	default:
 		return nil, fmt.Errorf("unknown session store type '%s'", opts.Type)
 	}
@@ -0,0 +1,167 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+
+	// Register the database/sql drivers of the dialects
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// migrationLockID identifies the postgres advisory lock taken while migrating
+// the schema, so that the replicas starting together migrate it once
+const migrationLockID = 4180
+
+// dialect holds the differences between the supported databases. The queries
+// are written for SQLite and adapted to the other databases by the dialect.
+type dialect struct {
+	driver string
+	// blobType is the column type of binary values
+	blobType string
+	// numberedPlaceholders replaces the ? placeholders by $1, $2...
+	numberedPlaceholders bool
+	// lockMigrations serialises the schema migrations in the transaction
+	lockMigrations string
+	// maxOpenConns limits the open connections, 0 is unlimited
+	maxOpenConns int
+}
+
+var dialects = map[string]dialect{
+	options.PostgresSQLDialect: {
+		driver:               "pgx",
+		blobType:             "BYTEA",
+		numberedPlaceholders: true,
+		lockMigrations:       fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", migrationLockID),
+	},
+	options.SQLiteSQLDialect: {
+		driver:   "sqlite",
+		blobType: "BLOB",
+		// SQLite writes lock the whole database, a single connection avoids
+		// failing on a busy database and is required by in-memory databases
+		maxOpenConns: 1,
+	},
+}
+
+func getDialect(name string) (dialect, error) {
+	d, ok := dialects[name]
+	if !ok {
+		return dialect{}, fmt.Errorf("unknown sql session dialect %q", name)
+	}
+	return d, nil
+}
+
+// rebind adapts the placeholders of the query to the dialect
+func (d dialect) rebind(query string) string {
+	if !d.numberedPlaceholders {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// migrations are the statements creating the schema, by version. They are
+// only ever appended to, as the databases record the last version applied.
+var migrations = []func(d dialect) []string{
+	func(d dialect) []string {
+		return []string{
+			`CREATE TABLE oauth2_proxy_sessions (
+				id TEXT PRIMARY KEY,
+				value ` + d.blobType + ` NOT NULL,
+				expires_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX oauth2_proxy_sessions_expires_at ON oauth2_proxy_sessions (expires_at)`,
+			`CREATE TABLE oauth2_proxy_session_locks (
+				id TEXT PRIMARY KEY,
+				token TEXT NOT NULL,
+				expires_at BIGINT NOT NULL
+			)`,
+			`CREATE TABLE oauth2_proxy_user_sessions (
+				username TEXT NOT NULL,
+				ticket_id TEXT NOT NULL,
+				expires_at BIGINT NOT NULL,
+				PRIMARY KEY (username, ticket_id)
+			)`,
+			`CREATE INDEX oauth2_proxy_user_sessions_expires_at ON oauth2_proxy_user_sessions (expires_at)`,
+		}
+	},
+	func(d dialect) []string {
+		return []string{
+			`CREATE TABLE oauth2_proxy_revocations (
+				id TEXT PRIMARY KEY,
+				revoked_at BIGINT NOT NULL,
+				expires_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX oauth2_proxy_revocations_expires_at ON oauth2_proxy_revocations (expires_at)`,
+		}
+	},
+	func(d dialect) []string {
+		return []string{
+			// The tickets indexed before are listed first
+			`ALTER TABLE oauth2_proxy_user_sessions ADD COLUMN created_at BIGINT NOT NULL DEFAULT 0`,
+		}
+	},
+}
+
+// migrate applies the migrations the database has not applied yet, in a
+// single transaction
+func migrate(ctx context.Context, db *dbsql.DB, d dialect) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// No-op once committed
+		_ = tx.Rollback()
+	}()
+
+	if d.lockMigrations != "" {
+		if _, err := tx.ExecContext(ctx, d.lockMigrations); err != nil {
+			return fmt.Errorf("error locking the migrations: %v", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS oauth2_proxy_schema_migrations (version INTEGER PRIMARY KEY)`)
+	if err != nil {
+		return fmt.Errorf("error creating the migrations table: %v", err)
+	}
+
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM oauth2_proxy_schema_migrations`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("error reading the schema version: %v", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than the supported version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		for _, statement := range migrations[i](d) {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("error migrating the schema to version %d: %v", i+1, err)
+			}
+		}
+		_, err := tx.ExecContext(ctx, d.rebind(`INSERT INTO oauth2_proxy_schema_migrations (version) VALUES (?)`), i+1)
+		if err != nil {
+			return fmt.Errorf("error recording the schema version %d: %v", i+1, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,95 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	dbsql "database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+)
+
+// Lock is a lock on a session, stored as a row with an expiration and a
+// random token identifying its holder. Each operation is a single statement,
+// so that the lock is atomic across the replicas sharing the database.
+type Lock struct {
+	store *SessionStore
+	key   string
+	token string
+}
+
+// Obtain obtains the lock unless it is held and has not expired.
+func (l *Lock) Obtain(ctx context.Context, expiration time.Duration) error {
+	rawToken := make([]byte, 16)
+	if _, err := rand.Read(rawToken); err != nil {
+		return fmt.Errorf("error creating lock token: %v", err)
+	}
+	token := hex.EncodeToString(rawToken)
+
+	// The row of an expired lock is taken over, the row of a held lock is
+	// left untouched
+	result, err := l.store.exec(ctx, `INSERT INTO oauth2_proxy_session_locks (id, token, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET token = excluded.token, expires_at = excluded.expires_at
+		WHERE oauth2_proxy_session_locks.expires_at <= ?`,
+		l.key, token, l.store.expiresAt(expiration), l.store.now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("error obtaining the lock: %v", err)
+	}
+	if !affected(result) {
+		return sessions.ErrLockNotObtained
+	}
+	l.token = token
+	return nil
+}
+
+// Refresh extends the expiration of the lock, if it is still held.
+func (l *Lock) Refresh(ctx context.Context, expiration time.Duration) error {
+	if l.token == "" {
+		return sessions.ErrNotLocked
+	}
+	result, err := l.store.exec(ctx, `UPDATE oauth2_proxy_session_locks SET expires_at = ? WHERE id = ? AND token = ? AND expires_at > ?`,
+		l.store.expiresAt(expiration), l.key, l.token, l.store.now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("error refreshing the lock: %v", err)
+	}
+	if !affected(result) {
+		return sessions.ErrNotLocked
+	}
+	return nil
+}
+
+// Peek returns true, if the lock is still applied.
+func (l *Lock) Peek(ctx context.Context) (bool, error) {
+	var count int
+	err := l.store.queryRow(ctx, `SELECT COUNT(*) FROM oauth2_proxy_session_locks WHERE id = ? AND expires_at > ?`,
+		l.key, l.store.now().UnixNano()).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error peeking the lock: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Release removes the lock, if it is still held.
+func (l *Lock) Release(ctx context.Context) error {
+	if l.token == "" {
+		return sessions.ErrNotLocked
+	}
+	result, err := l.store.exec(ctx, `DELETE FROM oauth2_proxy_session_locks WHERE id = ? AND token = ? AND expires_at > ?`,
+		l.key, l.token, l.store.now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("error releasing the lock: %v", err)
+	}
+	if !affected(result) {
+		return sessions.ErrNotLocked
+	}
+	l.token = ""
+	return nil
+}
+
+// affected returns whether the statement changed a row
+func affected(result dbsql.Result) bool {
+	n, err := result.RowsAffected()
+	return err == nil && n > 0
+}
@@ -0,0 +1,90 @@
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	dbsql "database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+)
+
+const (
+	revokedUserKeyPrefix      = "user-"
+	revokedSessionIDKeyPrefix = "sid-"
+)
+
+var _ sessions.RevocationList = (*SessionStore)(nil)
+
+// Revoke stores the revocation in the database so that it is shared by every
+// replica using the same database. A revocation without expiration never
+// expires.
+func (store *SessionStore) Revoke(ctx context.Context, user string, sessionID string, expiration time.Duration) error {
+	key := revokedUserKey(user)
+	if sessionID != "" {
+		key = revokedSessionIDKey(sessionID)
+	}
+
+	expiresAt := int64(math.MaxInt64)
+	if expiration > 0 {
+		expiresAt = store.expiresAt(expiration)
+	}
+	_, err := store.exec(ctx, `INSERT INTO oauth2_proxy_revocations (id, revoked_at, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET revoked_at = excluded.revoked_at, expires_at = excluded.expires_at`,
+		key, store.now().UnixNano(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("error saving session revocation: %v", err)
+	}
+	return nil
+}
+
+// IsRevoked checks whether the session ID of the session, or every session of
+// its user created before the revocation, has been revoked
+func (store *SessionStore) IsRevoked(ctx context.Context, s *sessions.SessionState) (bool, error) {
+	if s.SessionID != "" {
+		_, err := store.revokedAt(ctx, revokedSessionIDKey(s.SessionID))
+		switch {
+		case err == nil:
+			return true, nil
+		case !errors.Is(err, dbsql.ErrNoRows):
+			return false, fmt.Errorf("error loading session revocation: %v", err)
+		}
+	}
+
+	if s.User == "" {
+		return false, nil
+	}
+	revokedAt, err := store.revokedAt(ctx, revokedUserKey(s.User))
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error loading session revocation: %v", err)
+	}
+	return s.CreatedAt == nil || !s.CreatedAt.After(time.Unix(0, revokedAt)), nil
+}
+
+// revokedAt returns when the key was revoked, unless the revocation expired
+func (store *SessionStore) revokedAt(ctx context.Context, key string) (int64, error) {
+	var revokedAt int64
+	err := store.queryRow(ctx, `SELECT revoked_at FROM oauth2_proxy_revocations WHERE id = ? AND expires_at > ?`,
+		key, store.now().UnixNano()).Scan(&revokedAt)
+	return revokedAt, err
+}
+
+// The user and session ID are hashed as they are not under our control
+func revokedUserKey(user string) string {
+	return revokedUserKeyPrefix + hashKey(user)
+}
+
+func revokedSessionIDKey(sessionID string) string {
+	return revokedSessionIDKeyPrefix + hashKey(sessionID)
+}
+
+func hashKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
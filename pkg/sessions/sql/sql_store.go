@@ -0,0 +1,248 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+)
+
+// migrationTimeout bounds the connection to the database and the schema
+// migration on startup
+const migrationTimeout = 30 * time.Second
+
+var (
+	_ persistence.Lister    = (*SessionStore)(nil)
+	_ persistence.UserIndex = (*SessionStore)(nil)
+)
+
+// SessionStore is an implementation of the persistence.Store
+// interface that stores sessions in a SQL database
+type SessionStore struct {
+	db      *dbsql.DB
+	dialect dialect
+	// now is overridden in tests to fast forward the expiration
+	now func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewSQLSessionStore initialises a new instance of the SessionStore and wraps
+// it in a persistence.Manager
+func NewSQLSessionStore(opts *options.SessionOptions, cookieOpts *options.Cookie) (sessions.SessionStore, error) {
+	store, err := newSessionStore(opts.SQL)
+	if err != nil {
+		return nil, err
+	}
+	return persistence.NewManager(store, cookieOpts), nil
+}
+
+func newSessionStore(opts options.SQLStoreOptions) (*SessionStore, error) {
+	d, err := getDialect(opts.Dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbsql.Open(d.driver, opts.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the %s session database: %v", opts.Dialect, err)
+	}
+	if d.maxOpenConns > 0 {
+		db.SetMaxOpenConns(d.maxOpenConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+	if err := migrate(ctx, db, d); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating the %s session database: %v", opts.Dialect, err)
+	}
+
+	store := &SessionStore{
+		db:      db,
+		dialect: d,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go store.cleanupPeriodically(opts.CleanupInterval)
+	return store, nil
+}
+
+// Save stores the value with its expiration
+func (store *SessionStore) Save(ctx context.Context, key string, value []byte, exp time.Duration) error {
+	_, err := store.exec(ctx, `INSERT INTO oauth2_proxy_sessions (id, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		key, value, store.expiresAt(exp))
+	if err != nil {
+		return fmt.Errorf("error saving sql session: %v", err)
+	}
+	return nil
+}
+
+// Load reads the value of the key, unless it has expired
+func (store *SessionStore) Load(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := store.queryRow(ctx, `SELECT value FROM oauth2_proxy_sessions WHERE id = ? AND expires_at > ?`,
+		key, store.now().UnixNano()).Scan(&value)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, errors.New("session does not exist")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading sql session: %v", err)
+	}
+	return value, nil
+}
+
+// Clear removes the key
+func (store *SessionStore) Clear(ctx context.Context, key string) error {
+	if _, err := store.exec(ctx, `DELETE FROM oauth2_proxy_sessions WHERE id = ?`, key); err != nil {
+		return fmt.Errorf("error clearing the sql session: %v", err)
+	}
+	return nil
+}
+
+// Lock creates a lock object for sessions.SessionState
+func (store *SessionStore) Lock(key string) sessions.Lock {
+	return &Lock{
+		store: store,
+		key:   key,
+	}
+}
+
+// VerifyConnection verifies the connection to the database
+func (store *SessionStore) VerifyConnection(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// List returns the keys starting with the prefix that have not expired
+func (store *SessionStore) List(ctx context.Context, prefix string) ([]string, error) {
+	// substr is used rather than LIKE, which needs escaping and is case
+	// insensitive in SQLite
+	keys, err := store.queryStrings(ctx, `SELECT id FROM oauth2_proxy_sessions WHERE substr(id, 1, ?) = ? AND expires_at > ?`,
+		len(prefix), prefix, store.now().UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sql sessions: %v", err)
+	}
+	return keys, nil
+}
+
+// AddUserTicket adds the ticket to the index of the user
+func (store *SessionStore) AddUserTicket(ctx context.Context, user string, ticketID string, createdAt time.Time, exp time.Duration) error {
+	_, err := store.exec(ctx, `INSERT INTO oauth2_proxy_user_sessions (username, ticket_id, created_at, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (username, ticket_id) DO UPDATE SET created_at = excluded.created_at, expires_at = excluded.expires_at`,
+		user, ticketID, createdAt.UnixNano(), store.expiresAt(exp))
+	if err != nil {
+		return fmt.Errorf("error adding the session to the user index: %v", err)
+	}
+	return nil
+}
+
+// UserTickets returns the tickets of the user that have not expired yet, the
+// earliest created first
+func (store *SessionStore) UserTickets(ctx context.Context, user string) ([]string, error) {
+	ticketIDs, err := store.queryStrings(ctx, `SELECT ticket_id FROM oauth2_proxy_user_sessions WHERE username = ? AND expires_at > ?
+		ORDER BY created_at, ticket_id`,
+		user, store.now().UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("error listing the sessions of the user index: %v", err)
+	}
+	return ticketIDs, nil
+}
+
+// RemoveUserTickets removes the tickets from the index of the user
+func (store *SessionStore) RemoveUserTickets(ctx context.Context, user string, ticketIDs ...string) error {
+	for _, ticketID := range ticketIDs {
+		_, err := store.exec(ctx, `DELETE FROM oauth2_proxy_user_sessions WHERE username = ? AND ticket_id = ?`, user, ticketID)
+		if err != nil {
+			return fmt.Errorf("error removing the sessions from the user index: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close stops the cleanup and closes the connections to the database
+func (store *SessionStore) Close() error {
+	store.stopOnce.Do(func() {
+		close(store.stop)
+		<-store.done
+	})
+	return store.db.Close()
+}
+
+// cleanupPeriodically deletes the expired rows at every interval, until the
+// store is closed
+func (store *SessionStore) cleanupPeriodically(interval time.Duration) {
+	defer close(store.done)
+	if interval <= 0 {
+		<-store.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := store.cleanup(context.Background()); err != nil {
+				logger.Errorf("Error cleaning up the sql session store: %v", err)
+			}
+		case <-store.stop:
+			return
+		}
+	}
+}
+
+// cleanup deletes the expired sessions, locks, user index entries and
+// revocations
+func (store *SessionStore) cleanup(ctx context.Context) error {
+	now := store.now().UnixNano()
+	for _, table := range []string{"oauth2_proxy_sessions", "oauth2_proxy_session_locks", "oauth2_proxy_user_sessions", "oauth2_proxy_revocations"} {
+		if _, err := store.exec(ctx, `DELETE FROM `+table+` WHERE expires_at <= ?`, now); err != nil {
+			return fmt.Errorf("error deleting the expired rows of %s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// expiresAt returns the expiration in Unix nanoseconds, as stored in the
+// expires_at columns
+func (store *SessionStore) expiresAt(exp time.Duration) int64 {
+	return store.now().Add(exp).UnixNano()
+}
+
+func (store *SessionStore) exec(ctx context.Context, query string, args ...interface{}) (dbsql.Result, error) {
+	return store.db.ExecContext(ctx, store.dialect.rebind(query), args...)
+}
+
+func (store *SessionStore) queryRow(ctx context.Context, query string, args ...interface{}) *dbsql.Row {
+	return store.db.QueryRowContext(ctx, store.dialect.rebind(query), args...)
+}
+
+// queryStrings returns the values of the single column selected by the query
+func (store *SessionStore) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := store.db.QueryContext(ctx, store.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
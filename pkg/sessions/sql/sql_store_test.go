@@ -0,0 +1,271 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/persistence"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/sessions/tests"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"modernc.org/sqlite"
+)
+
+// postgresOverSQLiteDialect runs the queries of the postgres dialect, with
+// their numbered placeholders and migration lock, against SQLite, which
+// accepts the same placeholders and upserts. The advisory locks taken are
+// recorded by a pg_advisory_xact_lock function registered in SQLite.
+const postgresOverSQLiteDialect = "postgres-over-sqlite"
+
+var (
+	advisoryLocksMutex sync.Mutex
+	advisoryLocks      []int64
+)
+
+func init() {
+	d := dialects[options.PostgresSQLDialect]
+	d.driver = "sqlite"
+	d.maxOpenConns = 1
+	dialects[postgresOverSQLiteDialect] = d
+
+	sqlite.MustRegisterScalarFunction("pg_advisory_xact_lock", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		advisoryLocksMutex.Lock()
+		defer advisoryLocksMutex.Unlock()
+		advisoryLocks = append(advisoryLocks, args[0].(int64))
+		return nil, nil
+	})
+}
+
+var _ = Describe("SQL SessionStore Tests", func() {
+	for _, dialectName := range []string{options.SQLiteSQLDialect, postgresOverSQLiteDialect} {
+		Context("with the "+dialectName+" dialect", func() {
+			runSQLSessionStoreTests(dialectName)
+		})
+	}
+
+	It("fails with an unknown dialect", func() {
+		_, err := newSessionStore(options.SQLStoreOptions{Dialect: "oracle"})
+		Expect(err).To(MatchError(`unknown sql session dialect "oracle"`))
+	})
+})
+
+func runSQLSessionStoreTests(dialectName string) {
+	// offset fast forwards the clock of the stores
+	var offset time.Duration
+	var stores []*SessionStore
+
+	now := func() time.Time {
+		return time.Now().Add(offset)
+	}
+
+	sqliteOptions := func() options.SQLStoreOptions {
+		return options.SQLStoreOptions{
+			Dialect: dialectName,
+			DSN:     "file:" + filepath.Join(GinkgoT().TempDir(), "sessions.db"),
+		}
+	}
+
+	BeforeEach(func() {
+		offset = 0
+		stores = nil
+	})
+
+	JustAfterEach(func() {
+		// Release the database connections immediately after the test ends
+		for _, store := range stores {
+			Expect(store.Close()).To(Succeed())
+		}
+	})
+
+	tests.RunSessionStoreTests(
+		func(opts *options.SessionOptions, cookieOpts *options.Cookie) (sessionsapi.SessionStore, error) {
+			opts.Type = options.SQLSessionStoreType
+			opts.SQL = sqliteOptions()
+
+			ss, err := NewSQLSessionStore(opts, cookieOpts)
+			if err != nil {
+				return nil, err
+			}
+			store := ss.(*persistence.Manager).Store.(*SessionStore)
+			store.now = now
+			stores = append(stores, store)
+			return ss, nil
+		},
+		func(d time.Duration) error {
+			offset += d
+			return nil
+		},
+	)
+
+	Describe("SQL RevocationList", func() {
+		tests.RunRevocationListTests(
+			func() sessionsapi.RevocationList {
+				store, err := newSessionStore(sqliteOptions())
+				Expect(err).ToNot(HaveOccurred())
+				store.now = now
+				stores = append(stores, store)
+				return store
+			},
+			func(d time.Duration) error {
+				offset += d
+				return nil
+			},
+		)
+	})
+
+	Context("with a store", func() {
+		var store *SessionStore
+		var opts options.SQLStoreOptions
+		ctx := context.Background()
+
+		BeforeEach(func() {
+			opts = sqliteOptions()
+
+			var err error
+			store, err = newSessionStore(opts)
+			Expect(err).ToNot(HaveOccurred())
+			store.now = now
+			stores = append(stores, store)
+		})
+
+		It("migrates the schema once", func() {
+			Expect(store.Save(ctx, "key", []byte("value"), time.Minute)).To(Succeed())
+
+			reopened, err := newSessionStore(opts)
+			Expect(err).ToNot(HaveOccurred())
+			stores = append(stores, reopened)
+			Expect(reopened.Load(ctx, "key")).To(Equal([]byte("value")))
+
+			var version int
+			Expect(reopened.db.QueryRow(`SELECT MAX(version) FROM oauth2_proxy_schema_migrations`).Scan(&version)).To(Succeed())
+			Expect(version).To(Equal(len(migrations)))
+		})
+
+		if dialectName == postgresOverSQLiteDialect {
+			It("locks the migrations", func() {
+				advisoryLocksMutex.Lock()
+				advisoryLocks = nil
+				advisoryLocksMutex.Unlock()
+
+				reopened, err := newSessionStore(opts)
+				Expect(err).ToNot(HaveOccurred())
+				stores = append(stores, reopened)
+
+				advisoryLocksMutex.Lock()
+				defer advisoryLocksMutex.Unlock()
+				Expect(advisoryLocks).To(Equal([]int64{migrationLockID}))
+			})
+		}
+
+		It("does not load expired sessions", func() {
+			Expect(store.Save(ctx, "key", []byte("value"), time.Minute)).To(Succeed())
+			Expect(store.Load(ctx, "key")).To(Equal([]byte("value")))
+
+			offset = 2 * time.Minute
+			_, err := store.Load(ctx, "key")
+			Expect(err).To(MatchError("session does not exist"))
+		})
+
+		It("lists the keys with the prefix that have not expired", func() {
+			Expect(store.Save(ctx, "prefix-a", []byte("a"), time.Minute)).To(Succeed())
+			Expect(store.Save(ctx, "prefix-b", []byte("b"), time.Hour)).To(Succeed())
+			Expect(store.Save(ctx, "PREFIX-c", []byte("c"), time.Hour)).To(Succeed())
+			Expect(store.Save(ctx, "other", []byte("d"), time.Hour)).To(Succeed())
+
+			offset = 2 * time.Minute
+			Expect(store.List(ctx, "prefix-")).To(ConsistOf("prefix-b"))
+		})
+
+		It("deletes the expired rows", func() {
+			Expect(store.Save(ctx, "expired", []byte("value"), time.Minute)).To(Succeed())
+			Expect(store.Save(ctx, "valid", []byte("value"), time.Hour)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "expired", time.Now(), time.Minute)).To(Succeed())
+			Expect(store.AddUserTicket(ctx, "user", "valid", time.Now(), time.Hour)).To(Succeed())
+			Expect(store.Lock("expired").Obtain(ctx, time.Minute)).To(Succeed())
+			Expect(store.Revoke(ctx, "expired", "", time.Minute)).To(Succeed())
+			Expect(store.Revoke(ctx, "valid", "", time.Hour)).To(Succeed())
+			Expect(store.Revoke(ctx, "permanent", "", 0)).To(Succeed())
+
+			offset = 2 * time.Minute
+			Expect(store.cleanup(ctx)).To(Succeed())
+
+			for table, count := range map[string]int{
+				"oauth2_proxy_sessions":      1,
+				"oauth2_proxy_session_locks": 0,
+				"oauth2_proxy_user_sessions": 1,
+				"oauth2_proxy_revocations":   2,
+			} {
+				var rows int
+				Expect(store.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&rows)).To(Succeed())
+				Expect(rows).To(Equal(count), table)
+			}
+		})
+
+		Context("with a lock", func() {
+			var lock sessionsapi.Lock
+
+			BeforeEach(func() {
+				lock = store.Lock("key")
+				Expect(lock.Obtain(ctx, time.Minute)).To(Succeed())
+			})
+
+			It("is not obtained twice", func() {
+				Expect(store.Lock("key").Obtain(ctx, time.Minute)).To(Equal(sessionsapi.ErrLockNotObtained))
+			})
+
+			It("is obtained once expired", func() {
+				offset = 2 * time.Minute
+				Expect(store.Lock("key").Obtain(ctx, time.Minute)).To(Succeed())
+				Expect(lock.Refresh(ctx, time.Minute)).To(Equal(sessionsapi.ErrNotLocked))
+				Expect(lock.Release(ctx)).To(Equal(sessionsapi.ErrNotLocked))
+			})
+
+			It("is refreshed and released", func() {
+				Expect(lock.Refresh(ctx, time.Hour)).To(Succeed())
+				offset = 2 * time.Minute
+				Expect(lock.Peek(ctx)).To(BeTrue())
+
+				Expect(lock.Release(ctx)).To(Succeed())
+				Expect(lock.Peek(ctx)).To(BeFalse())
+				Expect(lock.Release(ctx)).To(Equal(sessionsapi.ErrNotLocked))
+			})
+		})
+	})
+}
+
+var _ = Describe("SQL dialects", func() {
+	DescribeTable("rebind",
+		func(dialectName string, expected string) {
+			d, err := getDialect(dialectName)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.rebind(`SELECT value FROM t WHERE id = ? AND expires_at > ?`)).To(Equal(expected))
+		},
+		Entry("with postgres", options.PostgresSQLDialect, `SELECT value FROM t WHERE id = $1 AND expires_at > $2`),
+		Entry("with sqlite", options.SQLiteSQLDialect, `SELECT value FROM t WHERE id = ? AND expires_at > ?`),
+	)
+
+	It("numbers the placeholders of the lock upsert in order", func() {
+		d, err := getDialect(options.PostgresSQLDialect)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.rebind(`INSERT INTO oauth2_proxy_session_locks (id, token, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET token = excluded.token, expires_at = excluded.expires_at
+		WHERE oauth2_proxy_session_locks.expires_at <= ?`)).To(Equal(`INSERT INTO oauth2_proxy_session_locks (id, token, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET token = excluded.token, expires_at = excluded.expires_at
+		WHERE oauth2_proxy_session_locks.expires_at <= $4`))
+	})
+
+	It("locks the migrations on postgres only", func() {
+		postgres, err := getDialect(options.PostgresSQLDialect)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(postgres.lockMigrations).To(Equal("SELECT pg_advisory_xact_lock(4180)"))
+
+		sqlite, err := getDialect(options.SQLiteSQLDialect)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sqlite.lockMigrations).To(BeEmpty())
+	})
+})
@@ -3,6 +3,8 @@ package tests
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
@@ -14,11 +16,20 @@ type entry struct {
 	expiration time.Duration
 }
 
+// userTicket is a MockStore user index entry with the creation of its
+// session and its expiration
+type userTicket struct {
+	createdAt  time.Time
+	expiration time.Duration
+}
+
 // MockStore is a generic in-memory implementation of persistence.Store
 // for mocking in tests
 type MockStore struct {
 	cache     map[string]entry
 	lockCache map[string]*MockLock
+	// userIndex holds the tickets of each user
+	userIndex map[string]map[string]userTicket
 	elapsed   time.Duration
 }
 
@@ -27,6 +38,7 @@ func NewMockStore() *MockStore {
 	return &MockStore{
 		cache:     map[string]entry{},
 		lockCache: map[string]*MockLock{},
+		userIndex: map[string]map[string]userTicket{},
 		elapsed:   0 * time.Second,
 	}
 }
@@ -65,6 +77,49 @@ func (s *MockStore) Lock(key string) sessions.Lock {
 	return lock
 }
 
+// List returns the keys of the memory cache starting with the prefix
+func (s *MockStore) List(_ context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	for key, entry := range s.cache {
+		if strings.HasPrefix(key, prefix) && entry.expiration > s.elapsed {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// AddUserTicket adds the ticket to the user index in memory
+func (s *MockStore) AddUserTicket(_ context.Context, user string, ticketID string, createdAt time.Time, exp time.Duration) error {
+	if s.userIndex[user] == nil {
+		s.userIndex[user] = map[string]userTicket{}
+	}
+	s.userIndex[user][ticketID] = userTicket{createdAt: createdAt, expiration: s.elapsed + exp}
+	return nil
+}
+
+// UserTickets returns the tickets of the user index that have not expired,
+// the earliest created first
+func (s *MockStore) UserTickets(_ context.Context, user string) ([]string, error) {
+	ticketIDs := []string{}
+	for ticketID, ticket := range s.userIndex[user] {
+		if ticket.expiration > s.elapsed {
+			ticketIDs = append(ticketIDs, ticketID)
+		}
+	}
+	sort.SliceStable(ticketIDs, func(i, j int) bool {
+		return s.userIndex[user][ticketIDs[i]].createdAt.Before(s.userIndex[user][ticketIDs[j]].createdAt)
+	})
+	return ticketIDs, nil
+}
+
+// RemoveUserTickets removes the tickets from the user index
+func (s *MockStore) RemoveUserTickets(_ context.Context, user string, ticketIDs ...string) error {
+	for _, ticketID := range ticketIDs {
+		delete(s.userIndex[user], ticketID)
+	}
+	return nil
+}
+
 func (s *MockStore) VerifyConnection(_ context.Context) error {
 	return nil
 }
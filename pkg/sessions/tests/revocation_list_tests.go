@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"time"
+
+	sessionsapi "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// NewRevocationListFunc allows any revocation list implementation to
+// configure its own list before each test.
+type NewRevocationListFunc func() sessionsapi.RevocationList
+
+// RunRevocationListTests runs the behaviour every RevocationList must have.
+// The fast forward function must make revocations older than the duration
+// expire.
+func RunRevocationListTests(newList NewRevocationListFunc, fastForward PersistentStoreFastForwardFunc) {
+	Describe("Revocation List Suite", func() {
+		const expiration = 100 * time.Millisecond
+
+		var list sessionsapi.RevocationList
+		ctx := context.Background()
+
+		newSession := func(user, sessionID string, createdAt time.Time) *sessionsapi.SessionState {
+			return &sessionsapi.SessionState{
+				User:      user,
+				SessionID: sessionID,
+				CreatedAt: &createdAt,
+			}
+		}
+
+		isRevoked := func(s *sessionsapi.SessionState) bool {
+			revoked, err := list.IsRevoked(ctx, s)
+			Expect(err).ToNot(HaveOccurred())
+			return revoked
+		}
+
+		BeforeEach(func() {
+			list = newList()
+		})
+
+		It("does not revoke sessions by default", func() {
+			Expect(isRevoked(newSession("user", "sid", time.Now()))).To(BeFalse())
+		})
+
+		Context("when a session ID is revoked", func() {
+			BeforeEach(func() {
+				Expect(list.Revoke(ctx, "user", "sid", expiration)).To(Succeed())
+			})
+
+			It("revokes the session with that session ID", func() {
+				Expect(isRevoked(newSession("user", "sid", time.Now()))).To(BeTrue())
+			})
+
+			It("does not revoke the other sessions of the user", func() {
+				Expect(isRevoked(newSession("user", "other", time.Now().Add(-time.Hour)))).To(BeFalse())
+				Expect(isRevoked(newSession("user", "", time.Now().Add(-time.Hour)))).To(BeFalse())
+			})
+
+			It("forgets the revocation once it expires", func() {
+				Expect(fastForward(2 * expiration)).To(Succeed())
+				Expect(isRevoked(newSession("user", "sid", time.Now()))).To(BeFalse())
+			})
+		})
+
+		Context("when a user is revoked", func() {
+			BeforeEach(func() {
+				Expect(list.Revoke(ctx, "user", "", expiration)).To(Succeed())
+			})
+
+			It("revokes the sessions of the user created before the revocation", func() {
+				Expect(isRevoked(newSession("user", "sid", time.Now().Add(-time.Hour)))).To(BeTrue())
+				Expect(isRevoked(newSession("user", "", time.Now().Add(-time.Hour)))).To(BeTrue())
+			})
+
+			It("does not revoke the sessions created after the revocation", func() {
+				Expect(isRevoked(newSession("user", "", time.Now().Add(time.Hour)))).To(BeFalse())
+			})
+
+			It("does not revoke the sessions of other users", func() {
+				Expect(isRevoked(newSession("other", "", time.Now().Add(-time.Hour)))).To(BeFalse())
+			})
+
+			It("forgets the revocation once it expires", func() {
+				Expect(fastForward(2 * expiration)).To(Succeed())
+				Expect(isRevoked(newSession("user", "", time.Now().Add(-time.Hour)))).To(BeFalse())
+			})
+		})
+	})
+}
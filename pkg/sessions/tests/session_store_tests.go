@@ -283,6 +283,133 @@ func PersistentSessionStoreInterfaceTests(in *testInput) {
 		CheckCookieOptions(in)
 	})
 
+	Context("when ClearUserSessions is called on a persistent store", func() {
+		var userRequests []*http.Request
+		var otherRequest *http.Request
+
+		saveSession := func(user string) *http.Request {
+			session := *in.session
+			session.User = user
+			resp := httptest.NewRecorder()
+			Expect(in.ss().Save(resp, httptest.NewRequest("GET", "http://example.com/", nil), &session)).To(Succeed())
+
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			for _, c := range resp.Result().Cookies() {
+				req.AddCookie(c)
+			}
+			return req
+		}
+
+		BeforeEach(func() {
+			userRequests = []*http.Request{saveSession("john.doe"), saveSession("john.doe")}
+			otherRequest = saveSession("jane.doe")
+
+			userStore, ok := in.ss().(sessionsapi.UserSessionStore)
+			Expect(ok).To(BeTrue())
+			Expect(userStore.ClearUserSessions(in.request.Context(), "john.doe")).To(Succeed())
+		})
+
+		It("clears every session of the user", func() {
+			for _, req := range userRequests {
+				loaded, err := in.ss().Load(req)
+				Expect(err).To(HaveOccurred())
+				Expect(loaded).To(BeNil())
+			}
+		})
+
+		It("does not clear the sessions of other users", func() {
+			loaded, err := in.ss().Load(otherRequest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.User).To(Equal("jane.doe"))
+		})
+	})
+
+	Context("when the sessions of a user are listed on a persistent store", func() {
+		It("lists the earliest created first, even after they were saved again", func() {
+			created := time.Now().Truncate(time.Second)
+			saveSession := func(req *http.Request, createdAt time.Time) *http.Request {
+				session := *in.session
+				session.User = "john.doe"
+				session.CreatedAt = &createdAt
+				resp := httptest.NewRecorder()
+				Expect(in.ss().Save(resp, req, &session)).To(Succeed())
+
+				sessionReq := httptest.NewRequest("GET", "http://example.com/", nil)
+				for _, c := range resp.Result().Cookies() {
+					sessionReq.AddCookie(c)
+				}
+				return sessionReq
+			}
+
+			first := saveSession(httptest.NewRequest("GET", "http://example.com/", nil), created)
+			Expect(in.persistentFastForward(time.Minute)).To(Succeed())
+			second := saveSession(httptest.NewRequest("GET", "http://example.com/", nil), created.Add(time.Minute))
+			Expect(in.persistentFastForward(time.Minute)).To(Succeed())
+			saveSession(first, created)
+
+			userStore, ok := in.ss().(sessionsapi.UserSessionStore)
+			Expect(ok).To(BeTrue())
+			Expect(userStore.UserSessionIDs(in.request.Context(), "john.doe")).To(Equal([]string{
+				userStore.SessionID(first),
+				userStore.SessionID(second),
+			}))
+		})
+	})
+
+	Context("when the sessions are administrated on a persistent store", func() {
+		var admin sessionsapi.SessionAdmin
+		var userRequest *http.Request
+
+		BeforeEach(func() {
+			var ok bool
+			admin, ok = in.ss().(sessionsapi.SessionAdmin)
+			Expect(ok).To(BeTrue())
+
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			req.Header.Set("User-Agent", "test-agent")
+			resp := httptest.NewRecorder()
+			Expect(in.ss().Save(resp, req, in.session)).To(Succeed())
+
+			userRequest = httptest.NewRequest("GET", "http://example.com/", nil)
+			for _, c := range resp.Result().Cookies() {
+				userRequest.AddCookie(c)
+			}
+		})
+
+		It("lists the metadata of the sessions", func() {
+			list, err := admin.ListSessions(in.request.Context())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(list).To(HaveLen(1))
+			Expect(list[0].ID).ToNot(BeEmpty())
+			Expect(list[0].User).To(Equal(in.session.User))
+			Expect(list[0].Email).To(Equal(in.session.Email))
+			Expect(list[0].CreatedAt).ToNot(BeNil())
+			Expect(list[0].ExpiresAt).To(BeTemporally("~", time.Now().Add(in.cookieOpts.Expire), time.Minute))
+			Expect(list[0].ClientIP).ToNot(BeEmpty())
+			Expect(list[0].UserAgent).To(Equal("test-agent"))
+		})
+
+		It("clears a session by its ID", func() {
+			list, err := admin.ListSessions(in.request.Context())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(list).To(HaveLen(1))
+
+			Expect(admin.ClearSession(in.request.Context(), list[0].ID)).To(Succeed())
+			_, err = in.ss().Load(userRequest)
+			Expect(err).To(HaveOccurred())
+			Expect(admin.ListSessions(in.request.Context())).To(BeEmpty())
+		})
+
+		It("does not clear unknown sessions", func() {
+			Expect(admin.ClearSession(in.request.Context(), "unknown")).To(MatchError(sessionsapi.ErrSessionNotFound))
+		})
+
+		It("does not list the sessions cleared by the user", func() {
+			Expect(in.ss().Clear(httptest.NewRecorder(), userRequest)).To(Succeed())
+			Expect(admin.ListSessions(in.request.Context())).To(BeEmpty())
+		})
+	})
+
 	// Test TTLs and cleanup of persistent session storage
 	// For non-persistent we rely on the browser cookie lifecycle
 	Context("when Load is called on a persistent store", func() {
@@ -550,7 +677,7 @@ func LoadSessionTests(in *testInput) {
 		s.CreatedAt = nil
 		s.ExpiresOn = nil
 		s.Lock = &sessionsapi.NoOpLock{}
-		if in.sessionOpts.Type == "jwt" {
+		if in.sessionOpts.Type == "jwt" && !in.sessionOpts.JWT.JWTTokens {
 			s.AccessToken = ""
 			s.IDToken = ""
 			s.RefreshToken = ""
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/version"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName identifies the spans created by OAuth2 Proxy itself
+	instrumentationName = "github.com/oauth2-proxy/oauth2-proxy/v7"
+
+	// defaultTracesPath is the OTLP/HTTP path traces are sent to when the
+	// configured endpoint has no path
+	defaultTracesPath = "/v1/traces"
+)
+
+// Init configures the global tracer provider to export spans to the
+// OTLP/HTTP collector at endpoint, and the W3C trace context propagator used
+// to continue traces from clients and propagate them to upstreams.
+// Tracing stays disabled when the endpoint is empty.
+// The returned function flushes the pending spans and must be called before exiting.
+func Init(ctx context.Context, endpoint, serviceName string, sampleRatio float64) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpointURL, err := tracesURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL))
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP trace exporter: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version.VERSION),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("could not create tracing resource: %v", err)
+	}
+
+	return setup(sdktrace.NewBatchSpanProcessor(exporter), res, sampleRatio), nil
+}
+
+// setup installs a tracer provider handing the ended spans to the processor
+// as the global one
+func setup(processor sdktrace.SpanProcessor, res *resource.Resource, sampleRatio float64) func(context.Context) error {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp.Shutdown
+}
+
+// tracesURL adds the default OTLP traces path to endpoints without a path,
+// so that the collector can be given as its base URL
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("could not parse tracing endpoint %q: %v", endpoint, err)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = defaultTracesPath
+	}
+	return u.String(), nil
+}
+
+// StartSpan starts a span as a child of the span in the context.
+// When tracing is disabled the span is a no-op.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartClientSpan starts a span for a call to a remote service as a child of
+// the span in the context
+func StartClientSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// EndSpan ends the span, marking it as failed when err is not nil
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject sets the W3C trace context headers of the span in the context on
+// the headers, so that the receiver continues the trace
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// NewHandler wraps the handler to start a server span for each request,
+// continuing the trace given in the W3C trace context headers of the request.
+func NewHandler(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "oauth2-proxy",
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return req.Method
+		}),
+	)
+}
+
+// NewTransport wraps the transport to start a client span for each outgoing
+// request and to propagate the trace context to the server.
+// The global tracer provider delegates to the one installed by Init, so
+// transports created before Init are traced as well.
+func NewTransport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next)
+}
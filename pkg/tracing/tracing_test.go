@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testCollector is a stand-in for an OTLP/HTTP collector that records the
+// names of the spans it receives, by service name
+type testCollector struct {
+	mu    sync.Mutex
+	paths []string
+	spans map[string][]string
+}
+
+func (c *testCollector) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	export := &coltracepb.ExportTraceServiceRequest{}
+	Expect(proto.Unmarshal(body, export)).To(Succeed())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = append(c.paths, req.URL.Path)
+	for _, resourceSpans := range export.ResourceSpans {
+		var service string
+		for _, attr := range resourceSpans.Resource.Attributes {
+			if attr.Key == "service.name" {
+				service = attr.Value.GetStringValue()
+			}
+		}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				c.spans[service] = append(c.spans[service], span.Name)
+			}
+		}
+	}
+
+	rw.Header().Set("Content-Type", "application/x-protobuf")
+	rw.WriteHeader(http.StatusOK)
+}
+
+var _ = Describe("Tracing", func() {
+	AfterEach(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	Context("Init", func() {
+		It("exports spans to the collector and propagates the trace context", func() {
+			collector := &testCollector{spans: map[string][]string{}}
+			collectorServer := httptest.NewServer(collector)
+			defer collectorServer.Close()
+
+			var traceparent string
+			backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				traceparent = req.Header.Get("traceparent")
+			}))
+			defer backend.Close()
+
+			shutdown, err := Init(context.Background(), collectorServer.URL, "oauth2-proxy-test", 1.0)
+			Expect(err).ToNot(HaveOccurred())
+
+			ctx, span := StartSpan(context.Background(), "provider.redeem")
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+			client := &http.Client{Transport: NewTransport(http.DefaultTransport)}
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.Body.Close()).To(Succeed())
+			span.End()
+
+			Expect(shutdown(context.Background())).To(Succeed())
+
+			Expect(traceparent).To(ContainSubstring(span.SpanContext().TraceID().String()))
+			Expect(collector.paths).To(ConsistOf("/v1/traces"))
+			Expect(collector.spans).To(HaveKeyWithValue("oauth2-proxy-test", ConsistOf("provider.redeem", "HTTP GET")))
+		})
+
+		It("does not trace without a collector endpoint", func() {
+			shutdown, err := Init(context.Background(), "", "oauth2-proxy-test", 1.0)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, span := StartSpan(context.Background(), "provider.redeem")
+			Expect(span.IsRecording()).To(BeFalse())
+			span.End()
+
+			header := http.Header{}
+			Inject(context.Background(), header)
+			Expect(header).To(BeEmpty())
+
+			Expect(shutdown(context.Background())).To(Succeed())
+		})
+	})
+
+	DescribeTable("tracesURL",
+		func(endpoint string, expected string) {
+			u, err := tracesURL(endpoint)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u).To(Equal(expected))
+		},
+		Entry("with a base URL", "http://localhost:4318", "http://localhost:4318/v1/traces"),
+		Entry("with a trailing slash", "http://localhost:4318/", "http://localhost:4318/v1/traces"),
+		Entry("with a custom path", "https://collector.example.com/otlp/v1/traces", "https://collector.example.com/otlp/v1/traces"),
+	)
+
+	It("continues the trace of the client in the server span", func() {
+		recorder := tracetest.NewSpanRecorder()
+		shutdown := setup(recorder, resource.Default(), 1.0)
+
+		var childTraceID string
+		handler := NewHandler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, span := StartSpan(req.Context(), "upstream.proxy")
+			EndSpan(span, errors.New("upstream failed"))
+			childTraceID = span.SpanContext().TraceID().String()
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		Expect(shutdown(context.Background())).To(Succeed())
+
+		Expect(childTraceID).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name()).To(Equal("upstream.proxy"))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+		Expect(spans[1].Name()).To(Equal("GET"))
+		Expect(spans[1].Parent().SpanID().String()).To(Equal("00f067aa0ba902b7"))
+	})
+})
@@ -17,6 +17,11 @@ import (
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/middleware"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	"golang.org/x/net/websocket"
 )
 
@@ -371,6 +376,43 @@ var _ = Describe("HTTP Upstream Suite", func() {
 		Expect(req.Host).To(Equal(strings.TrimPrefix(serverAddr, "http://")))
 	})
 
+	It("ServeHTTP, propagates the trace context to the upstream", func() {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		defer func() {
+			otel.SetTracerProvider(noop.NewTracerProvider())
+			otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+		}()
+
+		req := httptest.NewRequest("", "http://example.localhost/foo", nil)
+		req = middlewareapi.AddRequestScope(req, &middlewareapi.RequestScope{})
+		rw := httptest.NewRecorder()
+
+		upstream := options.Upstream{
+			ID:              "traced",
+			ProxyWebSockets: &falsum,
+			FlushInterval:   &defaultFlushInterval,
+			Timeout:         &defaultTimeout,
+		}
+
+		u, err := url.Parse(serverAddr)
+		Expect(err).ToNot(HaveOccurred())
+
+		handler := newHTTPUpstreamProxy(upstream, u, nil, nil)
+		handler.ServeHTTP(rw, req)
+		Expect(rw.Code).To(Equal(http.StatusOK))
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name()).To(Equal("upstream.proxy"))
+
+		request := testHTTPRequest{}
+		Expect(json.Unmarshal(rw.Body.Bytes(), &request)).To(Succeed())
+		Expect(request.Header.Get("Traceparent")).To(Equal(fmt.Sprintf("00-%s-%s-01",
+			spans[0].SpanContext().TraceID(), spans[0].SpanContext().SpanID())))
+	})
+
 	type newUpstreamTableInput struct {
 		proxyWebSockets   bool
 		flushInterval     options.Duration
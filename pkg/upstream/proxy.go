@@ -61,6 +61,12 @@ func NewProxy(upstreams options.UpstreamConfig, sigData *options.SignatureData,
 	return m, nil
 }
 
+// Matcher finds the upstream a request will be proxied to, so that the
+// request can be prepared for the upstream before it is proxied.
+type Matcher interface {
+	MatchUpstream(req *http.Request) string
+}
+
 // multiUpstreamProxy will serve requests directed to multiple upstream servers
 // registered in the serverMux.
 type multiUpstreamProxy struct {
@@ -72,6 +78,17 @@ func (m *multiUpstreamProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request
 	m.serveMux.ServeHTTP(rw, req)
 }
 
+// MatchUpstream returns the ID of the upstream the request will be proxied
+// to, or an empty string if no upstream matches the request.
+func (m *multiUpstreamProxy) MatchUpstream(req *http.Request) string {
+	match := &mux.RouteMatch{}
+	if !m.serveMux.Match(req, match) || match.Route == nil {
+		return ""
+	}
+	// Only the upstream routes are named
+	return match.Route.GetName()
+}
+
 // registerStaticResponseHandler registers a static response handler with at the given path.
 func (m *multiUpstreamProxy) registerStaticResponseHandler(upstream options.Upstream, writer pagewriter.Writer) error {
 	logger.Printf("mapping path %q => static response %d", upstream.Path, derefStaticCode(upstream.StaticCode))
@@ -93,7 +110,7 @@ func (m *multiUpstreamProxy) registerHTTPUpstreamProxy(upstream options.Upstream
 // registerHandler ensures the given handler is regiestered with the serveMux.
 func (m *multiUpstreamProxy) registerHandler(upstream options.Upstream, handler http.Handler, writer pagewriter.Writer) error {
 	if upstream.RewriteTarget == "" {
-		m.registerSimpleHandler(upstream.Path, handler)
+		m.registerSimpleHandler(upstream.ID, upstream.Path, handler)
 		return nil
 	}
 
@@ -102,11 +119,11 @@ func (m *multiUpstreamProxy) registerHandler(upstream options.Upstream, handler
 
 // registerSimpleHandler maintains the behaviour of the go standard serveMux
 // by ensuring any path with a trailing `/` matches all paths under that prefix.
-func (m *multiUpstreamProxy) registerSimpleHandler(path string, handler http.Handler) {
+func (m *multiUpstreamProxy) registerSimpleHandler(id string, path string, handler http.Handler) {
 	if strings.HasSuffix(path, "/") {
-		m.serveMux.PathPrefix(path).Handler(handler)
+		m.serveMux.PathPrefix(path).Name(id).Handler(handler)
 	} else {
-		m.serveMux.Path(path).Handler(handler)
+		m.serveMux.Path(path).Name(id).Handler(handler)
 	}
 }
 
@@ -124,7 +141,7 @@ func (m *multiUpstreamProxy) registerRewriteHandler(upstream options.Upstream, h
 	h := alice.New(rewrite).Then(handler)
 	m.serveMux.MatcherFunc(func(req *http.Request, _ *mux.RouteMatch) bool {
 		return rewriteRegExp.MatchString(req.URL.Path)
-	}).Handler(h)
+	}).Name(upstream.ID).Handler(h)
 
 	return nil
 }
@@ -122,6 +122,9 @@ var _ = Describe("Proxy Suite", func() {
 				// Don't mock the remote Address
 				req.RemoteAddr = ""
 
+				// The upstream is known before the request is proxied
+				Expect(upstreamServer.(Matcher).MatchUpstream(req)).To(Equal(in.upstream))
+
 				upstreamServer.ServeHTTP(rw, req)
 
 				scope := middlewareapi.GetRequestScope(req)
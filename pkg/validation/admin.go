@@ -0,0 +1,24 @@
+package validation
+
+import (
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+)
+
+// validateAdminAPI ensures the session administration API is restricted to
+// admin groups and backed by a session store that can list its sessions
+func validateAdminAPI(o *options.Options) []string {
+	if o.AdminAPI.BindAddress == "" {
+		return []string{}
+	}
+
+	msgs := []string{}
+	if len(o.AdminAPI.AllowedGroups) == 0 {
+		msgs = append(msgs, "admin-allowed-group is required with admin-address")
+	}
+	switch o.Session.Type {
+	case options.RedisSessionStoreType, options.FileSessionStoreType, options.SQLSessionStoreType:
+	default:
+		msgs = append(msgs, "admin-address requires the redis, file or sql session store")
+	}
+	return msgs
+}
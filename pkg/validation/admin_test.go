@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admin API", func() {
+	DescribeTable("validateAdminAPI",
+		func(adminAPI options.AdminAPI, sessionType string, errStrings []string) {
+			o := &options.Options{
+				AdminAPI: adminAPI,
+				Session:  options.SessionOptions{Type: sessionType},
+			}
+			Expect(validateAdminAPI(o)).To(ConsistOf(errStrings))
+		},
+		Entry("with the admin API disabled", options.AdminAPI{}, options.CookieSessionStoreType, []string{}),
+		Entry("with the redis session store", options.AdminAPI{
+			BindAddress:   ":4181",
+			AllowedGroups: []string{"admins"},
+		}, options.RedisSessionStoreType, []string{}),
+		Entry("with the file session store", options.AdminAPI{
+			BindAddress:   ":4181",
+			AllowedGroups: []string{"admins"},
+		}, options.FileSessionStoreType, []string{}),
+		Entry("with the sql session store", options.AdminAPI{
+			BindAddress:   ":4181",
+			AllowedGroups: []string{"admins"},
+		}, options.SQLSessionStoreType, []string{}),
+		Entry("without admin groups", options.AdminAPI{
+			BindAddress: ":4181",
+		}, options.RedisSessionStoreType, []string{
+			"admin-allowed-group is required with admin-address",
+		}),
+		Entry("with the cookie session store", options.AdminAPI{
+			BindAddress:   ":4181",
+			AllowedGroups: []string{"admins"},
+		}, options.CookieSessionStoreType, []string{
+			"admin-address requires the redis, file or sql session store",
+		}),
+	)
+})
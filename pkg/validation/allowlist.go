@@ -16,6 +16,7 @@ func validateAllowlists(o *options.Options) []string {
 	msgs = append(msgs, validateAuthRoutes(o)...)
 	msgs = append(msgs, validateAuthRegexes(o)...)
 	msgs = append(msgs, validateTrustedIPs(o)...)
+	msgs = append(msgs, validateBackChannelLogoutTrustedIPs(o)...)
 
 	if len(o.TrustedIPs) > 0 && o.ReverseProxy {
 		_, err := fmt.Fprintln(os.Stderr, "WARNING: mixing --trusted-ip with --reverse-proxy is a potential security vulnerability. An attacker can inject a trusted IP into an X-Real-IP or X-Forwarded-For header if they aren't properly protected outside of oauth2-proxy")
@@ -62,6 +63,18 @@ func validateTrustedIPs(o *options.Options) []string {
 	return msgs
 }
 
+// validateBackChannelLogoutTrustedIPs validates the IP/CIDRs allowed to send
+// unsigned back-channel logout requests
+func validateBackChannelLogoutTrustedIPs(o *options.Options) []string {
+	msgs := []string{}
+	for i, ipStr := range o.BackChannelLogoutTrustedIPs {
+		if nil == ip.ParseIPNet(ipStr) {
+			msgs = append(msgs, fmt.Sprintf("backchannel_logout_trusted_ips[%d] (%s) could not be recognized", i, ipStr))
+		}
+	}
+	return msgs
+}
+
 // validateAPIRoutes validates regex paths passed with options.ApiRoutes
 func validateAPIRoutes(o *options.Options) []string {
 	return validateRegexes(o.APIRoutes)
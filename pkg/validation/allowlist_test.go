@@ -121,4 +121,23 @@ var _ = Describe("Allowlist", func() {
 			},
 		}),
 	)
+
+	DescribeTable("validateBackChannelLogoutTrustedIPs",
+		func(t *validateTrustedIPsTableInput) {
+			opts := &options.Options{
+				BackChannelLogoutTrustedIPs: t.trustedIPs,
+			}
+			Expect(validateBackChannelLogoutTrustedIPs(opts)).To(ConsistOf(t.errStrings))
+		},
+		Entry("Valid IPs", &validateTrustedIPsTableInput{
+			trustedIPs: []string{"10.32.0.1", "43.36.201.0/24", "::1"},
+			errStrings: []string{},
+		}),
+		Entry("Invalid IPs", &validateTrustedIPsTableInput{
+			trustedIPs: []string{"10.32.0.1", "sis.example.com"},
+			errStrings: []string{
+				"backchannel_logout_trusted_ips[1] (sis.example.com) could not be recognized",
+			},
+		}),
+	)
 })
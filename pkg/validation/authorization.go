@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/authorization"
+)
+
+// validateAuthorizationRules ensures the global authorization rules compile
+// and evaluate to a bool
+func validateAuthorizationRules(o *options.Options) []string {
+	msgs := []string{}
+	for i, rule := range o.AuthorizationRules {
+		if err := authorization.ValidateRule(rule); err != nil {
+			msgs = append(msgs, fmt.Sprintf("authorization_rules[%d] is invalid: %v", i, err))
+		}
+	}
+	return msgs
+}
+
+// validateAuthorizationWebhook ensures the authorization webhook has a valid
+// URL, timeout, cache TTL and failure policy
+func validateAuthorizationWebhook(o *options.Options) []string {
+	webhook := o.AuthorizationWebhook
+	if webhook.URL == "" {
+		return []string{}
+	}
+
+	msgs := []string{}
+	if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		msgs = append(msgs, fmt.Sprintf("authorization-webhook-url %q must be an http or https URL", webhook.URL))
+	}
+	if webhook.Timeout <= 0 {
+		msgs = append(msgs, "authorization-webhook-timeout must be positive")
+	}
+	if webhook.CacheTTL < 0 {
+		msgs = append(msgs, "authorization-webhook-cache-ttl must not be negative")
+	}
+	switch webhook.FailurePolicy {
+	case options.DenyWebhookFailurePolicy, options.AllowWebhookFailurePolicy:
+	default:
+		msgs = append(msgs, fmt.Sprintf("authorization-webhook-failure-policy %q is not one of %q or %q",
+			webhook.FailurePolicy, options.DenyWebhookFailurePolicy, options.AllowWebhookFailurePolicy))
+	}
+	return msgs
+}
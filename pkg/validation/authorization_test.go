@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"time"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authorization rules", func() {
+	DescribeTable("validateAuthorizationRules",
+		func(rules []string, errStrings []string) {
+			o := &options.Options{AuthorizationRules: rules}
+			Expect(validateAuthorizationRules(o)).To(ConsistOf(errStrings))
+		},
+		Entry("without rules", nil, []string{}),
+		Entry("with valid rules", []string{
+			"'admin' in groups || (tenant == 'acme' && email.endsWith('@acme.com'))",
+			"request.method == 'GET'",
+		}, []string{}),
+		Entry("with a rule that is not a bool", []string{
+			"'admin' in groups",
+			"claims.email",
+		}, []string{
+			"authorization_rules[1] is invalid: rule must evaluate to a bool, not list(string)",
+		}),
+	)
+
+	DescribeTable("validateAuthorizationWebhook",
+		func(webhook options.AuthorizationWebhook, errStrings []string) {
+			o := &options.Options{AuthorizationWebhook: webhook}
+			Expect(validateAuthorizationWebhook(o)).To(ConsistOf(errStrings))
+		},
+		Entry("with the webhook disabled", options.AuthorizationWebhook{}, []string{}),
+		Entry("with a valid webhook", options.AuthorizationWebhook{
+			URL:           "https://entitlements.example.com/authorize",
+			Timeout:       5 * time.Second,
+			FailurePolicy: options.AllowWebhookFailurePolicy,
+		}, []string{}),
+		Entry("with invalid options", options.AuthorizationWebhook{
+			URL:           "entitlements.example.com",
+			CacheTTL:      -time.Minute,
+			FailurePolicy: "ignore",
+		}, []string{
+			"authorization-webhook-url \"entitlements.example.com\" must be an http or https URL",
+			"authorization-webhook-timeout must be positive",
+			"authorization-webhook-cache-ttl must not be negative",
+			"authorization-webhook-failure-policy \"ignore\" is not one of \"deny\" or \"allow\"",
+		}),
+	)
+})
@@ -33,6 +33,24 @@ func validateCookie(o options.Cookie) []string {
 	})
 
 	msgs = append(msgs, validateCookieName(o.Name)...)
+	msgs = append(msgs, validateCookiePreviousSecrets(o.PreviousSecrets)...)
+	return msgs
+}
+
+// validateCookiePreviousSecrets ensures the previous cookie secrets can still
+// decrypt the cookies, as the cookie secret does
+func validateCookiePreviousSecrets(secrets []string) []string {
+	msgs := []string{}
+	for i, secret := range secrets {
+		secretBytes := encryption.SecretBytes(secret)
+		switch len(secretBytes) {
+		case 16, 24, 32:
+		default:
+			msgs = append(msgs, fmt.Sprintf(
+				"cookie_previous_secrets[%d] must be 16, 24, or 32 bytes to create an AES cipher, but is %d bytes",
+				i, len(secretBytes)))
+		}
+	}
 	return msgs
 }
 
@@ -318,6 +318,24 @@ func TestValidateCookie(t *testing.T) {
 			},
 			errStrings: []string{"could not read cookie secret file: /nonexistent/file.txt"},
 		},
+		{
+			name: "with previous secrets",
+			cookie: options.Cookie{
+				Name:            validName,
+				Secret:          validSecret,
+				PreviousSecrets: []string{validBase64Secret, invalidSecret},
+				Domains:         emptyDomains,
+				Path:            "",
+				Expire:          24 * time.Hour,
+				Refresh:         0,
+				Secure:          true,
+				HTTPOnly:        true,
+				SameSite:        "",
+			},
+			errStrings: []string{
+				"cookie_previous_secrets[1] must be 16, 24, or 32 bytes to create an AES cipher, but is 6 bytes",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -40,10 +40,12 @@ func validateHeader(header options.Header, names map[string]struct{}) []string {
 
 func validateHeaderValue(_ string, value options.HeaderValue) []string {
 	switch {
-	case value.SecretSource != nil && value.ClaimSource == nil:
+	case value.SecretSource != nil && value.ClaimSource == nil && value.JWT == nil:
 		return []string{validateSecretSource(*value.SecretSource)}
-	case value.SecretSource == nil && value.ClaimSource != nil:
+	case value.SecretSource == nil && value.ClaimSource != nil && value.JWT == nil:
 		return validateHeaderValueClaimSource(*value.ClaimSource)
+	case value.SecretSource == nil && value.ClaimSource == nil && value.JWT != nil:
+		return validateHeaderValueJWTSource(*value.JWT)
 	default:
 		return []string{"header value has multiple entries: only one entry per value is allowed"}
 	}
@@ -61,3 +63,31 @@ func validateHeaderValueClaimSource(claim options.ClaimSource) []string {
 	}
 	return msgs
 }
+
+func validateHeaderValueJWTSource(source options.JWTSource) []string {
+	if source.Lifetime != nil && source.Lifetime.Duration() <= 0 {
+		return []string{"jwt lifetime should be greater than 0"}
+	}
+	return []string{}
+}
+
+// validateJWTHeaders ensures that the JWTs of JWTSource values can be signed.
+// They are only minted for the upstreams, not in the responses.
+func validateJWTHeaders(o *options.Options) []string {
+	msgs := []string{}
+	for _, header := range o.InjectRequestHeaders {
+		for _, value := range header.Values {
+			if value.JWT != nil && o.Session.JWT.JWTKey == "" && o.Session.JWT.JWTKeyFile == "" {
+				msgs = append(msgs, fmt.Sprintf("injectRequestHeaders: invalid header %q: jwt values require jwt-session-key or jwt-session-key-file to be set", header.Name))
+			}
+		}
+	}
+	for _, header := range o.InjectResponseHeaders {
+		for _, value := range header.Values {
+			if value.JWT != nil {
+				msgs = append(msgs, fmt.Sprintf("injectResponseHeaders: invalid header %q: jwt values are only allowed in injectRequestHeaders", header.Name))
+			}
+		}
+	}
+	return msgs
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"encoding/base64"
+	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	. "github.com/onsi/ginkgo/v2"
@@ -50,6 +51,19 @@ var _ = Describe("Headers", func() {
 		},
 	}
 
+	negativeLifetime := options.Duration(-1 * time.Minute)
+
+	validHeader4 := options.Header{
+		Name: "X-Auth-Request-Jwt",
+		Values: []options.HeaderValue{
+			{
+				JWT: &options.JWTSource{
+					Audience: "upstream",
+				},
+			},
+		},
+	}
+
 	DescribeTable("validateHeaders",
 		func(in validateHeaderTableInput) {
 			Expect(validateHeaders(in.headers)).To(ConsistOf(in.expectedMsgs))
@@ -159,5 +173,75 @@ var _ = Describe("Headers", func() {
 				"invalid header \"With-Invalid-Basic-Auth\": invalid values: invalid basicAuthPassword: error loading secret from environent: no value for for key \"UNKNOWN_ENV\"",
 			},
 		}),
+		Entry("with a header which has a claim and jwt source", validateHeaderTableInput{
+			headers: []options.Header{
+				{
+					Name: "With-Claim-And-JWT",
+					Values: []options.HeaderValue{
+						{
+							ClaimSource: &options.ClaimSource{Claim: "user"},
+							JWT:         &options.JWTSource{},
+						},
+					},
+				},
+				validHeader4,
+			},
+			expectedMsgs: []string{
+				"invalid header \"With-Claim-And-JWT\": invalid values: header value has multiple entries: only one entry per value is allowed",
+			},
+		}),
+		Entry("with a header with an invalid jwt lifetime", validateHeaderTableInput{
+			headers: []options.Header{
+				{
+					Name: "With-Invalid-Lifetime",
+					Values: []options.HeaderValue{
+						{
+							JWT: &options.JWTSource{
+								Lifetime: &negativeLifetime,
+							},
+						},
+					},
+				},
+			},
+			expectedMsgs: []string{
+				"invalid header \"With-Invalid-Lifetime\": invalid values: jwt lifetime should be greater than 0",
+			},
+		}),
+	)
+
+	type validateJWTHeadersTableInput struct {
+		requestHeaders  []options.Header
+		responseHeaders []options.Header
+		jwtKey          string
+		expectedMsgs    []string
+	}
+
+	DescribeTable("validateJWTHeaders",
+		func(in validateJWTHeadersTableInput) {
+			o := &options.Options{
+				InjectRequestHeaders:  in.requestHeaders,
+				InjectResponseHeaders: in.responseHeaders,
+			}
+			o.Session.JWT.JWTKey = in.jwtKey
+			Expect(validateJWTHeaders(o)).To(ConsistOf(in.expectedMsgs))
+		},
+		Entry("with a jwt request header and a key", validateJWTHeadersTableInput{
+			requestHeaders: []options.Header{validHeader4, validHeader1},
+			jwtKey:         "key",
+			expectedMsgs:   []string{},
+		}),
+		Entry("with a jwt request header without a key", validateJWTHeadersTableInput{
+			requestHeaders: []options.Header{validHeader4},
+			expectedMsgs: []string{
+				"injectRequestHeaders: invalid header \"X-Auth-Request-Jwt\": jwt values require jwt-session-key or jwt-session-key-file to be set",
+			},
+		}),
+		Entry("with a jwt response header", validateJWTHeadersTableInput{
+			responseHeaders: []options.Header{validHeader4},
+			jwtKey:          "key",
+			expectedMsgs: []string{
+				"injectResponseHeaders: invalid header \"X-Auth-Request-Jwt\": jwt values are only allowed in injectRequestHeaders",
+			},
+		}),
 	)
 })
@@ -21,15 +21,27 @@ import (
 // are of the correct format
 func Validate(o *options.Options) error {
 	msgs := validateCookie(o.Cookie)
-	if o.Session.Type != "jwt" {
+	// The JWT session store only needs the cookie secret to encrypt the tokens
+	if o.Session.Type != "jwt" || o.Session.JWT.JWTTokens {
 		msgs = append(msgs, validateCookieSecret(o.Cookie.Secret, o.Cookie.SecretFile)...)
 	}
 	msgs = append(msgs, validateSessionCookieMinimal(o)...)
 	msgs = append(msgs, validateRedisSessionStore(o)...)
+	msgs = append(msgs, validateFileSessionStore(o)...)
+	msgs = append(msgs, validateSQLSessionStore(o)...)
+	msgs = append(msgs, validateSessionTimeouts(o)...)
+	msgs = append(msgs, validateSessionLimit(o)...)
+	msgs = append(msgs, validateSessionBinding(o)...)
 	msgs = append(msgs, prefixValues("injectRequestHeaders: ", validateHeaders(o.InjectRequestHeaders)...)...)
 	msgs = append(msgs, prefixValues("injectResponseHeaders: ", validateHeaders(o.InjectResponseHeaders)...)...)
+	msgs = append(msgs, validateJWTHeaders(o)...)
 	msgs = append(msgs, validateProviders(o)...)
 	msgs = append(msgs, validateAPIRoutes(o)...)
+	msgs = append(msgs, validateTracing(o.Tracing)...)
+	msgs = append(msgs, validateTokenIntrospection(o.TokenIntrospection)...)
+	msgs = append(msgs, validateAdminAPI(o)...)
+	msgs = append(msgs, validateAuthorizationRules(o)...)
+	msgs = append(msgs, validateAuthorizationWebhook(o)...)
 	msgs = configureLogger(o.Logging, msgs)
 	msgs = parseSignatureKey(o, msgs)
 
@@ -73,6 +85,14 @@ func Validate(o *options.Options) error {
 		}
 	}
 
+	if o.SkipOpaqueBearerTokens && o.TokenIntrospection.URL == "" && len(o.Providers) > 0 && o.Providers[0].Type != options.SISProvider {
+		msgs = append(msgs, "skip-opaque-bearer-tokens requires the sis provider or a token-introspection-url")
+	}
+	// The introspection endpoint is only called for the bearer tokens accepted
+	if o.TokenIntrospection.URL != "" && !o.SkipJwtBearerTokens && !o.SkipOpaqueBearerTokens {
+		msgs = append(msgs, "token-introspection-url requires skip-jwt-bearer-tokens or skip-opaque-bearer-tokens")
+	}
+
 	var redirectURL *url.URL
 	redirectURL, msgs = parseURL(o.RawRedirectURL, "redirect", msgs)
 	o.SetRedirectURL(redirectURL)
@@ -129,6 +129,47 @@ func TestCookieRefreshMustBeLessThanCookieExpire(t *testing.T) {
 	assert.Equal(t, nil, Validate(o))
 }
 
+func TestJWTSessionStoreCookieSecret(t *testing.T) {
+	o := testOptions()
+	o.Cookie.Secret = ""
+	o.Session.Type = options.JWTSessionStoreType
+	assert.Equal(t, nil, Validate(o))
+
+	// The cookie secret encrypts the tokens stored in the JWT
+	o.Session.JWT.JWTTokens = true
+	assert.Equal(t, errorMsg([]string{"missing setting: cookie-secret or cookie-secret-file"}), Validate(o).Error())
+
+	o.Cookie.Secret = cookieSecret
+	assert.Equal(t, nil, Validate(o))
+}
+
+func TestSkipOpaqueBearerTokensRequiresSISOrIntrospection(t *testing.T) {
+	o := testOptions()
+	o.SkipOpaqueBearerTokens = true
+	assert.Equal(t, errorMsg([]string{"skip-opaque-bearer-tokens requires the sis provider or a token-introspection-url"}), Validate(o).Error())
+
+	o = testOptions()
+	o.SkipOpaqueBearerTokens = true
+	o.Providers[0].Type = options.SISProvider
+	assert.Equal(t, nil, Validate(o))
+
+	o = testOptions()
+	o.SkipOpaqueBearerTokens = true
+	o.TokenIntrospection.URL = "https://auth.example.com/oauth2/introspect"
+	assert.Equal(t, nil, Validate(o))
+}
+
+func TestTokenIntrospectionRequiresBearerTokens(t *testing.T) {
+	o := testOptions()
+	o.TokenIntrospection.URL = "https://auth.example.com/oauth2/introspect"
+	assert.Equal(t, errorMsg([]string{"token-introspection-url requires skip-jwt-bearer-tokens or skip-opaque-bearer-tokens"}), Validate(o).Error())
+
+	o = testOptions()
+	o.SkipJwtBearerTokens = true
+	o.TokenIntrospection.URL = "https://auth.example.com/oauth2/introspect"
+	assert.Equal(t, nil, Validate(o))
+}
+
 func TestBase64CookieSecret(t *testing.T) {
 	o := testOptions()
 	assert.Equal(t, nil, Validate(o))
@@ -86,3 +86,116 @@ func sendRedisConnectionTest(client redis.Client, key string, val string) []stri
 	}
 	return msgs
 }
+
+// validateFileSessionStore ensures the file session store has a database file
+func validateFileSessionStore(o *options.Options) []string {
+	if o.Session.Type != options.FileSessionStoreType {
+		return []string{}
+	}
+
+	msgs := []string{}
+	if o.Session.File.Path == "" {
+		msgs = append(msgs, "file-session-path is required with the file session store")
+	}
+	if o.Session.File.CompactionInterval < 0 {
+		msgs = append(msgs, "file-session-compaction-interval must not be negative")
+	}
+	return msgs
+}
+
+// validateSQLSessionStore ensures the sql session store has a supported
+// database to connect to
+func validateSQLSessionStore(o *options.Options) []string {
+	if o.Session.Type != options.SQLSessionStoreType {
+		return []string{}
+	}
+
+	msgs := []string{}
+	switch o.Session.SQL.Dialect {
+	case options.PostgresSQLDialect, options.SQLiteSQLDialect:
+	default:
+		msgs = append(msgs, fmt.Sprintf("unsupported sql-session-dialect %q: must be %s or %s",
+			o.Session.SQL.Dialect, options.PostgresSQLDialect, options.SQLiteSQLDialect))
+	}
+	if o.Session.SQL.DSN == "" {
+		msgs = append(msgs, "sql-session-dsn is required with the sql session store")
+	}
+	if o.Session.SQL.CleanupInterval < 0 {
+		msgs = append(msgs, "sql-session-cleanup-interval must not be negative")
+	}
+	return msgs
+}
+
+// validateSessionTimeouts ensures the idle timeout can be detected with the
+// activity saved at the update interval
+func validateSessionTimeouts(o *options.Options) []string {
+	msgs := []string{}
+	if o.Session.MaxAge < 0 {
+		msgs = append(msgs, "session-max-age must not be negative")
+	}
+	if o.Session.IdleTimeout < 0 {
+		msgs = append(msgs, "session-idle-timeout must not be negative")
+	}
+	if o.Session.ActivityUpdateInterval < 0 {
+		msgs = append(msgs, "session-activity-update-interval must not be negative")
+	}
+	if o.Session.IdleTimeout > 0 && o.Session.ActivityUpdateInterval >= o.Session.IdleTimeout {
+		msgs = append(msgs, fmt.Sprintf("session-activity-update-interval (%s) must be shorter than session-idle-timeout (%s)",
+			o.Session.ActivityUpdateInterval, o.Session.IdleTimeout))
+	}
+	return msgs
+}
+
+// validateSessionLimit ensures the concurrent sessions of the users can be
+// found in the session store
+func validateSessionLimit(o *options.Options) []string {
+	msgs := []string{}
+	if o.Session.MaxPerUser < 0 {
+		msgs = append(msgs, "session-max-per-user must not be negative")
+	}
+	switch o.Session.LimitPolicy {
+	case options.EvictSessionLimitPolicy, options.RejectSessionLimitPolicy:
+	default:
+		msgs = append(msgs, fmt.Sprintf("session-limit-policy %q is not one of %q or %q",
+			o.Session.LimitPolicy, options.EvictSessionLimitPolicy, options.RejectSessionLimitPolicy))
+	}
+	if o.Session.MaxPerUser > 0 {
+		switch o.Session.Type {
+		case options.RedisSessionStoreType, options.FileSessionStoreType, options.SQLSessionStoreType:
+		default:
+			msgs = append(msgs, "session-max-per-user requires the redis, file or sql session store")
+		}
+	}
+	return msgs
+}
+
+// validateSessionBinding ensures the attributes the sessions are bound to can
+// be captured
+func validateSessionBinding(o *options.Options) []string {
+	msgs := []string{}
+	for _, attribute := range o.Session.Bind {
+		switch attribute {
+		case options.IPSessionBinding, options.UserAgentSessionBinding:
+		case options.ClientCertificateSessionBinding:
+			if o.Server.SecureBindAddress == "" || o.Server.SecureBindAddress == "-" {
+				msgs = append(msgs, "session-bind client-certificate requires the https-address, the client certificates are read from the TLS connection")
+			}
+		default:
+			msgs = append(msgs, fmt.Sprintf("session-bind %q is not one of %q, %q or %q", attribute,
+				options.IPSessionBinding, options.UserAgentSessionBinding, options.ClientCertificateSessionBinding))
+		}
+	}
+	if o.Session.BindIPv4Prefix < 0 || o.Session.BindIPv4Prefix > 32 {
+		msgs = append(msgs, fmt.Sprintf("session-bind-ipv4-prefix (%d) must be between 0 and 32", o.Session.BindIPv4Prefix))
+	}
+	if o.Session.BindIPv6Prefix < 0 || o.Session.BindIPv6Prefix > 128 {
+		msgs = append(msgs, fmt.Sprintf("session-bind-ipv6-prefix (%d) must be between 0 and 128", o.Session.BindIPv6Prefix))
+	}
+	switch o.Session.BindMismatchAction {
+	case options.DenySessionBindingAction, options.ReauthenticateSessionBindingAction, options.LogSessionBindingAction:
+	default:
+		msgs = append(msgs, fmt.Sprintf("session-bind-mismatch-action %q is not one of %q, %q or %q", o.Session.BindMismatchAction,
+			options.DenySessionBindingAction, options.ReauthenticateSessionBindingAction, options.LogSessionBindingAction))
+	}
+	return msgs
+}
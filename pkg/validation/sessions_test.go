@@ -406,4 +406,170 @@ var _ = Describe("Sessions", func() {
 			errStrings: []string{clusterAndSentinelMsg},
 		}),
 	)
+
+	DescribeTable("validateFileSessionStore",
+		func(sessionOpts options.SessionOptions, errStrings []string) {
+			o := &options.Options{Session: sessionOpts}
+			Expect(validateFileSessionStore(o)).To(ConsistOf(errStrings))
+		},
+		Entry("cookie sessions are skipped", options.SessionOptions{
+			Type: options.CookieSessionStoreType,
+		}, []string{}),
+		Entry("with a database file", options.SessionOptions{
+			Type: options.FileSessionStoreType,
+			File: options.FileStoreOptions{Path: "/var/lib/oauth2-proxy/sessions.db", CompactionInterval: time.Minute},
+		}, []string{}),
+		Entry("without a database file", options.SessionOptions{
+			Type: options.FileSessionStoreType,
+		}, []string{
+			"file-session-path is required with the file session store",
+		}),
+		Entry("with a negative compaction interval", options.SessionOptions{
+			Type: options.FileSessionStoreType,
+			File: options.FileStoreOptions{Path: "/var/lib/oauth2-proxy/sessions.db", CompactionInterval: -time.Minute},
+		}, []string{
+			"file-session-compaction-interval must not be negative",
+		}),
+	)
+
+	DescribeTable("validateSQLSessionStore",
+		func(sessionOpts options.SessionOptions, errStrings []string) {
+			o := &options.Options{Session: sessionOpts}
+			Expect(validateSQLSessionStore(o)).To(ConsistOf(errStrings))
+		},
+		Entry("cookie sessions are skipped", options.SessionOptions{
+			Type: options.CookieSessionStoreType,
+		}, []string{}),
+		Entry("with a postgres database", options.SessionOptions{
+			Type: options.SQLSessionStoreType,
+			SQL: options.SQLStoreOptions{
+				Dialect:         options.PostgresSQLDialect,
+				DSN:             "postgres://oauth2-proxy@localhost/sessions",
+				CleanupInterval: time.Minute,
+			},
+		}, []string{}),
+		Entry("with an unsupported dialect and no dsn", options.SessionOptions{
+			Type: options.SQLSessionStoreType,
+			SQL: options.SQLStoreOptions{
+				Dialect: "oracle",
+			},
+		}, []string{
+			`unsupported sql-session-dialect "oracle": must be postgres or sqlite`,
+			"sql-session-dsn is required with the sql session store",
+		}),
+		Entry("with a negative cleanup interval", options.SessionOptions{
+			Type: options.SQLSessionStoreType,
+			SQL: options.SQLStoreOptions{
+				Dialect:         options.SQLiteSQLDialect,
+				DSN:             "file:/var/lib/oauth2-proxy/sessions.db",
+				CleanupInterval: -time.Minute,
+			},
+		}, []string{
+			"sql-session-cleanup-interval must not be negative",
+		}),
+	)
+
+	DescribeTable("validateSessionTimeouts",
+		func(sessionOpts options.SessionOptions, errStrings []string) {
+			o := &options.Options{Session: sessionOpts}
+			Expect(validateSessionTimeouts(o)).To(ConsistOf(errStrings))
+		},
+		Entry("with the timeouts disabled", options.SessionOptions{
+			ActivityUpdateInterval: time.Minute,
+		}, []string{}),
+		Entry("with both timeouts", options.SessionOptions{
+			MaxAge:                 12 * time.Hour,
+			IdleTimeout:            30 * time.Minute,
+			ActivityUpdateInterval: time.Minute,
+		}, []string{}),
+		Entry("with negative durations", options.SessionOptions{
+			MaxAge:                 -time.Hour,
+			IdleTimeout:            -time.Minute,
+			ActivityUpdateInterval: -time.Minute,
+		}, []string{
+			"session-max-age must not be negative",
+			"session-idle-timeout must not be negative",
+			"session-activity-update-interval must not be negative",
+		}),
+		Entry("with an update interval longer than the idle timeout", options.SessionOptions{
+			IdleTimeout:            5 * time.Minute,
+			ActivityUpdateInterval: 10 * time.Minute,
+		}, []string{
+			"session-activity-update-interval (10m0s) must be shorter than session-idle-timeout (5m0s)",
+		}),
+	)
+	DescribeTable("validateSessionLimit",
+		func(sessionOpts options.SessionOptions, errStrings []string) {
+			o := &options.Options{Session: sessionOpts}
+			Expect(validateSessionLimit(o)).To(ConsistOf(errStrings))
+		},
+		Entry("without a limit", options.SessionOptions{
+			Type:        options.CookieSessionStoreType,
+			LimitPolicy: options.EvictSessionLimitPolicy,
+		}, []string{}),
+		Entry("with a limit and the redis session store", options.SessionOptions{
+			Type:        options.RedisSessionStoreType,
+			MaxPerUser:  3,
+			LimitPolicy: options.RejectSessionLimitPolicy,
+		}, []string{}),
+		Entry("with a limit and the cookie session store", options.SessionOptions{
+			Type:        options.CookieSessionStoreType,
+			MaxPerUser:  3,
+			LimitPolicy: options.EvictSessionLimitPolicy,
+		}, []string{
+			"session-max-per-user requires the redis, file or sql session store",
+		}),
+		Entry("with a negative limit and an unknown policy", options.SessionOptions{
+			Type:        options.SQLSessionStoreType,
+			MaxPerUser:  -1,
+			LimitPolicy: "oldest",
+		}, []string{
+			"session-max-per-user must not be negative",
+			`session-limit-policy "oldest" is not one of "evict" or "reject"`,
+		}),
+	)
+	DescribeTable("validateSessionBinding",
+		func(o *options.Options, errStrings []string) {
+			Expect(validateSessionBinding(o)).To(ConsistOf(errStrings))
+		},
+		Entry("with the defaults", &options.Options{
+			Session: options.SessionOptions{
+				BindIPv4Prefix:     32,
+				BindIPv6Prefix:     128,
+				BindMismatchAction: options.DenySessionBindingAction,
+			},
+		}, []string{}),
+		Entry("with every attribute and an https address", &options.Options{
+			Server: options.Server{SecureBindAddress: ":443"},
+			Session: options.SessionOptions{
+				Bind:               []string{options.IPSessionBinding, options.UserAgentSessionBinding, options.ClientCertificateSessionBinding},
+				BindIPv4Prefix:     24,
+				BindIPv6Prefix:     64,
+				BindMismatchAction: options.ReauthenticateSessionBindingAction,
+			},
+		}, []string{}),
+		Entry("with the client certificate and no https address", &options.Options{
+			Session: options.SessionOptions{
+				Bind:               []string{options.ClientCertificateSessionBinding},
+				BindIPv4Prefix:     32,
+				BindIPv6Prefix:     128,
+				BindMismatchAction: options.LogSessionBindingAction,
+			},
+		}, []string{
+			"session-bind client-certificate requires the https-address, the client certificates are read from the TLS connection",
+		}),
+		Entry("with invalid values", &options.Options{
+			Session: options.SessionOptions{
+				Bind:               []string{"cookie"},
+				BindIPv4Prefix:     33,
+				BindIPv6Prefix:     -1,
+				BindMismatchAction: "block",
+			},
+		}, []string{
+			`session-bind "cookie" is not one of "ip", "user-agent" or "client-certificate"`,
+			"session-bind-ipv4-prefix (33) must be between 0 and 32",
+			"session-bind-ipv6-prefix (-1) must be between 0 and 128",
+			`session-bind-mismatch-action "block" is not one of "deny", "reauthenticate" or "log"`,
+		}),
+	)
 })
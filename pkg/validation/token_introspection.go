@@ -0,0 +1,29 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+)
+
+// validateTokenIntrospection ensures the introspection endpoint is an
+// HTTP(S) URL when bearer tokens are introspected
+func validateTokenIntrospection(o options.TokenIntrospection) []string {
+	if o.URL == "" {
+		return []string{}
+	}
+
+	msgs := []string{}
+	endpoint, err := url.Parse(o.URL)
+	if err != nil {
+		msgs = append(msgs, fmt.Sprintf("error parsing token-introspection-url=%q %s", o.URL, err))
+	} else if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		msgs = append(msgs, fmt.Sprintf("token-introspection-url=%q must be an http or https URL", o.URL))
+	}
+
+	if o.GroupsClaim == "" {
+		msgs = append(msgs, "token-introspection-groups-claim must not be empty")
+	}
+	return msgs
+}
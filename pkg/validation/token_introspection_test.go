@@ -0,0 +1,31 @@
+package validation
+
+import (
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Introspection", func() {
+	DescribeTable("validateTokenIntrospection",
+		func(o options.TokenIntrospection, errStrings []string) {
+			Expect(validateTokenIntrospection(o)).To(ConsistOf(errStrings))
+		},
+		Entry("with token introspection disabled", options.TokenIntrospection{}, []string{}),
+		Entry("with a valid introspection endpoint", options.TokenIntrospection{
+			URL:         "https://auth.example.com/oauth2/introspect",
+			GroupsClaim: "groups",
+		}, []string{}),
+		Entry("with an introspection endpoint that is not an HTTP URL", options.TokenIntrospection{
+			URL:         "auth.example.com/oauth2/introspect",
+			GroupsClaim: "groups",
+		}, []string{
+			"token-introspection-url=\"auth.example.com/oauth2/introspect\" must be an http or https URL",
+		}),
+		Entry("without a groups claim", options.TokenIntrospection{
+			URL: "https://auth.example.com/oauth2/introspect",
+		}, []string{
+			"token-introspection-groups-claim must not be empty",
+		}),
+	)
+})
@@ -0,0 +1,29 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+)
+
+// validateTracing ensures the collector endpoint is an HTTP(S) URL and the
+// sample ratio is a valid probability when tracing is enabled
+func validateTracing(o options.Tracing) []string {
+	if o.OTLPEndpoint == "" {
+		return []string{}
+	}
+
+	msgs := []string{}
+	endpoint, err := url.Parse(o.OTLPEndpoint)
+	if err != nil {
+		msgs = append(msgs, fmt.Sprintf("error parsing tracing-otlp-endpoint=%q %s", o.OTLPEndpoint, err))
+	} else if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		msgs = append(msgs, fmt.Sprintf("tracing-otlp-endpoint=%q must be an http or https URL", o.OTLPEndpoint))
+	}
+
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		msgs = append(msgs, fmt.Sprintf("tracing-sample-ratio=%v must be between 0 and 1", o.SampleRatio))
+	}
+	return msgs
+}
@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	DescribeTable("validateTracing",
+		func(o options.Tracing, errStrings []string) {
+			Expect(validateTracing(o)).To(ConsistOf(errStrings))
+		},
+		Entry("with tracing disabled", options.Tracing{
+			SampleRatio: 2,
+		}, []string{}),
+		Entry("with a valid collector endpoint", options.Tracing{
+			OTLPEndpoint: "http://localhost:4318",
+			SampleRatio:  0.5,
+		}, []string{}),
+		Entry("with a collector endpoint that is not an HTTP URL", options.Tracing{
+			OTLPEndpoint: "localhost:4318",
+			SampleRatio:  1,
+		}, []string{
+			"tracing-otlp-endpoint=\"localhost:4318\" must be an http or https URL",
+		}),
+		Entry("with an invalid sample ratio", options.Tracing{
+			OTLPEndpoint: "https://collector.example.com/v1/traces",
+			SampleRatio:  1.5,
+		}, []string{
+			"tracing-sample-ratio=1.5 must be between 0 and 1",
+		}),
+	)
+})
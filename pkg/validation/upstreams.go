@@ -5,6 +5,7 @@ import (
 	"net/url"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/authorization"
 )
 
 func validateUpstreams(upstreams options.UpstreamConfig) []string {
@@ -45,6 +46,28 @@ func validateUpstream(upstream options.Upstream, ids, paths map[string]struct{})
 
 	msgs = append(msgs, validateUpstreamURI(upstream)...)
 	msgs = append(msgs, validateStaticUpstream(upstream)...)
+	msgs = append(msgs, validateUpstreamAuthorization(upstream)...)
+	return msgs
+}
+
+// validateUpstreamAuthorization checks that the required claims of the
+// upstream name the claim they require, and that its rules compile.
+func validateUpstreamAuthorization(upstream options.Upstream) []string {
+	msgs := []string{}
+	if upstream.Authorization == nil {
+		return msgs
+	}
+
+	for i, requirement := range upstream.Authorization.RequiredClaims {
+		if requirement.Claim == "" {
+			msgs = append(msgs, fmt.Sprintf("upstream %q has requiredClaims[%d] with empty claim: claims are required for all required claims", upstream.ID, i))
+		}
+	}
+	for i, rule := range upstream.Authorization.Rules {
+		if err := authorization.ValidateRule(rule); err != nil {
+			msgs = append(msgs, fmt.Sprintf("upstream %q has invalid rules[%d]: %v", upstream.ID, i, err))
+		}
+	}
 	return msgs
 }
 
@@ -47,6 +47,8 @@ var _ = Describe("Upstreams", func() {
 	multipleIDsMsg := "multiple upstreams found with id \"foo\": upstream ids must be unique"
 	multiplePathsMsg := "multiple upstreams found with path \"/foo\": upstream paths must be unique"
 	staticCodeMsg := "upstream \"foo\" has staticCode (200), but is not a static upstream, set 'static' for a static response"
+	invalidRuleMsg := "upstream \"foo\" has invalid rules[1]: rule must evaluate to a bool, not list(string)"
+	emptyClaimMsg := "upstream \"foo\" has requiredClaims[1] with empty claim: claims are required for all required claims"
 
 	DescribeTable("validateUpstreams",
 		func(o *validateUpstreamTableInput) {
@@ -207,5 +209,24 @@ var _ = Describe("Upstreams", func() {
 			},
 			errStrings: []string{emptyURIMsg, staticCodeMsg},
 		}),
+		Entry("with a required claim without a claim and an invalid rule", &validateUpstreamTableInput{
+			upstreams: options.UpstreamConfig{
+				Upstreams: []options.Upstream{
+					{
+						ID:   "foo",
+						Path: "/foo",
+						URI:  "http://localhost:8080",
+						Authorization: &options.UpstreamAuthorization{
+							RequiredClaims: []options.ClaimRequirement{
+								{Claim: "preferred_username"},
+								{Values: []string{"admin"}},
+							},
+							Rules: []string{"'admin' in groups", "groups"},
+						},
+					},
+				},
+			},
+			errStrings: []string{emptyClaimMsg, invalidRuleMsg},
+		}),
 	)
 })
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// backChannelLogoutEvent is the event every OpenID Connect Back-Channel
+// Logout token must contain
+const backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// BackChannelLogout identifies the sessions ended by the provider in a
+// back-channel logout request
+type BackChannelLogout struct {
+	// User whose sessions have been ended
+	User string
+	// SessionID of the session ended at the provider.
+	// When it is empty every session of the User has been ended.
+	SessionID string
+	// Unsigned is set when the logout request could not be verified, it is
+	// then only accepted from the back-channel logout trusted IPs
+	Unsigned bool
+}
+
+// ParseBackChannelLogout validates an OpenID Connect Back-Channel Logout
+// request, the `logout_token` is verified like ID tokens are
+func (p *ProviderData) ParseBackChannelLogout(ctx context.Context, req *http.Request) (*BackChannelLogout, error) {
+	rawLogoutToken := req.PostFormValue("logout_token")
+	if rawLogoutToken == "" {
+		return nil, errors.New("missing logout_token")
+	}
+	if p.Verifier == nil {
+		return nil, ErrMissingOIDCVerifier
+	}
+
+	token, err := p.Verifier.Verify(ctx, rawLogoutToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logout_token: %v", err)
+	}
+
+	var claims struct {
+		SessionID string                     `json:"sid"`
+		Events    map[string]json.RawMessage `json:"events"`
+		Nonce     *string                    `json:"nonce"`
+	}
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid logout_token claims: %v", err)
+	}
+
+	switch {
+	case claims.Events[backChannelLogoutEvent] == nil:
+		return nil, errors.New("logout_token is missing the back-channel logout event")
+	case claims.Nonce != nil:
+		// Prevents ID tokens from being used as logout tokens
+		return nil, errors.New("logout_token must not contain a nonce")
+	case token.Subject == "" && claims.SessionID == "":
+		return nil, errors.New("logout_token must contain a sub or a sid")
+	}
+
+	return &BackChannelLogout{
+		User:      token.Subject,
+		SessionID: claims.SessionID,
+	}, nil
+}
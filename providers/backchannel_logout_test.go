@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	internaloidc "github.com/oauth2-proxy/oauth2-proxy/v7/pkg/providers/oidc"
+	. "github.com/onsi/gomega"
+)
+
+type logoutTokenClaims struct {
+	Events    map[string]interface{} `json:"events,omitempty"`
+	SessionID string                 `json:"sid,omitempty"`
+	Nonce     string                 `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func newBackChannelLogoutRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/backchannel_logout", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func newBackChannelLogoutTestProvider() *ProviderData {
+	return &ProviderData{
+		Verifier: internaloidc.NewVerifier(oidc.NewVerifier(
+			oidcIssuer,
+			mockJWKS{},
+			&oidc.Config{ClientID: oidcClientID},
+		), internaloidc.IDTokenVerificationOptions{
+			AudienceClaims: []string{"aud"},
+			ClientID:       oidcClientID,
+		}),
+	}
+}
+
+func TestProviderData_ParseBackChannelLogout(t *testing.T) {
+	logoutEvent := map[string]interface{}{backChannelLogoutEvent: map[string]interface{}{}}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	NewWithT(t).Expect(err).ToNot(HaveOccurred())
+
+	testCases := map[string]struct {
+		claims         *logoutTokenClaims
+		expectedLogout *BackChannelLogout
+		expectedError  string
+	}{
+		"with a session ID": {
+			claims: &logoutTokenClaims{
+				Events:           logoutEvent,
+				SessionID:        "session-1",
+				RegisteredClaims: registeredClaims,
+			},
+			expectedLogout: &BackChannelLogout{User: "123456789", SessionID: "session-1"},
+		},
+		"without a session ID": {
+			claims: &logoutTokenClaims{
+				Events:           logoutEvent,
+				RegisteredClaims: registeredClaims,
+			},
+			expectedLogout: &BackChannelLogout{User: "123456789"},
+		},
+		"without a logout token": {
+			expectedError: "missing logout_token",
+		},
+		"without the logout event": {
+			claims: &logoutTokenClaims{
+				SessionID:        "session-1",
+				RegisteredClaims: registeredClaims,
+			},
+			expectedError: "logout_token is missing the back-channel logout event",
+		},
+		"with a nonce": {
+			claims: &logoutTokenClaims{
+				Events:           logoutEvent,
+				Nonce:            oidcNonce,
+				RegisteredClaims: registeredClaims,
+			},
+			expectedError: "logout_token must not contain a nonce",
+		},
+		"without a subject or session ID": {
+			claims: &logoutTokenClaims{
+				Events: logoutEvent,
+				RegisteredClaims: jwt.RegisteredClaims{
+					Audience:  registeredClaims.Audience,
+					ExpiresAt: registeredClaims.ExpiresAt,
+					Issuer:    oidcIssuer,
+				},
+			},
+			expectedError: "logout_token must contain a sub or a sid",
+		},
+		"with an invalid logout token": {
+			claims: &logoutTokenClaims{
+				Events: logoutEvent,
+				RegisteredClaims: jwt.RegisteredClaims{
+					Audience:  registeredClaims.Audience,
+					ExpiresAt: registeredClaims.ExpiresAt,
+					Issuer:    failureIssuer,
+					Subject:   "123456789",
+				},
+			},
+			expectedError: "invalid logout_token",
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			g := NewWithT(t)
+
+			form := url.Values{}
+			if tc.claims != nil {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, tc.claims).SignedString(key)
+				g.Expect(err).ToNot(HaveOccurred())
+				form.Set("logout_token", token)
+			}
+
+			logout, err := newBackChannelLogoutTestProvider().ParseBackChannelLogout(context.Background(), newBackChannelLogoutRequest(form))
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(logout).To(Equal(tc.expectedLogout))
+		})
+	}
+}
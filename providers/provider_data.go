@@ -56,6 +56,10 @@ type ProviderData struct {
 	// any provider can set to consume
 	AllowedGroups map[string]struct{}
 
+	// Universal Tenant authorization data structure
+	// any provider populating SessionState.Tenant can consume
+	AllowedTenants map[string]struct{}
+
 	getAuthorizationHeaderFunc func(string) http.Header
 	loginURLParameterDefaults  url.Values
 	loginURLParameterOverrides map[string]*regexp.Regexp
@@ -186,6 +190,15 @@ func (p *ProviderData) setAllowedGroups(groups []string) {
 	}
 }
 
+// setAllowedTenants organizes a tenant list into the AllowedTenants map
+// to be consumed by Authorize implementations
+func (p *ProviderData) setAllowedTenants(tenants []string) {
+	p.AllowedTenants = make(map[string]struct{}, len(tenants))
+	for _, tenant := range tenants {
+		p.AllowedTenants[tenant] = struct{}{}
+	}
+}
+
 type providerDefaults struct {
 	name        string
 	loginURL    *url.URL
@@ -267,6 +280,7 @@ func (p *ProviderData) buildSessionFromClaims(rawIDToken, accessToken string) (*
 		{p.GroupsClaim, &ss.Groups},
 		// TODO (@NickMeves) Deprecate for dynamic claim to session mapping
 		{"preferred_username", &ss.PreferredUsername},
+		{"sid", &ss.SessionID},
 	} {
 		if _, err := extractor.GetClaimInto(c.claim, c.dst); err != nil {
 			return nil, err
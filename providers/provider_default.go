@@ -21,6 +21,10 @@ var (
 	// code
 	ErrMissingCode = errors.New("missing code")
 
+	// ErrSessionRejected is returned when the provider no longer accepts the
+	// credentials of a session, so that it must not be kept
+	ErrSessionRejected = errors.New("session rejected by provider")
+
 	// ErrMissingIDToken is returned when an oidc.Token does not contain the
 	// extra `id_token` field for an IDToken.
 	ErrMissingIDToken = errors.New("missing id_token")
@@ -121,6 +125,10 @@ func (p *ProviderData) EnrichSession(_ context.Context, _ *sessions.SessionState
 // Authorize performs global authorization on an authenticated session.
 // This is not used for fine-grained per route authorization rules.
 func (p *ProviderData) Authorize(_ context.Context, s *sessions.SessionState) (bool, error) {
+	if !p.isAllowedTenant(s.Tenant) {
+		return false, nil
+	}
+
 	if len(p.AllowedGroups) == 0 {
 		return true, nil
 	}
@@ -134,6 +142,17 @@ func (p *ProviderData) Authorize(_ context.Context, s *sessions.SessionState) (b
 	return false, nil
 }
 
+// isAllowedTenant checks the active session tenant against the AllowedTenants
+// map. Every tenant is allowed when no AllowedTenants are configured.
+func (p *ProviderData) isAllowedTenant(tenant string) bool {
+	if len(p.AllowedTenants) == 0 {
+		return true
+	}
+
+	_, ok := p.AllowedTenants[tenant]
+	return ok
+}
+
 // ValidateSession validates the AccessToken
 func (p *ProviderData) ValidateSession(ctx context.Context, s *sessions.SessionState) bool {
 	return validateToken(ctx, p, s.AccessToken, nil)
@@ -152,6 +171,12 @@ func (p *ProviderData) CreateSessionFromToken(ctx context.Context, token string)
 	return nil, ErrNotImplemented
 }
 
+// CreateSessionFromOpaqueToken converts opaque Bearer access tokens into
+// sessions, only providers able to validate their tokens implement it
+func (p *ProviderData) CreateSessionFromOpaqueToken(_ context.Context, _ string) (*sessions.SessionState, error) {
+	return nil, ErrNotImplemented
+}
+
 // GetSignOutURL for this provider if any
 func (p *ProviderData) GetSignOutURL(redirectURI string) string {
 	return redirectURI
@@ -121,6 +121,76 @@ func TestProviderDataAuthorize(t *testing.T) {
 	}
 }
 
+func TestProviderDataAuthorizeTenants(t *testing.T) {
+	testCases := []struct {
+		name           string
+		allowedTenants []string
+		allowedGroups  []string
+		tenant         string
+		groups         []string
+		expectedAuthZ  bool
+	}{
+		{
+			name:           "NoAllowedTenants",
+			allowedTenants: []string{},
+			tenant:         "foo",
+			expectedAuthZ:  true,
+		},
+		{
+			name:           "TenantAllowed",
+			allowedTenants: []string{"foo", "bar"},
+			tenant:         "bar",
+			expectedAuthZ:  true,
+		},
+		{
+			name:           "TenantNotAllowed",
+			allowedTenants: []string{"foo"},
+			tenant:         "baz",
+			expectedAuthZ:  false,
+		},
+		{
+			name:           "NoTenantInSession",
+			allowedTenants: []string{"foo"},
+			tenant:         "",
+			expectedAuthZ:  false,
+		},
+		{
+			name:           "TenantAllowedGroupNotAllowed",
+			allowedTenants: []string{"foo"},
+			allowedGroups:  []string{"admins"},
+			tenant:         "foo",
+			groups:         []string{"users"},
+			expectedAuthZ:  false,
+		},
+		{
+			name:           "TenantNotAllowedGroupAllowed",
+			allowedTenants: []string{"foo"},
+			allowedGroups:  []string{"admins"},
+			tenant:         "bar",
+			groups:         []string{"admins"},
+			expectedAuthZ:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			session := &sessions.SessionState{
+				Tenant: tc.tenant,
+				Groups: tc.groups,
+			}
+			p := &ProviderData{}
+			p.setAllowedGroups(tc.allowedGroups)
+			p.setAllowedTenants(tc.allowedTenants)
+
+			authorized, err := p.Authorize(context.Background(), session)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(authorized).To(Equal(tc.expectedAuthZ))
+		})
+	}
+}
+
 func TestResponseModeConfigured(t *testing.T) {
 	p := &ProviderData{
 		LoginURL: &url.URL{
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
@@ -29,6 +30,8 @@ type Provider interface {
 	ValidateSession(ctx context.Context, s *sessions.SessionState) bool
 	RefreshSession(ctx context.Context, s *sessions.SessionState) (bool, error)
 	CreateSessionFromToken(ctx context.Context, token string) (*sessions.SessionState, error)
+	CreateSessionFromOpaqueToken(ctx context.Context, token string) (*sessions.SessionState, error)
+	ParseBackChannelLogout(ctx context.Context, req *http.Request) (*BackChannelLogout, error)
 	// Stratio SingOutUrl
 	GetSignOutURL(redirectURI string) string
 }
@@ -172,6 +175,7 @@ func newProviderDataFromConfig(providerConfig options.Provider) (*ProviderData,
 	}
 
 	p.setAllowedGroups(providerConfig.AllowedGroups)
+	p.setAllowedTenants(providerConfig.AllowedTenants)
 
 	p.BackendLogoutURL = providerConfig.BackendLogoutURL
 
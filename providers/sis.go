@@ -3,16 +3,20 @@ package providers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/logger"
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/requests"
 )
 
@@ -142,14 +146,13 @@ func (p *SISProvider) Configure(rootURL *url.URL) {
 }
 
 // Redeem provides a default implementation of the OAuth2 token redemption process
-func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifier string) (s *sessions.SessionState, err error) {
+func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifier string) (*sessions.SessionState, error) {
 	if code == "" {
-		err = errors.New("missing code")
-		return
+		return nil, errors.New("missing code")
 	}
 	clientSecret, err := p.GetClientSecret()
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	params := url.Values{}
@@ -162,6 +165,12 @@ func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifie
 		params.Add("resource", p.ProtectedResource.String())
 	}
 
+	return p.redeemToken(ctx, params)
+}
+
+// redeemToken posts the given grant to the SIS token endpoint and builds a
+// session from the token response
+func (p *SISProvider) redeemToken(ctx context.Context, params url.Values) (s *sessions.SessionState, err error) {
 	result := requests.New(p.RedeemURL.String()).
 		WithContext(ctx).
 		WithMethod("POST").
@@ -169,23 +178,30 @@ func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifie
 		SetHeader("Content-Type", "application/x-www-form-urlencoded").
 		Do()
 	if result.Error() != nil {
-		fmt.Println("ERROR 1", result, params)
 		return nil, result.Error()
 	}
+	// A refresh token the server rejects will never be accepted again, the
+	// session is ended instead of failing every refresh
+	if params.Get("grant_type") == "refresh_token" && refreshTokenRejected(result) {
+		return nil, fmt.Errorf("refresh token rejected with status %d: %w", result.StatusCode(), ErrSessionRejected)
+	}
 
 	// blindly try json and x-www-form-urlencoded
 	var jsonResponse struct {
-		AccessToken string `json:"access_token"`
-		ExpiresOn   int64  `json:"expires"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresOn    int64  `json:"expires"`
 	}
 
 	err = result.UnmarshalInto(&jsonResponse)
 	if err == nil {
-		fmt.Println("ERROR 2", result)
-		expires := time.Now().Add(time.Duration(jsonResponse.ExpiresOn) * time.Second).Truncate(time.Second)
+		created := time.Now()
+		expires := created.Add(time.Duration(jsonResponse.ExpiresOn) * time.Second).Truncate(time.Second)
 		s = &sessions.SessionState{
-			AccessToken: jsonResponse.AccessToken,
-			ExpiresOn:   &expires,
+			AccessToken:  jsonResponse.AccessToken,
+			RefreshToken: jsonResponse.RefreshToken,
+			CreatedAt:    &created,
+			ExpiresOn:    &expires,
 		}
 		return
 	}
@@ -193,7 +209,6 @@ func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifie
 	var v url.Values
 	v, err = url.ParseQuery(string(result.Body()))
 	if err != nil {
-		fmt.Println("ERROR 3", result)
 		return
 	}
 
@@ -203,7 +218,6 @@ func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifie
 		var i int
 		i, err = strconv.Atoi(e)
 		if err != nil {
-			fmt.Println("ERROR 4", result)
 			return
 		}
 		expires = time.Now().Add(time.Duration(i) * time.Second).Truncate(time.Second)
@@ -213,15 +227,97 @@ func (p *SISProvider) Redeem(ctx context.Context, redirectURL, code, codeVerifie
 
 	if a := v.Get("access_token"); a != "" {
 		created := time.Now()
-		s = &sessions.SessionState{AccessToken: a, CreatedAt: &created, ExpiresOn: &expires}
+		s = &sessions.SessionState{AccessToken: a, RefreshToken: v.Get("refresh_token"), CreatedAt: &created, ExpiresOn: &expires}
 	} else {
-		fmt.Println("ERROR 5", result)
 		err = fmt.Errorf("no access token found %s", result.Body())
 	}
 
 	return
 }
 
+// RefreshSession uses the RefreshToken, when SIS issued one, to fetch a new
+// AccessToken and then reads the profile again so that changes to the user
+// groups and tenants are picked up without a new login.
+func (p *SISProvider) RefreshSession(ctx context.Context, s *sessions.SessionState) (bool, error) {
+	if s == nil || (s.AccessToken == "" && s.RefreshToken == "") {
+		return false, nil
+	}
+
+	refreshed := *s
+	if s.RefreshToken != "" {
+		tokens, err := p.redeemRefreshToken(ctx, s.RefreshToken)
+		if err != nil {
+			return false, fmt.Errorf("unable to redeem refresh token: %w", err)
+		}
+		refreshed.AccessToken = tokens.AccessToken
+		// SIS may not rotate the refresh token, in which case the old one is kept
+		if tokens.RefreshToken != "" {
+			refreshed.RefreshToken = tokens.RefreshToken
+		}
+		refreshed.CreatedAt = tokens.CreatedAt
+		refreshed.ExpiresOn = tokens.ExpiresOn
+	}
+
+	// The profile is read into a copy, so that the session keeps its groups
+	// and tenants when the profile cannot be read
+	enriched := refreshed
+	enriched.Groups = nil
+	enriched.Tenant = ""
+	enriched.Tenants = nil
+	if err := p.EnrichSession(ctx, &enriched); err != nil {
+		if s.RefreshToken == "" || errors.Is(err, ErrSessionRejected) {
+			return false, err
+		}
+		// SIS may have invalidated the old refresh token when rotating it,
+		// the new tokens are kept with the previous groups and tenants
+		logger.Errorf("Keeping the refreshed tokens of %s with the previous profile: %v", s.User, err)
+		*s = refreshed
+		return true, nil
+	}
+	// Keep the tenant the user switched to, as long as it is still one of theirs
+	if s.Tenant != "" && slices.Contains(enriched.Tenants, s.Tenant) {
+		enriched.Tenant = s.Tenant
+	}
+	*s = enriched
+
+	return true, nil
+}
+
+// redeemRefreshToken uses the refresh token with the RedeemURL to get new
+// tokens, returned in a new session without a profile
+func (p *SISProvider) redeemRefreshToken(ctx context.Context, refreshToken string) (*sessions.SessionState, error) {
+	clientSecret, err := p.GetClientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("client_id", p.ClientID)
+	params.Add("client_secret", clientSecret)
+	params.Add("refresh_token", refreshToken)
+	params.Add("grant_type", "refresh_token")
+
+	return p.redeemToken(ctx, params)
+}
+
+// refreshTokenRejected checks whether the token endpoint rejected the refresh
+// token itself, with an `invalid_grant` error (RFC 6749 section 5.2) or a 401,
+// rather than failing to process the request
+func refreshTokenRejected(result requests.Result) bool {
+	switch result.StatusCode() {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusBadRequest:
+		var errorResponse struct {
+			Error string `json:"error"`
+		}
+		return json.Unmarshal(result.Body(), &errorResponse) == nil &&
+			errorResponse.Error == "invalid_grant"
+	default:
+		return false
+	}
+}
+
 func makeSISHeaders(accessToken string) http.Header {
 	header := make(http.Header)
 	header.Set("Content-Type", "application/json")
@@ -232,11 +328,15 @@ func makeSISHeaders(accessToken string) http.Header {
 // EnrichSession is called after Redeem to allow providers to enrich session fields
 // such as User, Email, Groups with provider specific API calls.
 func (p *SISProvider) EnrichSession(ctx context.Context, s *sessions.SessionState) error {
-	json, err := requests.New(p.ProfileURL.String()).
+	result := requests.New(p.ProfileURL.String()).
 		WithContext(ctx).
 		WithHeaders(makeSISHeaders(s.AccessToken)).
-		Do().
-		UnmarshalSimpleJSON()
+		Do()
+	if result.Error() == nil && result.StatusCode() == http.StatusUnauthorized {
+		return fmt.Errorf("error getting user info: %w", ErrSessionRejected)
+	}
+
+	json, err := result.UnmarshalSimpleJSON()
 	if err != nil {
 		return fmt.Errorf("error getting user info: %v", err)
 	}
@@ -269,6 +369,54 @@ func (p *SISProvider) EnrichSession(ctx context.Context, s *sessions.SessionStat
 	return nil
 }
 
+// CreateSessionFromOpaqueToken validates an opaque SIS access token used as a
+// Bearer token by reading the profile of its user, the token is rejected when
+// SIS does not know it.
+func (p *SISProvider) CreateSessionFromOpaqueToken(ctx context.Context, token string) (*sessions.SessionState, error) {
+	s := &sessions.SessionState{AccessToken: token}
+	if err := p.EnrichSession(ctx, s); err != nil {
+		return nil, err
+	}
+	if s.User == "" {
+		return nil, fmt.Errorf("no user found in the profile of the token: %w", ErrSessionRejected)
+	}
+	s.CreatedAtNow()
+
+	return s, nil
+}
+
+// sisLogoutRequest is the SAML LogoutRequest SIS sends, like CAS servers, to
+// the services of a user when their SSO session ends
+type sisLogoutRequest struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutRequest"`
+	NameID       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	SessionIndex string   `xml:"urn:oasis:names:tc:SAML:2.0:protocol SessionIndex"`
+}
+
+// ParseBackChannelLogout accepts the CAS `logoutRequest` sent by SIS, which
+// ends every session of the user as its SessionIndex is the service ticket of
+// the CAS protocol, unknown to OAuth 2.0 sessions. CAS logout requests are not
+// signed, the logout is Unsigned so that its source is checked instead.
+// OpenID Connect logout tokens are accepted as well.
+func (p *SISProvider) ParseBackChannelLogout(ctx context.Context, req *http.Request) (*BackChannelLogout, error) {
+	rawLogoutRequest := req.PostFormValue("logoutRequest")
+	if rawLogoutRequest == "" {
+		return p.ProviderData.ParseBackChannelLogout(ctx, req)
+	}
+
+	var logoutRequest sisLogoutRequest
+	if err := xml.Unmarshal([]byte(rawLogoutRequest), &logoutRequest); err != nil {
+		return nil, fmt.Errorf("invalid logoutRequest: %v", err)
+	}
+
+	user := strings.TrimSpace(logoutRequest.NameID)
+	// Old CAS servers do not send the user
+	if user == "" || user == "@NOT_USED@" {
+		return nil, errors.New("logoutRequest does not contain the user in its NameID")
+	}
+	return &BackChannelLogout{User: user, Unsigned: true}, nil
+}
+
 // GetSignOutURL for this provider if any
 func (p *SISProvider) GetSignOutURL(redirectURI string) string {
 	// copy URL
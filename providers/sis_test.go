@@ -2,12 +2,15 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/options"
+	"github.com/oauth2-proxy/oauth2-proxy/v7/pkg/apis/sessions"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
 )
@@ -93,6 +96,333 @@ func TestSISProviderEnrichSession(t *testing.T) {
 	assert.Equal(t, s.Tenant, "NONE")
 }
 
+func TestSISProviderRefreshSession(t *testing.T) {
+	const profile = `{"id":"admin","attributes":[{"uid":"admin"},{"tenant":"NONE"},
+{"groups":["admins"]},{"tenants":["NONE","NUNI"]},{"mail":"admin@example.com"}]}`
+
+	testCases := []struct {
+		name                 string
+		session              *sessions.SessionState
+		payloads             map[string]string
+		tokenStatus          int
+		profileStatus        int
+		expectedRefreshed    bool
+		expectedErr          error
+		expectedErrMessage   string
+		expectUnchanged      bool
+		expectedAccessToken  string
+		expectedRefreshToken string
+		expectedTenant       string
+	}{
+		{
+			name: "with a refresh token",
+			session: &sessions.SessionState{
+				AccessToken:  "old_access_token",
+				RefreshToken: "old_refresh_token",
+				Tenant:       "NONE",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/accessToken": `{"access_token":"new_access_token","refresh_token":"new_refresh_token","expires":3600}`,
+				"/sso/oauth2.0/profile":     profile,
+			},
+			profileStatus:        http.StatusOK,
+			expectedRefreshed:    true,
+			expectedAccessToken:  "new_access_token",
+			expectedRefreshToken: "new_refresh_token",
+			expectedTenant:       "NONE",
+		},
+		{
+			name: "with a refresh token that is not rotated",
+			session: &sessions.SessionState{
+				AccessToken:  "old_access_token",
+				RefreshToken: "old_refresh_token",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/accessToken": "access_token=new_access_token&expires=3600",
+				"/sso/oauth2.0/profile":     profile,
+			},
+			profileStatus:        http.StatusOK,
+			expectedRefreshed:    true,
+			expectedAccessToken:  "new_access_token",
+			expectedRefreshToken: "old_refresh_token",
+			expectedTenant:       "NONE",
+		},
+		{
+			name: "without a refresh token keeps the switched tenant",
+			session: &sessions.SessionState{
+				AccessToken: "access_token",
+				Tenant:      "NUNI",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/profile": profile,
+			},
+			profileStatus:       http.StatusOK,
+			expectedRefreshed:   true,
+			expectedAccessToken: "access_token",
+			expectedTenant:      "NUNI",
+		},
+		{
+			name: "without a refresh token drops a tenant the user left",
+			session: &sessions.SessionState{
+				AccessToken: "access_token",
+				Tenant:      "OLD",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/profile": profile,
+			},
+			profileStatus:       http.StatusOK,
+			expectedRefreshed:   true,
+			expectedAccessToken: "access_token",
+			expectedTenant:      "NONE",
+		},
+		{
+			name: "when the profile endpoint rejects the token",
+			session: &sessions.SessionState{
+				AccessToken: "access_token",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/profile": "",
+			},
+			profileStatus:     http.StatusUnauthorized,
+			expectedRefreshed: false,
+			expectedErr:       ErrSessionRejected,
+		},
+		{
+			name: "when the profile endpoint fails keeps the groups and tenants",
+			session: &sessions.SessionState{
+				AccessToken: "access_token",
+				Groups:      []string{"admins"},
+				Tenant:      "NUNI",
+				Tenants:     []string{"NONE", "NUNI"},
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/profile": "",
+			},
+			profileStatus:      http.StatusInternalServerError,
+			expectedRefreshed:  false,
+			expectedErrMessage: "unexpected status \"500\"",
+			expectUnchanged:    true,
+		},
+		{
+			name: "when the profile endpoint fails after the refresh keeps the new tokens",
+			session: &sessions.SessionState{
+				AccessToken:  "old_access_token",
+				RefreshToken: "old_refresh_token",
+				Groups:       []string{"admins"},
+				Tenant:       "NUNI",
+				Tenants:      []string{"NONE", "NUNI"},
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/accessToken": `{"access_token":"new_access_token","refresh_token":"new_refresh_token","expires":3600}`,
+				"/sso/oauth2.0/profile":     "",
+			},
+			profileStatus:        http.StatusInternalServerError,
+			expectedRefreshed:    true,
+			expectedAccessToken:  "new_access_token",
+			expectedRefreshToken: "new_refresh_token",
+			expectedTenant:       "NUNI",
+		},
+		{
+			name: "when the refresh token is invalid",
+			session: &sessions.SessionState{
+				AccessToken:  "old_access_token",
+				RefreshToken: "old_refresh_token",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/accessToken": `{"error":"invalid_grant"}`,
+			},
+			tokenStatus:       http.StatusBadRequest,
+			expectedRefreshed: false,
+			expectedErr:       ErrSessionRejected,
+		},
+		{
+			name: "when the refresh token is unauthorized",
+			session: &sessions.SessionState{
+				AccessToken:  "old_access_token",
+				RefreshToken: "old_refresh_token",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/accessToken": "",
+			},
+			tokenStatus:       http.StatusUnauthorized,
+			expectedRefreshed: false,
+			expectedErr:       ErrSessionRejected,
+		},
+		{
+			name: "when the token endpoint fails",
+			session: &sessions.SessionState{
+				AccessToken:  "old_access_token",
+				RefreshToken: "old_refresh_token",
+			},
+			payloads: map[string]string{
+				"/sso/oauth2.0/accessToken": `{"error":"server_error"}`,
+			},
+			tokenStatus:        http.StatusInternalServerError,
+			expectedRefreshed:  false,
+			expectedErrMessage: "unable to redeem refresh token",
+			expectUnchanged:    true,
+		},
+		{
+			name:              "without tokens",
+			session:           &sessions.SessionState{},
+			expectedRefreshed: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload, ok := tc.payloads[r.URL.Path]
+				switch {
+				case !ok:
+					w.WriteHeader(http.StatusNotFound)
+				case r.URL.Path == "/sso/oauth2.0/profile":
+					w.WriteHeader(tc.profileStatus)
+				case tc.tokenStatus != 0:
+					w.WriteHeader(tc.tokenStatus)
+				default:
+					w.WriteHeader(http.StatusOK)
+				}
+				w.Write([]byte(payload))
+			}))
+			defer b.Close()
+
+			bURL, _ := url.Parse(b.URL + "/sso")
+			p := testSISProvider(bURL)
+
+			original := *tc.session
+			refreshed, err := p.RefreshSession(context.Background(), tc.session)
+			switch {
+			case tc.expectedErr != nil:
+				g.Expect(err).To(MatchError(tc.expectedErr))
+			case tc.expectedErrMessage != "":
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedErrMessage)))
+				g.Expect(errors.Is(err, ErrSessionRejected)).To(BeFalse())
+			default:
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			g.Expect(refreshed).To(Equal(tc.expectedRefreshed))
+			if tc.expectUnchanged {
+				g.Expect(*tc.session).To(Equal(original))
+			}
+			if !tc.expectedRefreshed {
+				return
+			}
+			g.Expect(tc.session.AccessToken).To(Equal(tc.expectedAccessToken))
+			g.Expect(tc.session.RefreshToken).To(Equal(tc.expectedRefreshToken))
+			g.Expect(tc.session.Tenant).To(Equal(tc.expectedTenant))
+			g.Expect(tc.session.Tenants).To(Equal([]string{"NONE", "NUNI"}))
+			g.Expect(tc.session.Groups).To(Equal([]string{"admins"}))
+		})
+	}
+}
+
+func TestSISProviderCreateSessionFromOpaqueToken(t *testing.T) {
+	testCases := []struct {
+		name          string
+		profile       string
+		profileStatus int
+		expectedErr   error
+		expectedUser  string
+	}{
+		{
+			name: "with a valid token",
+			profile: `{"id":"admin","attributes":[{"uid":"admin"},{"tenant":"NONE"},
+{"groups":["admins"]},{"tenants":["NONE","NUNI"]},{"mail":"admin@example.com"}]}`,
+			profileStatus: http.StatusOK,
+			expectedUser:  "admin",
+		},
+		{
+			name:          "with a token unknown to SIS",
+			profileStatus: http.StatusUnauthorized,
+			expectedErr:   ErrSessionRejected,
+		},
+		{
+			name:          "with a profile without user",
+			profile:       `{"id":"","attributes":[]}`,
+			profileStatus: http.StatusOK,
+			expectedErr:   ErrSessionRejected,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.URL.Path).To(Equal("/sso/oauth2.0/profile"))
+				g.Expect(r.Header.Get("Authorization")).To(Equal("Bearer opaque_token"))
+				w.WriteHeader(tc.profileStatus)
+				w.Write([]byte(tc.profile))
+			}))
+			defer b.Close()
+
+			bURL, _ := url.Parse(b.URL + "/sso")
+			p := testSISProvider(bURL)
+
+			s, err := p.CreateSessionFromOpaqueToken(context.Background(), "opaque_token")
+			if tc.expectedErr != nil {
+				g.Expect(err).To(MatchError(tc.expectedErr))
+				g.Expect(s).To(BeNil())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(s.AccessToken).To(Equal("opaque_token"))
+			g.Expect(s.User).To(Equal(tc.expectedUser))
+			g.Expect(s.Email).To(Equal("admin@example.com"))
+			g.Expect(s.Groups).To(Equal([]string{"admins"}))
+			g.Expect(s.Tenant).To(Equal("NONE"))
+			g.Expect(s.CreatedAt).ToNot(BeNil())
+		})
+	}
+}
+
+func TestSISProviderParseBackChannelLogout(t *testing.T) {
+	const logoutRequest = `<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ` +
+		`xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="LR-1" Version="2.0" IssueInstant="2024-01-01T00:00:00Z">` +
+		`<saml:NameID>%s</saml:NameID><samlp:SessionIndex>ST-1</samlp:SessionIndex></samlp:LogoutRequest>`
+
+	testCases := map[string]struct {
+		form           url.Values
+		expectedLogout *BackChannelLogout
+		expectedError  string
+	}{
+		"with a logout request": {
+			form:           url.Values{"logoutRequest": {fmt.Sprintf(logoutRequest, "admin")}},
+			expectedLogout: &BackChannelLogout{User: "admin", Unsigned: true},
+		},
+		"with a logout request without user": {
+			form:          url.Values{"logoutRequest": {fmt.Sprintf(logoutRequest, "@NOT_USED@")}},
+			expectedError: "logoutRequest does not contain the user in its NameID",
+		},
+		"with an invalid logout request": {
+			form:          url.Values{"logoutRequest": {"<LogoutRequest>"}},
+			expectedError: "invalid logoutRequest",
+		},
+		"without a logout request": {
+			form:          url.Values{},
+			expectedError: "missing logout_token",
+		},
+	}
+
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			g := NewWithT(t)
+
+			p := testSISProvider(&url.URL{Scheme: "https", Host: "sis", Path: "/sso"})
+			logout, err := p.ParseBackChannelLogout(context.Background(), newBackChannelLogoutRequest(tc.form))
+			if tc.expectedError != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.expectedError)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(logout).To(Equal(tc.expectedLogout))
+		})
+	}
+}
+
 func testSISBackend(payloads map[string]string) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {